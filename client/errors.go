@@ -0,0 +1,64 @@
+package main
+
+import (
+	"math"
+	"net/http"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+// httpStatusCodes maps gRPC status codes to the HTTP status returned by the
+// proxy. Codes missing from the map are reported as 500.
+var httpStatusCodes = map[codes.Code]int{
+	codes.InvalidArgument:    http.StatusBadRequest,
+	codes.OutOfRange:         http.StatusBadRequest,
+	codes.FailedPrecondition: http.StatusBadRequest,
+	codes.Unauthenticated:    http.StatusUnauthorized,
+	codes.PermissionDenied:   http.StatusForbidden,
+	codes.NotFound:           http.StatusNotFound,
+	codes.AlreadyExists:      http.StatusConflict,
+	codes.Aborted:            http.StatusConflict,
+	codes.ResourceExhausted:  http.StatusTooManyRequests,
+	codes.Canceled:           499,
+	codes.Unimplemented:      http.StatusNotImplemented,
+	codes.Unavailable:        http.StatusServiceUnavailable,
+	codes.DeadlineExceeded:   http.StatusGatewayTimeout,
+}
+
+type fieldError struct {
+	Field       string `json:"field"`
+	Description string `json:"description"`
+}
+
+// grpcError writes the status of a failed gRPC call as a JSON error. Field
+// violations reported by the server are listed in an "errors" array, and a
+// retry delay becomes the Retry-After header.
+func grpcError(ctx *gin.Context, err error) {
+	st := status.Convert(err)
+	code, ok := httpStatusCodes[st.Code()]
+	if !ok {
+		code = http.StatusInternalServerError
+	}
+
+	body := gin.H{"error": st.Message()}
+	var fields []fieldError
+	for _, detail := range st.Details() {
+		switch d := detail.(type) {
+		case *errdetails.BadRequest:
+			for _, v := range d.GetFieldViolations() {
+				fields = append(fields, fieldError{Field: v.GetField(), Description: v.GetDescription()})
+			}
+		case *errdetails.RetryInfo:
+			seconds := math.Ceil(d.GetRetryDelay().AsDuration().Seconds())
+			ctx.Header("Retry-After", strconv.Itoa(int(seconds)))
+		}
+	}
+	if len(fields) > 0 {
+		body["errors"] = fields
+	}
+	ctx.JSON(code, body)
+}
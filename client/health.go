@@ -0,0 +1,59 @@
+package main
+
+import (
+	"context"
+	"net/http"
+	"time"
+
+	pb "grpc/proto"
+
+	"github.com/gin-gonic/gin"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+const healthCheckTimeout = 2 * time.Second
+
+// readyServices are the upstream services the proxy forwards to.
+var readyServices = []string{
+	pb.UserService_ServiceDesc.ServiceName,
+	pb.BookService_ServiceDesc.ServiceName,
+}
+
+// registerHealthRoutes exposes the upstream gRPC health status to HTTP
+// probes: /healthz reports the server as a whole and /readyz every service
+// in readyServices. Both answer 503 unless everything is SERVING.
+func registerHealthRoutes(r *gin.Engine, client healthpb.HealthClient) {
+	r.GET("/healthz", func(ctx *gin.Context) {
+		status := checkHealth(ctx.Request.Context(), client, "")
+		ctx.JSON(httpStatus(status == healthpb.HealthCheckResponse_SERVING), gin.H{"status": status.String()})
+	})
+
+	r.GET("/readyz", func(ctx *gin.Context) {
+		ready := true
+		services := gin.H{}
+		for _, service := range readyServices {
+			status := checkHealth(ctx.Request.Context(), client, service)
+			services[service] = status.String()
+			ready = ready && status == healthpb.HealthCheckResponse_SERVING
+		}
+		ctx.JSON(httpStatus(ready), gin.H{"ready": ready, "services": services})
+	})
+}
+
+// checkHealth reports UNKNOWN when the server cannot be asked.
+func checkHealth(ctx context.Context, client healthpb.HealthClient, service string) healthpb.HealthCheckResponse_ServingStatus {
+	ctx, cancel := context.WithTimeout(ctx, healthCheckTimeout)
+	defer cancel()
+	resp, err := client.Check(ctx, &healthpb.HealthCheckRequest{Service: service})
+	if err != nil {
+		return healthpb.HealthCheckResponse_UNKNOWN
+	}
+	return resp.Status
+}
+
+func httpStatus(ok bool) int {
+	if ok {
+		return http.StatusOK
+	}
+	return http.StatusServiceUnavailable
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"bufio"
+	"encoding/csv"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"net/http"
+	"strings"
+
+	pb "grpc/proto"
+
+	"github.com/gin-gonic/gin"
+)
+
+// importBooks streams a CSV (with a title,author header) or NDJSON request
+// body to BookService.ImportBooks row by row, without buffering the upload.
+func importBooks(bookClient pb.BookServiceClient) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		var next func() (*pb.Book, error)
+		switch ctx.ContentType() {
+		case "text/csv":
+			reader, err := newCSVBookReader(ctx.Request.Body)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+			next = reader
+		case "application/x-ndjson", "application/jsonl":
+			next = newNDJSONBookReader(ctx.Request.Body)
+		default:
+			ctx.JSON(http.StatusUnsupportedMediaType, gin.H{"error": "expected text/csv or application/x-ndjson body"})
+			return
+		}
+
+		stream, err := bookClient.ImportBooks(withAuthMetadata(ctx.Request.Context()))
+		if err != nil {
+			grpcError(ctx, err)
+			return
+		}
+
+		// The server commits rows in batches as they arrive, so a malformed
+		// row ends the upload there and the rows before it are still
+		// reported, letting the caller resume after them.
+		var malformed error
+		for row := 1; ; row++ {
+			book, err := next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				malformed = fmt.Errorf("row %d: %v", row, err)
+				break
+			}
+			if err := stream.Send(book); err != nil {
+				// The real error is reported by CloseAndRecv.
+				break
+			}
+		}
+
+		res, err := stream.CloseAndRecv()
+		if err != nil {
+			grpcError(ctx, err)
+			return
+		}
+		body := gin.H{
+			"created_ids": res.CreatedIds,
+			"errors":      res.Errors,
+			"total":       res.Total,
+		}
+		if malformed != nil {
+			body["error"] = malformed.Error()
+			ctx.JSON(http.StatusBadRequest, body)
+			return
+		}
+		ctx.JSON(http.StatusOK, body)
+	}
+}
+
+func newCSVBookReader(body io.Reader) (func() (*pb.Book, error), error) {
+	r := csv.NewReader(body)
+	r.TrimLeadingSpace = true
+
+	header, err := r.Read()
+	if err != nil {
+		return nil, fmt.Errorf("reading csv header: %w", err)
+	}
+
+	titleCol, authorCol := -1, -1
+	for i, name := range header {
+		switch strings.ToLower(strings.TrimSpace(name)) {
+		case "title":
+			titleCol = i
+		case "author":
+			authorCol = i
+		}
+	}
+	if titleCol < 0 || authorCol < 0 {
+		return nil, errors.New("csv header must contain title and author columns")
+	}
+
+	return func() (*pb.Book, error) {
+		record, err := r.Read()
+		if err != nil {
+			return nil, err
+		}
+		return &pb.Book{Title: record[titleCol], Author: record[authorCol]}, nil
+	}, nil
+}
+
+func newNDJSONBookReader(body io.Reader) func() (*pb.Book, error) {
+	scanner := bufio.NewScanner(body)
+	scanner.Buffer(make([]byte, 64*1024), 1024*1024)
+
+	return func() (*pb.Book, error) {
+		for scanner.Scan() {
+			line := strings.TrimSpace(scanner.Text())
+			if line == "" {
+				continue
+			}
+			var book struct {
+				Title  string `json:"title"`
+				Author string `json:"author"`
+			}
+			if err := json.Unmarshal([]byte(line), &book); err != nil {
+				return nil, err
+			}
+			return &pb.Book{Title: book.Title, Author: book.Author}, nil
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, err
+		}
+		return nil, io.EOF
+	}
+}
@@ -11,17 +11,30 @@ import (
 	"time"
 
 	pb "grpc/proto"
+	"grpc/server/pkg/tracing"
 
 	"github.com/gin-gonic/gin"
 	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 	"google.golang.org/grpc/metadata"
 )
 
 var jwtToken string
 
 func main() {
-	conn, err := grpc.NewClient("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+	shutdownTracing, err := setupTracing()
+	if err != nil {
+		log.Fatalf("invalid tracing configuration: %v", err)
+	}
+
+	creds, err := transportCredentials()
+	if err != nil {
+		log.Fatalf("invalid TLS configuration: %v", err)
+	}
+	conn, err := grpc.NewClient(serverAddr(),
+		grpc.WithTransportCredentials(creds),
+		grpc.WithStatsHandler(tracing.ClientHandler()),
+	)
 	if err != nil {
 		log.Fatalf("did not connect: %v", err)
 	}
@@ -31,8 +44,16 @@ func main() {
 	userClient := pb.NewUserServiceClient(conn)
 
 	r := gin.Default()
+	// Let handlers that pass *gin.Context to gRPC calls carry the request's
+	// trace context and cancellation.
+	r.ContextWithFallback = true
 
 	r.SetTrustedProxies([]string{"127.0.0.1"})
+	registerMetrics(r)
+	r.Use(tracingMiddleware())
+	r.Use(forwardClientIP())
+	registerHealthRoutes(r, healthpb.NewHealthClient(conn))
+
 	// auth
 
 	r.POST("/auth/sign-up", func(ctx *gin.Context) {
@@ -41,12 +62,13 @@ func main() {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
-		res, err := userClient.SignUp(ctx, &user)
-		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+		if _, err := userClient.SignUp(ctx, &user); err != nil {
+			grpcError(ctx, err)
 			return
 		}
-		ctx.JSON(http.StatusCreated, gin.H{"id": res.Id})
+		// A taken username gets this same answer, so there is no id to
+		// return; UserId.id is deprecated.
+		ctx.JSON(http.StatusCreated, gin.H{"message": "signed up"})
 	})
 
 	r.POST("/auth/sign-in", func(ctx *gin.Context) {
@@ -57,26 +79,118 @@ func main() {
 		}
 		res, err := userClient.SignIn(ctx, &user)
 		if err != nil {
+			grpcError(ctx, err)
+			return
+		}
+		jwtToken = res.Token
+		ctx.JSON(http.StatusCreated, gin.H{
+			"token":         res.Token,
+			"refresh_token": res.RefreshToken,
+			"expires_in":    res.ExpiresIn,
+		})
+	})
+
+	r.POST("/auth/refresh", func(ctx *gin.Context) {
+		var req pb.RefreshTokenRequest
+		if err := ctx.ShouldBindJSON(&req); err != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 			return
 		}
+		res, err := userClient.RefreshToken(ctx, &req)
+		if err != nil {
+			grpcError(ctx, err)
+			return
+		}
 		jwtToken = res.Token
-		ctx.JSON(http.StatusCreated, gin.H{"token": res.Token})
+		ctx.JSON(http.StatusOK, gin.H{
+			"token":         res.Token,
+			"refresh_token": res.RefreshToken,
+			"expires_in":    res.ExpiresIn,
+		})
+	})
+
+	r.POST("/auth/sign-out", func(ctx *gin.Context) {
+		var req pb.SignOutRequest
+		if ctx.Request.ContentLength > 0 {
+			if err := ctx.ShouldBindJSON(&req); err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+				return
+			}
+		}
+		mdCtx := withAuthMetadata(ctx.Request.Context())
+		if _, err := userClient.SignOut(mdCtx, &req); err != nil {
+			grpcError(ctx, err)
+			return
+		}
+		jwtToken = ""
+		ctx.JSON(http.StatusOK, gin.H{"message": "signed out"})
+	})
+
+	r.GET("/.well-known/jwks.json", func(ctx *gin.Context) {
+		res, err := userClient.GetJWKS(ctx, &pb.Empty{})
+		if err != nil {
+			grpcError(ctx, err)
+			return
+		}
+		keys := res.Keys
+		if keys == nil {
+			keys = []*pb.JSONWebKey{}
+		}
+		ctx.Header("Cache-Control", "public, max-age=300")
+		ctx.JSON(http.StatusOK, gin.H{"keys": keys})
+	})
+
+	// admin
+
+	r.POST("/admin/users/:username/unlock", func(ctx *gin.Context) {
+		mdCtx := withAuthMetadata(ctx.Request.Context())
+		_, err := userClient.UnlockUser(mdCtx, &pb.UnlockUserRequest{Username: ctx.Param("username")})
+		if err != nil {
+			grpcError(ctx, err)
+			return
+		}
+		ctx.JSON(http.StatusOK, gin.H{"message": "user unlocked"})
 	})
 
 	// books
 	r.GET("/books", func(ctx *gin.Context) {
-		mdCtx := withAuthMetadata(context.Background())
-		res, err := bookClient.GetBooks(mdCtx, &pb.Empty{})
+		mdCtx := withAuthMetadata(ctx.Request.Context())
+		req := &pb.ListBooksRequest{
+			PageToken:   ctx.Query("page_token"),
+			OrderBy:     ctx.Query("order_by"),
+			Author:      ctx.Query("author"),
+			TitlePrefix: ctx.Query("title_prefix"),
+		}
+		if v := ctx.Query("page_size"); v != "" {
+			pageSize, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid page_size"})
+				return
+			}
+			req.PageSize = uint32(pageSize)
+		}
+		if v := ctx.Query("user_id"); v != "" {
+			userId, err := strconv.ParseUint(v, 10, 32)
+			if err != nil {
+				ctx.JSON(http.StatusBadRequest, gin.H{"error": "invalid user_id"})
+				return
+			}
+			req.UserId = uint32(userId)
+		}
+		res, err := bookClient.GetBooks(mdCtx, req)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			grpcError(ctx, err)
 			return
 		}
-		ctx.JSON(http.StatusOK, gin.H{"books": res.Books})
+		ctx.JSON(http.StatusOK, gin.H{
+			"books":           res.Books,
+			"next_page_token": res.NextPageToken,
+			"total_size":      res.TotalSize,
+		})
 	})
 
 	r.GET("/books/:id", func(ctx *gin.Context) {
-		mdCtx := withAuthMetadata(context.Background())
+		mdCtx := withAuthMetadata(ctx.Request.Context())
 		idParam := ctx.Param("id")
 		id, err := strconv.ParseUint(idParam, 10, 32)
 		if err != nil {
@@ -85,14 +199,14 @@ func main() {
 		}
 		res, err := bookClient.GetBook(mdCtx, &pb.BookId{Id: uint32(id)})
 		if err != nil {
-			ctx.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+			grpcError(ctx, err)
 			return
 		}
 		ctx.JSON(http.StatusOK, gin.H{"book": res})
 	})
 
 	r.POST("/books", func(ctx *gin.Context) {
-		mdCtx := withAuthMetadata(context.Background())
+		mdCtx := withAuthMetadata(ctx.Request.Context())
 		var book pb.Book
 		if err := ctx.ShouldBindJSON(&book); err != nil {
 			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
@@ -100,14 +214,16 @@ func main() {
 		}
 		res, err := bookClient.CreateBook(mdCtx, &book)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			grpcError(ctx, err)
 			return
 		}
 		ctx.JSON(http.StatusCreated, gin.H{"id": res.Id})
 	})
 
+	r.POST("/books/import", importBooks(bookClient))
+
 	r.PUT("/books/:id", func(ctx *gin.Context) {
-		mdCtx := withAuthMetadata(context.Background())
+		mdCtx := withAuthMetadata(ctx.Request.Context())
 		var book pb.Book
 		idParam := ctx.Param("id")
 		id, err := strconv.ParseUint(idParam, 10, 32)
@@ -124,14 +240,14 @@ func main() {
 		book.Id = uint32(id)
 		res, err := bookClient.UpdateBook(mdCtx, &book)
 		if err != nil {
-			ctx.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
+			grpcError(ctx, err)
 			return
 		}
 		ctx.JSON(http.StatusOK, gin.H{"book": res})
 	})
 
 	r.DELETE("/books/:id", func(ctx *gin.Context) {
-		mdCtx := withAuthMetadata(context.Background())
+		mdCtx := withAuthMetadata(ctx.Request.Context())
 		idParam := ctx.Param("id")
 		id, err := strconv.ParseUint(idParam, 10, 32)
 		if err != nil {
@@ -140,7 +256,7 @@ func main() {
 		}
 		_, err = bookClient.DeleteBook(mdCtx, &pb.BookId{Id: uint32(id)})
 		if err != nil {
-			ctx.JSON(http.StatusInternalServerError, gin.H{"error": err.Error()})
+			grpcError(ctx, err)
 			return
 		}
 		ctx.JSON(http.StatusOK, gin.H{"message": "book deleted"})
@@ -171,6 +287,10 @@ func main() {
 		log.Fatalf("forced shutdown: %v", err)
 	}
 
+	if err := shutdownTracing(ctx); err != nil {
+		log.Printf("error flushing traces: %v", err)
+	}
+
 	log.Println("REST server stopped gracefully")
 }
 
@@ -178,9 +298,16 @@ func withAuthMetadata(ctx context.Context) context.Context {
 	if jwtToken == "" {
 		return ctx
 	}
-	md := metadata.New(map[string]string{
-		"authorization": "Bearer " + jwtToken,
-	})
+	return metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+jwtToken)
+}
 
-	return metadata.NewOutgoingContext(ctx, md)
+// forwardClientIP passes the caller's address to the server in the
+// x-forwarded-for metadata, so that anonymous calls are rate limited per
+// client rather than per proxy.
+func forwardClientIP() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		md := metadata.AppendToOutgoingContext(ctx.Request.Context(), "x-forwarded-for", ctx.ClientIP())
+		ctx.Request = ctx.Request.WithContext(md)
+		ctx.Next()
+	}
 }
@@ -0,0 +1,41 @@
+package main
+
+import (
+	"context"
+	"grpc/server/pkg/tracing"
+	"net/http"
+	"os"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/contrib/instrumentation/github.com/gin-gonic/gin/otelgin"
+)
+
+// setupTracing configures tracing from the same TRACING_* variables that
+// override the server's tracing settings. The returned function flushes
+// pending spans.
+func setupTracing() (func(context.Context) error, error) {
+	insecure, _ := strconv.ParseBool(os.Getenv("TRACING_INSECURE"))
+	ratio, _ := strconv.ParseFloat(os.Getenv("TRACING_SAMPLE_RATIO"), 64)
+	return tracing.Setup(context.Background(), tracing.Config{
+		Exporter:    os.Getenv("TRACING_EXPORTER"),
+		Endpoint:    os.Getenv("TRACING_ENDPOINT"),
+		Insecure:    insecure,
+		File:        os.Getenv("TRACING_FILE"),
+		SampleRatio: ratio,
+		ServiceName: "rest-proxy",
+	})
+}
+
+// tracingMiddleware starts a span for every request except the probe and
+// metrics endpoints. Handlers must pass the request context to gRPC calls
+// for their spans to join the trace.
+func tracingMiddleware() gin.HandlerFunc {
+	return otelgin.Middleware("rest-proxy", otelgin.WithFilter(func(r *http.Request) bool {
+		switch r.URL.Path {
+		case "/metrics", "/healthz", "/readyz":
+			return false
+		}
+		return true
+	}))
+}
@@ -0,0 +1,38 @@
+package main
+
+import (
+	"grpc/server/pkg/tlsconfig"
+	"os"
+	"strconv"
+
+	"google.golang.org/grpc/credentials"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// serverAddr returns the address of the upstream gRPC server.
+func serverAddr() string {
+	if addr := os.Getenv("GRPC_SERVER_ADDR"); addr != "" {
+		return addr
+	}
+	return "localhost:50051"
+}
+
+// transportCredentials configures the upstream connection from the
+// environment. TLS is used when GRPC_TLS is true or a CA bundle is given;
+// GRPC_TLS_CERT_FILE and GRPC_TLS_KEY_FILE add a client certificate for
+// servers that require mutual TLS.
+func transportCredentials() (credentials.TransportCredentials, error) {
+	enabled, _ := strconv.ParseBool(os.Getenv("GRPC_TLS"))
+	cfg := tlsconfig.ClientConfig{
+		CAFile:     os.Getenv("GRPC_TLS_CA_FILE"),
+		CertFile:   os.Getenv("GRPC_TLS_CERT_FILE"),
+		KeyFile:    os.Getenv("GRPC_TLS_KEY_FILE"),
+		ServerName: os.Getenv("GRPC_TLS_SERVER_NAME"),
+		MinVersion: os.Getenv("GRPC_TLS_MIN_VERSION"),
+	}
+	if !enabled && cfg.CAFile == "" {
+		return insecure.NewCredentials(), nil
+	}
+
+	return tlsconfig.ClientCredentials(cfg)
+}
@@ -21,6 +21,58 @@ const (
 	_ = protoimpl.EnforceVersion(protoimpl.MaxVersion - 20)
 )
 
+type BookEvent_Type int32
+
+const (
+	BookEvent_TYPE_UNSPECIFIED BookEvent_Type = 0
+	BookEvent_CREATED          BookEvent_Type = 1
+	BookEvent_UPDATED          BookEvent_Type = 2
+	BookEvent_DELETED          BookEvent_Type = 3
+)
+
+// Enum value maps for BookEvent_Type.
+var (
+	BookEvent_Type_name = map[int32]string{
+		0: "TYPE_UNSPECIFIED",
+		1: "CREATED",
+		2: "UPDATED",
+		3: "DELETED",
+	}
+	BookEvent_Type_value = map[string]int32{
+		"TYPE_UNSPECIFIED": 0,
+		"CREATED":          1,
+		"UPDATED":          2,
+		"DELETED":          3,
+	}
+)
+
+func (x BookEvent_Type) Enum() *BookEvent_Type {
+	p := new(BookEvent_Type)
+	*p = x
+	return p
+}
+
+func (x BookEvent_Type) String() string {
+	return protoimpl.X.EnumStringOf(x.Descriptor(), protoreflect.EnumNumber(x))
+}
+
+func (BookEvent_Type) Descriptor() protoreflect.EnumDescriptor {
+	return file_proto_book_proto_enumTypes[0].Descriptor()
+}
+
+func (BookEvent_Type) Type() protoreflect.EnumType {
+	return &file_proto_book_proto_enumTypes[0]
+}
+
+func (x BookEvent_Type) Number() protoreflect.EnumNumber {
+	return protoreflect.EnumNumber(x)
+}
+
+// Deprecated: Use BookEvent_Type.Descriptor instead.
+func (BookEvent_Type) EnumDescriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{8, 0}
+}
+
 type Book struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
@@ -136,6 +188,8 @@ func (x *BookId) GetId() uint32 {
 type BookList struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
 	Books         []*Book                `protobuf:"bytes,1,rep,name=books,proto3" json:"books,omitempty"`
+	NextPageToken string                 `protobuf:"bytes,2,opt,name=next_page_token,json=nextPageToken,proto3" json:"next_page_token,omitempty"`
+	TotalSize     int64                  `protobuf:"varint,3,opt,name=total_size,json=totalSize,proto3" json:"total_size,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
@@ -177,30 +231,49 @@ func (x *BookList) GetBooks() []*Book {
 	return nil
 }
 
-type User struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
-	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
-	Username      string                 `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
-	Password      string                 `protobuf:"bytes,4,opt,name=password,proto3" json:"password,omitempty"`
+func (x *BookList) GetNextPageToken() string {
+	if x != nil {
+		return x.NextPageToken
+	}
+	return ""
+}
+
+func (x *BookList) GetTotalSize() int64 {
+	if x != nil {
+		return x.TotalSize
+	}
+	return 0
+}
+
+type ListBooksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Maximum number of books to return, 0 means the server default.
+	PageSize uint32 `protobuf:"varint,1,opt,name=page_size,json=pageSize,proto3" json:"page_size,omitempty"`
+	// Opaque token returned as next_page_token by the previous call.
+	PageToken string `protobuf:"bytes,2,opt,name=page_token,json=pageToken,proto3" json:"page_token,omitempty"`
+	// One of title, author, id or created, optionally followed by " desc".
+	OrderBy       string `protobuf:"bytes,3,opt,name=order_by,json=orderBy,proto3" json:"order_by,omitempty"`
+	Author        string `protobuf:"bytes,4,opt,name=author,proto3" json:"author,omitempty"`
+	UserId        uint32 `protobuf:"varint,5,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	TitlePrefix   string `protobuf:"bytes,6,opt,name=title_prefix,json=titlePrefix,proto3" json:"title_prefix,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *User) Reset() {
-	*x = User{}
+func (x *ListBooksRequest) Reset() {
+	*x = ListBooksRequest{}
 	mi := &file_proto_book_proto_msgTypes[3]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *User) String() string {
+func (x *ListBooksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*User) ProtoMessage() {}
+func (*ListBooksRequest) ProtoMessage() {}
 
-func (x *User) ProtoReflect() protoreflect.Message {
+func (x *ListBooksRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_book_proto_msgTypes[3]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -212,61 +285,78 @@ func (x *User) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use User.ProtoReflect.Descriptor instead.
-func (*User) Descriptor() ([]byte, []int) {
+// Deprecated: Use ListBooksRequest.ProtoReflect.Descriptor instead.
+func (*ListBooksRequest) Descriptor() ([]byte, []int) {
 	return file_proto_book_proto_rawDescGZIP(), []int{3}
 }
 
-func (x *User) GetId() uint32 {
+func (x *ListBooksRequest) GetPageSize() uint32 {
 	if x != nil {
-		return x.Id
+		return x.PageSize
 	}
 	return 0
 }
 
-func (x *User) GetName() string {
+func (x *ListBooksRequest) GetPageToken() string {
 	if x != nil {
-		return x.Name
+		return x.PageToken
 	}
 	return ""
 }
 
-func (x *User) GetUsername() string {
+func (x *ListBooksRequest) GetOrderBy() string {
 	if x != nil {
-		return x.Username
+		return x.OrderBy
 	}
 	return ""
 }
 
-func (x *User) GetPassword() string {
+func (x *ListBooksRequest) GetAuthor() string {
 	if x != nil {
-		return x.Password
+		return x.Author
 	}
 	return ""
 }
 
-type SignInRequest struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
-	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+func (x *ListBooksRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *ListBooksRequest) GetTitlePrefix() string {
+	if x != nil {
+		return x.TitlePrefix
+	}
+	return ""
+}
+
+type StreamBooksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Number of rows read from the database per batch, 0 means the server default.
+	BatchSize     uint32 `protobuf:"varint,1,opt,name=batch_size,json=batchSize,proto3" json:"batch_size,omitempty"`
+	Author        string `protobuf:"bytes,2,opt,name=author,proto3" json:"author,omitempty"`
+	UserId        uint32 `protobuf:"varint,3,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
+	TitlePrefix   string `protobuf:"bytes,4,opt,name=title_prefix,json=titlePrefix,proto3" json:"title_prefix,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *SignInRequest) Reset() {
-	*x = SignInRequest{}
+func (x *StreamBooksRequest) Reset() {
+	*x = StreamBooksRequest{}
 	mi := &file_proto_book_proto_msgTypes[4]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *SignInRequest) String() string {
+func (x *StreamBooksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*SignInRequest) ProtoMessage() {}
+func (*StreamBooksRequest) ProtoMessage() {}
 
-func (x *SignInRequest) ProtoReflect() protoreflect.Message {
+func (x *StreamBooksRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_book_proto_msgTypes[4]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -278,46 +368,63 @@ func (x *SignInRequest) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use SignInRequest.ProtoReflect.Descriptor instead.
-func (*SignInRequest) Descriptor() ([]byte, []int) {
+// Deprecated: Use StreamBooksRequest.ProtoReflect.Descriptor instead.
+func (*StreamBooksRequest) Descriptor() ([]byte, []int) {
 	return file_proto_book_proto_rawDescGZIP(), []int{4}
 }
 
-func (x *SignInRequest) GetUsername() string {
+func (x *StreamBooksRequest) GetBatchSize() uint32 {
 	if x != nil {
-		return x.Username
+		return x.BatchSize
+	}
+	return 0
+}
+
+func (x *StreamBooksRequest) GetAuthor() string {
+	if x != nil {
+		return x.Author
 	}
 	return ""
 }
 
-func (x *SignInRequest) GetPassword() string {
+func (x *StreamBooksRequest) GetUserId() uint32 {
 	if x != nil {
-		return x.Password
+		return x.UserId
+	}
+	return 0
+}
+
+func (x *StreamBooksRequest) GetTitlePrefix() string {
+	if x != nil {
+		return x.TitlePrefix
 	}
 	return ""
 }
 
-type UserId struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
-	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+type ImportError struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// 1-based position of the book in the import stream.
+	Row           uint32 `protobuf:"varint,1,opt,name=row,proto3" json:"row,omitempty"`
+	Field         string `protobuf:"bytes,2,opt,name=field,proto3" json:"field,omitempty"`
+	Message       string `protobuf:"bytes,3,opt,name=message,proto3" json:"message,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *UserId) Reset() {
-	*x = UserId{}
+func (x *ImportError) Reset() {
+	*x = ImportError{}
 	mi := &file_proto_book_proto_msgTypes[5]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *UserId) String() string {
+func (x *ImportError) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*UserId) ProtoMessage() {}
+func (*ImportError) ProtoMessage() {}
 
-func (x *UserId) ProtoReflect() protoreflect.Message {
+func (x *ImportError) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_book_proto_msgTypes[5]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -329,39 +436,55 @@ func (x *UserId) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use UserId.ProtoReflect.Descriptor instead.
-func (*UserId) Descriptor() ([]byte, []int) {
+// Deprecated: Use ImportError.ProtoReflect.Descriptor instead.
+func (*ImportError) Descriptor() ([]byte, []int) {
 	return file_proto_book_proto_rawDescGZIP(), []int{5}
 }
 
-func (x *UserId) GetId() uint32 {
+func (x *ImportError) GetRow() uint32 {
 	if x != nil {
-		return x.Id
+		return x.Row
 	}
 	return 0
 }
 
-type AuthResponse struct {
+func (x *ImportError) GetField() string {
+	if x != nil {
+		return x.Field
+	}
+	return ""
+}
+
+func (x *ImportError) GetMessage() string {
+	if x != nil {
+		return x.Message
+	}
+	return ""
+}
+
+type ImportBooksResponse struct {
 	state         protoimpl.MessageState `protogen:"open.v1"`
-	Token         string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	CreatedIds    []uint32               `protobuf:"varint,1,rep,packed,name=created_ids,json=createdIds,proto3" json:"created_ids,omitempty"`
+	Errors        []*ImportError         `protobuf:"bytes,2,rep,name=errors,proto3" json:"errors,omitempty"`
+	Total         uint32                 `protobuf:"varint,3,opt,name=total,proto3" json:"total,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *AuthResponse) Reset() {
-	*x = AuthResponse{}
+func (x *ImportBooksResponse) Reset() {
+	*x = ImportBooksResponse{}
 	mi := &file_proto_book_proto_msgTypes[6]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *AuthResponse) String() string {
+func (x *ImportBooksResponse) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*AuthResponse) ProtoMessage() {}
+func (*ImportBooksResponse) ProtoMessage() {}
 
-func (x *AuthResponse) ProtoReflect() protoreflect.Message {
+func (x *ImportBooksResponse) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_book_proto_msgTypes[6]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -373,38 +496,56 @@ func (x *AuthResponse) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use AuthResponse.ProtoReflect.Descriptor instead.
-func (*AuthResponse) Descriptor() ([]byte, []int) {
+// Deprecated: Use ImportBooksResponse.ProtoReflect.Descriptor instead.
+func (*ImportBooksResponse) Descriptor() ([]byte, []int) {
 	return file_proto_book_proto_rawDescGZIP(), []int{6}
 }
 
-func (x *AuthResponse) GetToken() string {
+func (x *ImportBooksResponse) GetCreatedIds() []uint32 {
 	if x != nil {
-		return x.Token
+		return x.CreatedIds
 	}
-	return ""
+	return nil
 }
 
-type Empty struct {
-	state         protoimpl.MessageState `protogen:"open.v1"`
+func (x *ImportBooksResponse) GetErrors() []*ImportError {
+	if x != nil {
+		return x.Errors
+	}
+	return nil
+}
+
+func (x *ImportBooksResponse) GetTotal() uint32 {
+	if x != nil {
+		return x.Total
+	}
+	return 0
+}
+
+type WatchBooksRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Resume after this revision, 0 starts with the next change.
+	FromRevision uint64 `protobuf:"varint,1,opt,name=from_revision,json=fromRevision,proto3" json:"from_revision,omitempty"`
+	// Only report changes to books owned by this user, 0 reports all.
+	UserId        uint32 `protobuf:"varint,2,opt,name=user_id,json=userId,proto3" json:"user_id,omitempty"`
 	unknownFields protoimpl.UnknownFields
 	sizeCache     protoimpl.SizeCache
 }
 
-func (x *Empty) Reset() {
-	*x = Empty{}
+func (x *WatchBooksRequest) Reset() {
+	*x = WatchBooksRequest{}
 	mi := &file_proto_book_proto_msgTypes[7]
 	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
 	ms.StoreMessageInfo(mi)
 }
 
-func (x *Empty) String() string {
+func (x *WatchBooksRequest) String() string {
 	return protoimpl.X.MessageStringOf(x)
 }
 
-func (*Empty) ProtoMessage() {}
+func (*WatchBooksRequest) ProtoMessage() {}
 
-func (x *Empty) ProtoReflect() protoreflect.Message {
+func (x *WatchBooksRequest) ProtoReflect() protoreflect.Message {
 	mi := &file_proto_book_proto_msgTypes[7]
 	if x != nil {
 		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
@@ -416,50 +557,736 @@ func (x *Empty) ProtoReflect() protoreflect.Message {
 	return mi.MessageOf(x)
 }
 
-// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
-func (*Empty) Descriptor() ([]byte, []int) {
+// Deprecated: Use WatchBooksRequest.ProtoReflect.Descriptor instead.
+func (*WatchBooksRequest) Descriptor() ([]byte, []int) {
 	return file_proto_book_proto_rawDescGZIP(), []int{7}
 }
 
-var File_proto_book_proto protoreflect.FileDescriptor
+func (x *WatchBooksRequest) GetFromRevision() uint64 {
+	if x != nil {
+		return x.FromRevision
+	}
+	return 0
+}
 
-const file_proto_book_proto_rawDesc = "" +
-	"\n" +
-	"\x10proto/book.proto\x12\x05proto\"\\\n" +
-	"\x04Book\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\rR\x02id\x12\x14\n" +
-	"\x05title\x18\x02 \x01(\tR\x05title\x12\x16\n" +
-	"\x06author\x18\x03 \x01(\tR\x06author\x12\x16\n" +
-	"\x06userid\x18\x04 \x01(\rR\x06userid\"\x18\n" +
-	"\x06BookId\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\rR\x02id\"-\n" +
-	"\bBookList\x12!\n" +
-	"\x05books\x18\x01 \x03(\v2\v.proto.BookR\x05books\"b\n" +
-	"\x04User\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
-	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
-	"\busername\x18\x03 \x01(\tR\busername\x12\x1a\n" +
-	"\bpassword\x18\x04 \x01(\tR\bpassword\"G\n" +
-	"\rSignInRequest\x12\x1a\n" +
-	"\busername\x18\x01 \x01(\tR\busername\x12\x1a\n" +
-	"\bpassword\x18\x02 \x01(\tR\bpassword\"\x18\n" +
-	"\x06UserId\x12\x0e\n" +
-	"\x02id\x18\x01 \x01(\rR\x02id\"$\n" +
-	"\fAuthResponse\x12\x14\n" +
-	"\x05token\x18\x01 \x01(\tR\x05token\"\a\n" +
-	"\x05Empty2h\n" +
-	"\vUserService\x12$\n" +
-	"\x06SignUp\x12\v.proto.User\x1a\r.proto.UserId\x123\n" +
-	"\x06SignIn\x12\x14.proto.SignInRequest\x1a\x13.proto.AuthResponse2\xdc\x01\n" +
-	"\vBookService\x12(\n" +
+func (x *WatchBooksRequest) GetUserId() uint32 {
+	if x != nil {
+		return x.UserId
+	}
+	return 0
+}
+
+type BookEvent struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Revision      uint64                 `protobuf:"varint,1,opt,name=revision,proto3" json:"revision,omitempty"`
+	Type          BookEvent_Type         `protobuf:"varint,2,opt,name=type,proto3,enum=proto.BookEvent_Type" json:"type,omitempty"`
+	Book          *Book                  `protobuf:"bytes,3,opt,name=book,proto3" json:"book,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *BookEvent) Reset() {
+	*x = BookEvent{}
+	mi := &file_proto_book_proto_msgTypes[8]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *BookEvent) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*BookEvent) ProtoMessage() {}
+
+func (x *BookEvent) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[8]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use BookEvent.ProtoReflect.Descriptor instead.
+func (*BookEvent) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{8}
+}
+
+func (x *BookEvent) GetRevision() uint64 {
+	if x != nil {
+		return x.Revision
+	}
+	return 0
+}
+
+func (x *BookEvent) GetType() BookEvent_Type {
+	if x != nil {
+		return x.Type
+	}
+	return BookEvent_TYPE_UNSPECIFIED
+}
+
+func (x *BookEvent) GetBook() *Book {
+	if x != nil {
+		return x.Book
+	}
+	return nil
+}
+
+type User struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Id            uint32                 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	Name          string                 `protobuf:"bytes,2,opt,name=name,proto3" json:"name,omitempty"`
+	Username      string                 `protobuf:"bytes,3,opt,name=username,proto3" json:"username,omitempty"`
+	Password      string                 `protobuf:"bytes,4,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *User) Reset() {
+	*x = User{}
+	mi := &file_proto_book_proto_msgTypes[9]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *User) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*User) ProtoMessage() {}
+
+func (x *User) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[9]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use User.ProtoReflect.Descriptor instead.
+func (*User) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{9}
+}
+
+func (x *User) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+func (x *User) GetName() string {
+	if x != nil {
+		return x.Name
+	}
+	return ""
+}
+
+func (x *User) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *User) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type SignInRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	Password      string                 `protobuf:"bytes,2,opt,name=password,proto3" json:"password,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignInRequest) Reset() {
+	*x = SignInRequest{}
+	mi := &file_proto_book_proto_msgTypes[10]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignInRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignInRequest) ProtoMessage() {}
+
+func (x *SignInRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[10]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignInRequest.ProtoReflect.Descriptor instead.
+func (*SignInRequest) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{10}
+}
+
+func (x *SignInRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+func (x *SignInRequest) GetPassword() string {
+	if x != nil {
+		return x.Password
+	}
+	return ""
+}
+
+type UserId struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Always 0: SignUp answers a taken username exactly like a new account,
+	// so it cannot return the id. The id of a signed-in user is the user_id
+	// claim of its access token.
+	//
+	// Deprecated: Marked as deprecated in proto/book.proto.
+	Id            uint32 `protobuf:"varint,1,opt,name=id,proto3" json:"id,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UserId) Reset() {
+	*x = UserId{}
+	mi := &file_proto_book_proto_msgTypes[11]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UserId) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UserId) ProtoMessage() {}
+
+func (x *UserId) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[11]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UserId.ProtoReflect.Descriptor instead.
+func (*UserId) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{11}
+}
+
+// Deprecated: Marked as deprecated in proto/book.proto.
+func (x *UserId) GetId() uint32 {
+	if x != nil {
+		return x.Id
+	}
+	return 0
+}
+
+type AuthResponse struct {
+	state        protoimpl.MessageState `protogen:"open.v1"`
+	Token        string                 `protobuf:"bytes,1,opt,name=token,proto3" json:"token,omitempty"`
+	RefreshToken string                 `protobuf:"bytes,2,opt,name=refresh_token,json=refreshToken,proto3" json:"refresh_token,omitempty"`
+	// Lifetime of token in seconds.
+	ExpiresIn     int64 `protobuf:"varint,3,opt,name=expires_in,json=expiresIn,proto3" json:"expires_in,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *AuthResponse) Reset() {
+	*x = AuthResponse{}
+	mi := &file_proto_book_proto_msgTypes[12]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *AuthResponse) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*AuthResponse) ProtoMessage() {}
+
+func (x *AuthResponse) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[12]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use AuthResponse.ProtoReflect.Descriptor instead.
+func (*AuthResponse) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{12}
+}
+
+func (x *AuthResponse) GetToken() string {
+	if x != nil {
+		return x.Token
+	}
+	return ""
+}
+
+func (x *AuthResponse) GetRefreshToken() string {
+	if x != nil {
+		return x.RefreshToken
+	}
+	return ""
+}
+
+func (x *AuthResponse) GetExpiresIn() int64 {
+	if x != nil {
+		return x.ExpiresIn
+	}
+	return 0
+}
+
+type RefreshTokenRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	RefreshToken  string                 `protobuf:"bytes,1,opt,name=refresh_token,json=refreshToken,proto3" json:"refresh_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *RefreshTokenRequest) Reset() {
+	*x = RefreshTokenRequest{}
+	mi := &file_proto_book_proto_msgTypes[13]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *RefreshTokenRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*RefreshTokenRequest) ProtoMessage() {}
+
+func (x *RefreshTokenRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[13]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use RefreshTokenRequest.ProtoReflect.Descriptor instead.
+func (*RefreshTokenRequest) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{13}
+}
+
+func (x *RefreshTokenRequest) GetRefreshToken() string {
+	if x != nil {
+		return x.RefreshToken
+	}
+	return ""
+}
+
+type SignOutRequest struct {
+	state protoimpl.MessageState `protogen:"open.v1"`
+	// Optional, also revokes the refresh token family of the session.
+	RefreshToken  string `protobuf:"bytes,1,opt,name=refresh_token,json=refreshToken,proto3" json:"refresh_token,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *SignOutRequest) Reset() {
+	*x = SignOutRequest{}
+	mi := &file_proto_book_proto_msgTypes[14]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *SignOutRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*SignOutRequest) ProtoMessage() {}
+
+func (x *SignOutRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[14]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use SignOutRequest.ProtoReflect.Descriptor instead.
+func (*SignOutRequest) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{14}
+}
+
+func (x *SignOutRequest) GetRefreshToken() string {
+	if x != nil {
+		return x.RefreshToken
+	}
+	return ""
+}
+
+type UnlockUserRequest struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Username      string                 `protobuf:"bytes,1,opt,name=username,proto3" json:"username,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *UnlockUserRequest) Reset() {
+	*x = UnlockUserRequest{}
+	mi := &file_proto_book_proto_msgTypes[15]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *UnlockUserRequest) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*UnlockUserRequest) ProtoMessage() {}
+
+func (x *UnlockUserRequest) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[15]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use UnlockUserRequest.ProtoReflect.Descriptor instead.
+func (*UnlockUserRequest) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{15}
+}
+
+func (x *UnlockUserRequest) GetUsername() string {
+	if x != nil {
+		return x.Username
+	}
+	return ""
+}
+
+type JSONWebKey struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Kty           string                 `protobuf:"bytes,1,opt,name=kty,proto3" json:"kty,omitempty"`
+	Kid           string                 `protobuf:"bytes,2,opt,name=kid,proto3" json:"kid,omitempty"`
+	Use           string                 `protobuf:"bytes,3,opt,name=use,proto3" json:"use,omitempty"`
+	Alg           string                 `protobuf:"bytes,4,opt,name=alg,proto3" json:"alg,omitempty"`
+	N             string                 `protobuf:"bytes,5,opt,name=n,proto3" json:"n,omitempty"`
+	E             string                 `protobuf:"bytes,6,opt,name=e,proto3" json:"e,omitempty"`
+	Crv           string                 `protobuf:"bytes,7,opt,name=crv,proto3" json:"crv,omitempty"`
+	X             string                 `protobuf:"bytes,8,opt,name=x,proto3" json:"x,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JSONWebKey) Reset() {
+	*x = JSONWebKey{}
+	mi := &file_proto_book_proto_msgTypes[16]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JSONWebKey) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JSONWebKey) ProtoMessage() {}
+
+func (x *JSONWebKey) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[16]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JSONWebKey.ProtoReflect.Descriptor instead.
+func (*JSONWebKey) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{16}
+}
+
+func (x *JSONWebKey) GetKty() string {
+	if x != nil {
+		return x.Kty
+	}
+	return ""
+}
+
+func (x *JSONWebKey) GetKid() string {
+	if x != nil {
+		return x.Kid
+	}
+	return ""
+}
+
+func (x *JSONWebKey) GetUse() string {
+	if x != nil {
+		return x.Use
+	}
+	return ""
+}
+
+func (x *JSONWebKey) GetAlg() string {
+	if x != nil {
+		return x.Alg
+	}
+	return ""
+}
+
+func (x *JSONWebKey) GetN() string {
+	if x != nil {
+		return x.N
+	}
+	return ""
+}
+
+func (x *JSONWebKey) GetE() string {
+	if x != nil {
+		return x.E
+	}
+	return ""
+}
+
+func (x *JSONWebKey) GetCrv() string {
+	if x != nil {
+		return x.Crv
+	}
+	return ""
+}
+
+func (x *JSONWebKey) GetX() string {
+	if x != nil {
+		return x.X
+	}
+	return ""
+}
+
+type JWKS struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	Keys          []*JSONWebKey          `protobuf:"bytes,1,rep,name=keys,proto3" json:"keys,omitempty"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *JWKS) Reset() {
+	*x = JWKS{}
+	mi := &file_proto_book_proto_msgTypes[17]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *JWKS) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*JWKS) ProtoMessage() {}
+
+func (x *JWKS) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[17]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use JWKS.ProtoReflect.Descriptor instead.
+func (*JWKS) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{17}
+}
+
+func (x *JWKS) GetKeys() []*JSONWebKey {
+	if x != nil {
+		return x.Keys
+	}
+	return nil
+}
+
+type Empty struct {
+	state         protoimpl.MessageState `protogen:"open.v1"`
+	unknownFields protoimpl.UnknownFields
+	sizeCache     protoimpl.SizeCache
+}
+
+func (x *Empty) Reset() {
+	*x = Empty{}
+	mi := &file_proto_book_proto_msgTypes[18]
+	ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+	ms.StoreMessageInfo(mi)
+}
+
+func (x *Empty) String() string {
+	return protoimpl.X.MessageStringOf(x)
+}
+
+func (*Empty) ProtoMessage() {}
+
+func (x *Empty) ProtoReflect() protoreflect.Message {
+	mi := &file_proto_book_proto_msgTypes[18]
+	if x != nil {
+		ms := protoimpl.X.MessageStateOf(protoimpl.Pointer(x))
+		if ms.LoadMessageInfo() == nil {
+			ms.StoreMessageInfo(mi)
+		}
+		return ms
+	}
+	return mi.MessageOf(x)
+}
+
+// Deprecated: Use Empty.ProtoReflect.Descriptor instead.
+func (*Empty) Descriptor() ([]byte, []int) {
+	return file_proto_book_proto_rawDescGZIP(), []int{18}
+}
+
+var File_proto_book_proto protoreflect.FileDescriptor
+
+const file_proto_book_proto_rawDesc = "" +
+	"\n" +
+	"\x10proto/book.proto\x12\x05proto\x1a\x10proto/auth.proto\"\\\n" +
+	"\x04Book\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x14\n" +
+	"\x05title\x18\x02 \x01(\tR\x05title\x12\x16\n" +
+	"\x06author\x18\x03 \x01(\tR\x06author\x12\x16\n" +
+	"\x06userid\x18\x04 \x01(\rR\x06userid\"\x18\n" +
+	"\x06BookId\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\"t\n" +
+	"\bBookList\x12!\n" +
+	"\x05books\x18\x01 \x03(\v2\v.proto.BookR\x05books\x12&\n" +
+	"\x0fnext_page_token\x18\x02 \x01(\tR\rnextPageToken\x12\x1d\n" +
+	"\n" +
+	"total_size\x18\x03 \x01(\x03R\ttotalSize\"\xbd\x01\n" +
+	"\x10ListBooksRequest\x12\x1b\n" +
+	"\tpage_size\x18\x01 \x01(\rR\bpageSize\x12\x1d\n" +
+	"\n" +
+	"page_token\x18\x02 \x01(\tR\tpageToken\x12\x19\n" +
+	"\border_by\x18\x03 \x01(\tR\aorderBy\x12\x16\n" +
+	"\x06author\x18\x04 \x01(\tR\x06author\x12\x17\n" +
+	"\auser_id\x18\x05 \x01(\rR\x06userId\x12!\n" +
+	"\ftitle_prefix\x18\x06 \x01(\tR\vtitlePrefix\"\x87\x01\n" +
+	"\x12StreamBooksRequest\x12\x1d\n" +
+	"\n" +
+	"batch_size\x18\x01 \x01(\rR\tbatchSize\x12\x16\n" +
+	"\x06author\x18\x02 \x01(\tR\x06author\x12\x17\n" +
+	"\auser_id\x18\x03 \x01(\rR\x06userId\x12!\n" +
+	"\ftitle_prefix\x18\x04 \x01(\tR\vtitlePrefix\"O\n" +
+	"\vImportError\x12\x10\n" +
+	"\x03row\x18\x01 \x01(\rR\x03row\x12\x14\n" +
+	"\x05field\x18\x02 \x01(\tR\x05field\x12\x18\n" +
+	"\amessage\x18\x03 \x01(\tR\amessage\"x\n" +
+	"\x13ImportBooksResponse\x12\x1f\n" +
+	"\vcreated_ids\x18\x01 \x03(\rR\n" +
+	"createdIds\x12*\n" +
+	"\x06errors\x18\x02 \x03(\v2\x12.proto.ImportErrorR\x06errors\x12\x14\n" +
+	"\x05total\x18\x03 \x01(\rR\x05total\"Q\n" +
+	"\x11WatchBooksRequest\x12#\n" +
+	"\rfrom_revision\x18\x01 \x01(\x04R\ffromRevision\x12\x17\n" +
+	"\auser_id\x18\x02 \x01(\rR\x06userId\"\xb8\x01\n" +
+	"\tBookEvent\x12\x1a\n" +
+	"\brevision\x18\x01 \x01(\x04R\brevision\x12)\n" +
+	"\x04type\x18\x02 \x01(\x0e2\x15.proto.BookEvent.TypeR\x04type\x12\x1f\n" +
+	"\x04book\x18\x03 \x01(\v2\v.proto.BookR\x04book\"C\n" +
+	"\x04Type\x12\x14\n" +
+	"\x10TYPE_UNSPECIFIED\x10\x00\x12\v\n" +
+	"\aCREATED\x10\x01\x12\v\n" +
+	"\aUPDATED\x10\x02\x12\v\n" +
+	"\aDELETED\x10\x03\"b\n" +
+	"\x04User\x12\x0e\n" +
+	"\x02id\x18\x01 \x01(\rR\x02id\x12\x12\n" +
+	"\x04name\x18\x02 \x01(\tR\x04name\x12\x1a\n" +
+	"\busername\x18\x03 \x01(\tR\busername\x12\x1a\n" +
+	"\bpassword\x18\x04 \x01(\tR\bpassword\"G\n" +
+	"\rSignInRequest\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\x12\x1a\n" +
+	"\bpassword\x18\x02 \x01(\tR\bpassword\"\x1c\n" +
+	"\x06UserId\x12\x12\n" +
+	"\x02id\x18\x01 \x01(\rB\x02\x18\x01R\x02id\"h\n" +
+	"\fAuthResponse\x12\x14\n" +
+	"\x05token\x18\x01 \x01(\tR\x05token\x12#\n" +
+	"\rrefresh_token\x18\x02 \x01(\tR\frefreshToken\x12\x1d\n" +
+	"\n" +
+	"expires_in\x18\x03 \x01(\x03R\texpiresIn\":\n" +
+	"\x13RefreshTokenRequest\x12#\n" +
+	"\rrefresh_token\x18\x01 \x01(\tR\frefreshToken\"5\n" +
+	"\x0eSignOutRequest\x12#\n" +
+	"\rrefresh_token\x18\x01 \x01(\tR\frefreshToken\"/\n" +
+	"\x11UnlockUserRequest\x12\x1a\n" +
+	"\busername\x18\x01 \x01(\tR\busername\"\x90\x01\n" +
+	"\n" +
+	"JSONWebKey\x12\x10\n" +
+	"\x03kty\x18\x01 \x01(\tR\x03kty\x12\x10\n" +
+	"\x03kid\x18\x02 \x01(\tR\x03kid\x12\x10\n" +
+	"\x03use\x18\x03 \x01(\tR\x03use\x12\x10\n" +
+	"\x03alg\x18\x04 \x01(\tR\x03alg\x12\f\n" +
+	"\x01n\x18\x05 \x01(\tR\x01n\x12\f\n" +
+	"\x01e\x18\x06 \x01(\tR\x01e\x12\x10\n" +
+	"\x03crv\x18\a \x01(\tR\x03crv\x12\f\n" +
+	"\x01x\x18\b \x01(\tR\x01x\"-\n" +
+	"\x04JWKS\x12%\n" +
+	"\x04keys\x18\x01 \x03(\v2\x11.proto.JSONWebKeyR\x04keys\"\a\n" +
+	"\x05Empty2\x80\x03\n" +
+	"\vUserService\x12,\n" +
+	"\x06SignUp\x12\v.proto.User\x1a\r.proto.UserId\"\x06\x8a\xb5\x18\x02\b\x01\x12;\n" +
+	"\x06SignIn\x12\x14.proto.SignInRequest\x1a\x13.proto.AuthResponse\"\x06\x8a\xb5\x18\x02\b\x01\x12G\n" +
+	"\fRefreshToken\x12\x1a.proto.RefreshTokenRequest\x1a\x13.proto.AuthResponse\"\x06\x8a\xb5\x18\x02\b\x01\x12L\n" +
+	"\aSignOut\x12\x15.proto.SignOutRequest\x1a\f.proto.Empty\"\x1c\x8a\xb5\x18\x18\x12\x04user\x12\tmoderator\x12\x05admin\x12,\n" +
+	"\aGetJWKS\x12\f.proto.Empty\x1a\v.proto.JWKS\"\x06\x8a\xb5\x18\x02\b\x01\x12A\n" +
+	"\n" +
+	"UnlockUser\x12\x18.proto.UnlockUserRequest\x1a\f.proto.Empty\"\v\x8a\xb5\x18\a\x12\x05admin2\x9f\x05\n" +
+	"\vBookService\x12F\n" +
+	"\n" +
+	"CreateBook\x12\v.proto.Book\x1a\r.proto.BookId\"\x1c\x8a\xb5\x18\x18\x12\x04user\x12\tmoderator\x12\x05admin\x12C\n" +
+	"\aGetBook\x12\r.proto.BookId\x1a\v.proto.Book\"\x1c\x8a\xb5\x18\x18\x12\x04user\x12\tmoderator\x12\x05admin\x12R\n" +
+	"\bGetBooks\x12\x17.proto.ListBooksRequest\x1a\x0f.proto.BookList\"\x1c\x8a\xb5\x18\x18\x12\x04user\x12\tmoderator\x12\x05admin\x12K\n" +
 	"\n" +
-	"CreateBook\x12\v.proto.Book\x1a\r.proto.BookId\x12%\n" +
-	"\aGetBook\x12\r.proto.BookId\x1a\v.proto.Book\x12)\n" +
-	"\bGetBooks\x12\f.proto.Empty\x1a\x0f.proto.BookList\x12&\n" +
+	"UpdateBook\x12\v.proto.Book\x1a\v.proto.Book\"#\x8a\xb5\x18\x1f\x12\x04user\x12\tmoderator\x12\x05admin\x1a\x05admin\x12Y\n" +
 	"\n" +
-	"UpdateBook\x12\v.proto.Book\x1a\v.proto.Book\x12)\n" +
+	"DeleteBook\x12\r.proto.BookId\x1a\f.proto.Empty\".\x8a\xb5\x18*\x12\x04user\x12\tmoderator\x12\x05admin\x1a\tmoderator\x1a\x05admin\x12U\n" +
+	"\vStreamBooks\x12\x19.proto.StreamBooksRequest\x1a\v.proto.Book\"\x1c\x8a\xb5\x18\x18\x12\x04user\x12\tmoderator\x12\x05admin0\x01\x12V\n" +
+	"\vImportBooks\x12\v.proto.Book\x1a\x1a.proto.ImportBooksResponse\"\x1c\x8a\xb5\x18\x18\x12\x04user\x12\tmoderator\x12\x05admin(\x01\x12X\n" +
 	"\n" +
-	"DeleteBook\x12\r.proto.BookId\x1a\f.proto.EmptyB\bZ\x06/protob\x06proto3"
+	"WatchBooks\x12\x18.proto.WatchBooksRequest\x1a\x10.proto.BookEvent\"\x1c\x8a\xb5\x18\x18\x12\x04user\x12\tmoderator\x12\x05admin0\x01B\bZ\x06/protob\x06proto3"
 
 var (
 	file_proto_book_proto_rawDescOnce sync.Once
@@ -473,38 +1300,69 @@ func file_proto_book_proto_rawDescGZIP() []byte {
 	return file_proto_book_proto_rawDescData
 }
 
-var file_proto_book_proto_msgTypes = make([]protoimpl.MessageInfo, 8)
+var file_proto_book_proto_enumTypes = make([]protoimpl.EnumInfo, 1)
+var file_proto_book_proto_msgTypes = make([]protoimpl.MessageInfo, 19)
 var file_proto_book_proto_goTypes = []any{
-	(*Book)(nil),          // 0: proto.Book
-	(*BookId)(nil),        // 1: proto.BookId
-	(*BookList)(nil),      // 2: proto.BookList
-	(*User)(nil),          // 3: proto.User
-	(*SignInRequest)(nil), // 4: proto.SignInRequest
-	(*UserId)(nil),        // 5: proto.UserId
-	(*AuthResponse)(nil),  // 6: proto.AuthResponse
-	(*Empty)(nil),         // 7: proto.Empty
+	(BookEvent_Type)(0),         // 0: proto.BookEvent.Type
+	(*Book)(nil),                // 1: proto.Book
+	(*BookId)(nil),              // 2: proto.BookId
+	(*BookList)(nil),            // 3: proto.BookList
+	(*ListBooksRequest)(nil),    // 4: proto.ListBooksRequest
+	(*StreamBooksRequest)(nil),  // 5: proto.StreamBooksRequest
+	(*ImportError)(nil),         // 6: proto.ImportError
+	(*ImportBooksResponse)(nil), // 7: proto.ImportBooksResponse
+	(*WatchBooksRequest)(nil),   // 8: proto.WatchBooksRequest
+	(*BookEvent)(nil),           // 9: proto.BookEvent
+	(*User)(nil),                // 10: proto.User
+	(*SignInRequest)(nil),       // 11: proto.SignInRequest
+	(*UserId)(nil),              // 12: proto.UserId
+	(*AuthResponse)(nil),        // 13: proto.AuthResponse
+	(*RefreshTokenRequest)(nil), // 14: proto.RefreshTokenRequest
+	(*SignOutRequest)(nil),      // 15: proto.SignOutRequest
+	(*UnlockUserRequest)(nil),   // 16: proto.UnlockUserRequest
+	(*JSONWebKey)(nil),          // 17: proto.JSONWebKey
+	(*JWKS)(nil),                // 18: proto.JWKS
+	(*Empty)(nil),               // 19: proto.Empty
 }
 var file_proto_book_proto_depIdxs = []int32{
-	0, // 0: proto.BookList.books:type_name -> proto.Book
-	3, // 1: proto.UserService.SignUp:input_type -> proto.User
-	4, // 2: proto.UserService.SignIn:input_type -> proto.SignInRequest
-	0, // 3: proto.BookService.CreateBook:input_type -> proto.Book
-	1, // 4: proto.BookService.GetBook:input_type -> proto.BookId
-	7, // 5: proto.BookService.GetBooks:input_type -> proto.Empty
-	0, // 6: proto.BookService.UpdateBook:input_type -> proto.Book
-	1, // 7: proto.BookService.DeleteBook:input_type -> proto.BookId
-	5, // 8: proto.UserService.SignUp:output_type -> proto.UserId
-	6, // 9: proto.UserService.SignIn:output_type -> proto.AuthResponse
-	1, // 10: proto.BookService.CreateBook:output_type -> proto.BookId
-	0, // 11: proto.BookService.GetBook:output_type -> proto.Book
-	2, // 12: proto.BookService.GetBooks:output_type -> proto.BookList
-	0, // 13: proto.BookService.UpdateBook:output_type -> proto.Book
-	7, // 14: proto.BookService.DeleteBook:output_type -> proto.Empty
-	8, // [8:15] is the sub-list for method output_type
-	1, // [1:8] is the sub-list for method input_type
-	1, // [1:1] is the sub-list for extension type_name
-	1, // [1:1] is the sub-list for extension extendee
-	0, // [0:1] is the sub-list for field type_name
+	1,  // 0: proto.BookList.books:type_name -> proto.Book
+	6,  // 1: proto.ImportBooksResponse.errors:type_name -> proto.ImportError
+	0,  // 2: proto.BookEvent.type:type_name -> proto.BookEvent.Type
+	1,  // 3: proto.BookEvent.book:type_name -> proto.Book
+	17, // 4: proto.JWKS.keys:type_name -> proto.JSONWebKey
+	10, // 5: proto.UserService.SignUp:input_type -> proto.User
+	11, // 6: proto.UserService.SignIn:input_type -> proto.SignInRequest
+	14, // 7: proto.UserService.RefreshToken:input_type -> proto.RefreshTokenRequest
+	15, // 8: proto.UserService.SignOut:input_type -> proto.SignOutRequest
+	19, // 9: proto.UserService.GetJWKS:input_type -> proto.Empty
+	16, // 10: proto.UserService.UnlockUser:input_type -> proto.UnlockUserRequest
+	1,  // 11: proto.BookService.CreateBook:input_type -> proto.Book
+	2,  // 12: proto.BookService.GetBook:input_type -> proto.BookId
+	4,  // 13: proto.BookService.GetBooks:input_type -> proto.ListBooksRequest
+	1,  // 14: proto.BookService.UpdateBook:input_type -> proto.Book
+	2,  // 15: proto.BookService.DeleteBook:input_type -> proto.BookId
+	5,  // 16: proto.BookService.StreamBooks:input_type -> proto.StreamBooksRequest
+	1,  // 17: proto.BookService.ImportBooks:input_type -> proto.Book
+	8,  // 18: proto.BookService.WatchBooks:input_type -> proto.WatchBooksRequest
+	12, // 19: proto.UserService.SignUp:output_type -> proto.UserId
+	13, // 20: proto.UserService.SignIn:output_type -> proto.AuthResponse
+	13, // 21: proto.UserService.RefreshToken:output_type -> proto.AuthResponse
+	19, // 22: proto.UserService.SignOut:output_type -> proto.Empty
+	18, // 23: proto.UserService.GetJWKS:output_type -> proto.JWKS
+	19, // 24: proto.UserService.UnlockUser:output_type -> proto.Empty
+	2,  // 25: proto.BookService.CreateBook:output_type -> proto.BookId
+	1,  // 26: proto.BookService.GetBook:output_type -> proto.Book
+	3,  // 27: proto.BookService.GetBooks:output_type -> proto.BookList
+	1,  // 28: proto.BookService.UpdateBook:output_type -> proto.Book
+	19, // 29: proto.BookService.DeleteBook:output_type -> proto.Empty
+	1,  // 30: proto.BookService.StreamBooks:output_type -> proto.Book
+	7,  // 31: proto.BookService.ImportBooks:output_type -> proto.ImportBooksResponse
+	9,  // 32: proto.BookService.WatchBooks:output_type -> proto.BookEvent
+	19, // [19:33] is the sub-list for method output_type
+	5,  // [5:19] is the sub-list for method input_type
+	5,  // [5:5] is the sub-list for extension type_name
+	5,  // [5:5] is the sub-list for extension extendee
+	0,  // [0:5] is the sub-list for field type_name
 }
 
 func init() { file_proto_book_proto_init() }
@@ -512,18 +1370,20 @@ func file_proto_book_proto_init() {
 	if File_proto_book_proto != nil {
 		return
 	}
+	file_proto_auth_proto_init()
 	type x struct{}
 	out := protoimpl.TypeBuilder{
 		File: protoimpl.DescBuilder{
 			GoPackagePath: reflect.TypeOf(x{}).PkgPath(),
 			RawDescriptor: unsafe.Slice(unsafe.StringData(file_proto_book_proto_rawDesc), len(file_proto_book_proto_rawDesc)),
-			NumEnums:      0,
-			NumMessages:   8,
+			NumEnums:      1,
+			NumMessages:   19,
 			NumExtensions: 0,
 			NumServices:   2,
 		},
 		GoTypes:           file_proto_book_proto_goTypes,
 		DependencyIndexes: file_proto_book_proto_depIdxs,
+		EnumInfos:         file_proto_book_proto_enumTypes,
 		MessageInfos:      file_proto_book_proto_msgTypes,
 	}.Build()
 	File_proto_book_proto = out.File
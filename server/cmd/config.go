@@ -0,0 +1,182 @@
+package main
+
+import (
+	"fmt"
+	"grpc/proto"
+	grpcserver "grpc/server"
+	"grpc/server/pkg/health"
+	"grpc/server/pkg/logging"
+	"grpc/server/pkg/metrics"
+	"grpc/server/pkg/password"
+	"grpc/server/pkg/policy"
+	"grpc/server/pkg/ratelimit"
+	"grpc/server/pkg/repository"
+	"grpc/server/pkg/service"
+	"grpc/server/pkg/tlsconfig"
+	"grpc/server/pkg/tracing"
+	"log/slog"
+	"os"
+	"strings"
+
+	"github.com/fsnotify/fsnotify"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
+	"github.com/spf13/viper"
+)
+
+func initConfig() error {
+	viper.AddConfigPath("server/configs")
+	viper.SetConfigName("config")
+	// Every key can be overridden from the environment, e.g. server.tls.cert_file
+	// by SERVER_TLS_CERT_FILE.
+	viper.SetEnvKeyReplacer(strings.NewReplacer(".", "_"))
+	viper.AutomaticEnv()
+	return viper.ReadInConfig()
+}
+
+// setupLogging installs the configured logger as the default, which also
+// routes the log package through it. log.level is reread whenever the
+// config file changes, so verbosity can be raised without a restart.
+func setupLogging() (*slog.Logger, error) {
+	level := new(slog.LevelVar)
+	initial, err := logging.ParseLevel(viper.GetString("log.level"))
+	if err != nil {
+		return nil, err
+	}
+	level.Set(initial)
+
+	logger, err := logging.New(os.Stderr, viper.GetString("log.format"), level)
+	if err != nil {
+		return nil, err
+	}
+	slog.SetDefault(logger)
+
+	viper.OnConfigChange(func(fsnotify.Event) {
+		next, err := logging.ParseLevel(viper.GetString("log.level"))
+		if err != nil {
+			logger.Warn("ignoring log level change", "error", err)
+			return
+		}
+		if next != level.Level() {
+			level.Set(next)
+			logger.Info("log level changed", "level", next.String())
+		}
+	})
+	viper.WatchConfig()
+	return logger, nil
+}
+
+func tracingConfig() tracing.Config {
+	return tracing.Config{
+		Exporter:    viper.GetString("tracing.exporter"),
+		Endpoint:    viper.GetString("tracing.endpoint"),
+		Insecure:    viper.GetBool("tracing.insecure"),
+		File:        viper.GetString("tracing.file"),
+		SampleRatio: viper.GetFloat64("tracing.sample_ratio"),
+		ServiceName: "book-server",
+	}
+}
+
+func serverConfig() grpcserver.Config {
+	return grpcserver.Config{
+		Address:        viper.GetString("server.address"),
+		DefaultTimeout: viper.GetDuration("server.default_timeout"),
+		TLS: tlsconfig.ServerConfig{
+			CertFile:     viper.GetString("server.tls.cert_file"),
+			KeyFile:      viper.GetString("server.tls.key_file"),
+			ClientCAFile: viper.GetString("server.tls.client_ca_file"),
+			MinVersion:   viper.GetString("server.tls.min_version"),
+		},
+		TrustedProxies: viper.GetStringSlice("server.trusted_proxies"),
+	}
+}
+
+// openRepository opens the configured storage, migrating and instrumenting
+// the database if there is one.
+func openRepository(appMetrics *metrics.Metrics, registry *prometheus.Registry) (*repository.Repository, error) {
+	var repo *repository.Repository
+	if driver := viper.GetString("storage.driver"); driver == "memory" {
+		repo = repository.NewMemoryRepository()
+	} else {
+		db := openDB(driver)
+		if viper.GetBool("storage.migrate_on_start") {
+			if err := migrateUp(db); err != nil {
+				return nil, fmt.Errorf("migrating database: %w", err)
+			}
+		}
+		if err := appMetrics.InstrumentGORM(db); err != nil {
+			return nil, fmt.Errorf("instrumenting database: %w", err)
+		}
+		if err := tracing.InstrumentGORM(db); err != nil {
+			return nil, fmt.Errorf("instrumenting database: %w", err)
+		}
+		sqlDB, err := db.DB()
+		if err != nil {
+			return nil, fmt.Errorf("instrumenting database: %w", err)
+		}
+		registry.MustRegister(collectors.NewDBStatsCollector(sqlDB, driver))
+		repo = repository.NewRepository(db)
+	}
+	if viper.GetString("auth.revocation.store") == "memory" {
+		repo.Revocation = repository.NewRevocationMemory()
+	}
+	return repo, nil
+}
+
+// accessPolicy combines the rules declared in the proto files with the
+// overrides under auth.policy.
+func accessPolicy() (*policy.Policy, error) {
+	rules, err := policy.FromFile(proto.File_proto_book_proto)
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth policy: %w", err)
+	}
+	rules = append(rules, health.PolicyRule())
+	var overrides []policy.Rule
+	if err := viper.UnmarshalKey("auth.policy", &overrides); err != nil {
+		return nil, fmt.Errorf("reading auth policy: %w", err)
+	}
+	accessPolicy, err := policy.New(policy.Override(rules, overrides))
+	if err != nil {
+		return nil, fmt.Errorf("invalid auth policy: %w", err)
+	}
+	return accessPolicy, nil
+}
+
+func rateLimiter() (*ratelimit.Limiter, error) {
+	var cfg ratelimit.Config
+	if err := viper.UnmarshalKey("rate_limit", &cfg); err != nil {
+		return nil, fmt.Errorf("reading rate limits: %w", err)
+	}
+	limiter, err := ratelimit.New(cfg)
+	if err != nil {
+		return nil, fmt.Errorf("invalid rate limits: %w", err)
+	}
+	return limiter, nil
+}
+
+// serviceConfig reads the lockout policy and password settings.
+func serviceConfig() (service.Config, error) {
+	cfg := service.Config{Lockout: service.DefaultLockoutPolicy()}
+	if err := viper.UnmarshalKey("auth.lockout", &cfg.Lockout); err != nil {
+		return service.Config{}, fmt.Errorf("reading lockout policy: %w", err)
+	}
+	cfg.Lockout = cfg.Lockout.WithDefaults()
+
+	hashConfig := password.DefaultConfig()
+	if err := viper.UnmarshalKey("auth.password.hashing", &hashConfig); err != nil {
+		return service.Config{}, fmt.Errorf("reading password hashing config: %w", err)
+	}
+	var err error
+	if cfg.Hasher, err = password.New(hashConfig); err != nil {
+		return service.Config{}, fmt.Errorf("invalid password hashing config: %w", err)
+	}
+
+	passwordPolicy := password.DefaultPolicy()
+	if err := viper.UnmarshalKey("auth.password.policy", &passwordPolicy); err != nil {
+		return service.Config{}, fmt.Errorf("reading password policy: %w", err)
+	}
+	if cfg.PasswordPolicy, err = password.NewChecker(passwordPolicy); err != nil {
+		return service.Config{}, fmt.Errorf("invalid password policy: %w", err)
+	}
+	return cfg, nil
+}
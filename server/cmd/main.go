@@ -1,16 +1,28 @@
 package main
 
 import (
+	"context"
+	"fmt"
+	"grpc/proto"
 	grpcserver "grpc/server"
-	"grpc/server/models"
 	"grpc/server/pkg/handler"
+	"grpc/server/pkg/health"
+	"grpc/server/pkg/metrics"
 	"grpc/server/pkg/repository"
 	"grpc/server/pkg/service"
+	"grpc/server/pkg/tracing"
 	"log"
+	"log/slog"
+	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 
 	"github.com/joho/godotenv"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/collectors"
 	"github.com/spf13/viper"
+	"google.golang.org/grpc"
 )
 
 func main() {
@@ -22,25 +34,103 @@ func main() {
 		log.Fatalf("error loading env variables: %s", err.Error())
 	}
 
-	db := repository.NewPostgresDB(repository.Config{
-		Host:     viper.GetString("db.host"),
-		Port:     viper.GetString("db.port"),
-		Username: viper.GetString("db.username"),
-		DBName:   viper.GetString("db.dbname"),
-		SSLMode:  viper.GetString("db.sslmode"),
-		Password: os.Getenv("DB_PASSWORD"),
-	})
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		runMigrate(os.Args[2:])
+		return
+	}
 
-	db.AutoMigrate(&models.Book{})
-	repo := repository.NewRepository(db)
-	service := service.NewService(repo)
-	handler := handler.NewHandler(service)
+	logger, err := setupLogging()
+	if err != nil {
+		log.Fatalf("error configuring logging: %s", err.Error())
+	}
 
-	grpcserver.RunServer(handler, service)
+	ctx, stop := signal.NotifyContext(context.Background(), syscall.SIGINT, syscall.SIGTERM)
+	err = run(ctx, logger)
+	stop()
+	if err != nil {
+		logger.Error("server failed", "error", err)
+		os.Exit(1)
+	}
 }
 
-func initConfig() error {
-	viper.AddConfigPath("server/configs")
-	viper.SetConfigName("config")
-	return viper.ReadInConfig()
+// run serves until ctx is cancelled, which also stops the background jobs,
+// and then shuts the server down gracefully.
+func run(ctx context.Context, logger *slog.Logger) error {
+	shutdownTracing, err := tracing.Setup(ctx, tracingConfig())
+	if err != nil {
+		return fmt.Errorf("configuring tracing: %w", err)
+	}
+
+	registry := prometheus.NewRegistry()
+	registry.MustRegister(collectors.NewGoCollector(), collectors.NewProcessCollector(collectors.ProcessCollectorOpts{}))
+	appMetrics := metrics.New(registry)
+
+	repo, err := openRepository(appMetrics, registry)
+	if err != nil {
+		return err
+	}
+	accessPolicy, err := accessPolicy()
+	if err != nil {
+		return err
+	}
+	limiter, err := rateLimiter()
+	if err != nil {
+		return err
+	}
+	serviceConfig, err := serviceConfig()
+	if err != nil {
+		return err
+	}
+
+	go repository.PruneRevocations(ctx, repo.Revocation, viper.GetDuration("auth.revocation.prune_interval"))
+	go repository.PruneLoginHistory(ctx, repo.LoginAttempts, viper.GetDuration("auth.lockout.prune_interval"),
+		serviceConfig.Lockout.ResetAfter, viper.GetDuration("auth.lockout.event_retention"))
+
+	service := service.NewService(repo, serviceConfig)
+	service.Authorization = appMetrics.InstrumentAuth(service.Authorization)
+	handler := handler.NewHandler(service)
+
+	monitor := health.NewMonitor(repo.Ping,
+		viper.GetDuration("health.check_interval"), viper.GetDuration("health.check_timeout"),
+		proto.BookService_ServiceDesc.ServiceName, proto.UserService_ServiceDesc.ServiceName,
+	)
+	go monitor.Run(ctx)
+
+	server, err := grpcserver.NewBookServer(handler, service, accessPolicy, serverConfig(),
+		grpcserver.WithHealth(monitor), grpcserver.WithMetrics(appMetrics), grpcserver.WithLogger(logger),
+		grpcserver.WithRateLimit(limiter),
+		grpcserver.WithServerOptions(grpc.StatsHandler(tracing.ServerHandler())))
+	if err != nil {
+		return fmt.Errorf("configuring server: %w", err)
+	}
+	var metricsServer *http.Server
+	if address := viper.GetString("metrics.address"); address != "" {
+		metricsServer = metrics.Serve(address, registry)
+	}
+
+	if err := server.Start(ctx); err != nil {
+		return fmt.Errorf("failed to listen: %w", err)
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-server.Done():
+		return fmt.Errorf("failed to serve: %w", server.Err())
+	}
+
+	logger.Info("shutting down gRPC server")
+	shutdownCtx, cancel := context.WithTimeout(context.Background(), viper.GetDuration("server.shutdown_timeout"))
+	defer cancel()
+	if err := server.Stop(shutdownCtx); err != nil {
+		logger.Error("failed to stop gRPC server", "error", err)
+	}
+	if metricsServer != nil {
+		if err := metricsServer.Shutdown(shutdownCtx); err != nil {
+			logger.Error("failed to stop metrics server", "error", err)
+		}
+	}
+	if err := shutdownTracing(shutdownCtx); err != nil {
+		logger.Error("failed to flush traces", "error", err)
+	}
+	return nil
 }
@@ -0,0 +1,115 @@
+package main
+
+import (
+	"context"
+	"fmt"
+	"grpc/server/pkg/migrate"
+	"grpc/server/pkg/repository"
+	"log"
+	"log/slog"
+	"os"
+	"strconv"
+
+	"github.com/spf13/viper"
+	"gorm.io/gorm"
+)
+
+const migrateUsage = "usage: server migrate up | down [steps] | status"
+
+// runMigrate implements the "migrate" subcommand against the configured
+// database.
+func runMigrate(args []string) {
+	if len(args) == 0 {
+		log.Fatal(migrateUsage)
+	}
+
+	driver := viper.GetString("storage.driver")
+	if driver == "memory" {
+		log.Fatal("the memory storage driver has no schema to migrate")
+	}
+	migrator, err := migrate.New(openDB(driver))
+	if err != nil {
+		log.Fatalf("error migrating database: %s", err.Error())
+	}
+	ctx := context.Background()
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		report("applied", applied)
+		if err != nil {
+			log.Fatalf("error migrating database: %s", err.Error())
+		}
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			if steps, err = strconv.Atoi(args[1]); err != nil || steps < 1 {
+				log.Fatal(migrateUsage)
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		report("reverted", reverted)
+		if err != nil {
+			log.Fatalf("error migrating database: %s", err.Error())
+		}
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			log.Fatalf("error reading migration status: %s", err.Error())
+		}
+		for _, s := range statuses {
+			state := "pending"
+			if s.AppliedAt != nil {
+				state = "applied " + s.AppliedAt.Format("2006-01-02 15:04:05")
+			}
+			fmt.Printf("%04d_%s\t%s\n", s.Version, s.Name, state)
+		}
+	default:
+		log.Fatal(migrateUsage)
+	}
+}
+
+func report(verb string, migrations []migrate.Migration) {
+	if len(migrations) == 0 {
+		fmt.Println("nothing to do")
+	}
+	for _, m := range migrations {
+		fmt.Printf("%s %04d_%s\n", verb, m.Version, m.Name)
+	}
+}
+
+// migrateUp applies pending migrations at startup, logging each one through
+// slog rather than printing like the migrate subcommand.
+func migrateUp(db *gorm.DB) error {
+	migrator, err := migrate.New(db)
+	if err != nil {
+		return err
+	}
+	applied, err := migrator.Up(context.Background())
+	for _, m := range applied {
+		slog.Info("applied migration", "version", m.Version, "name", m.Name)
+	}
+	if err == nil && len(applied) == 0 {
+		slog.Debug("database schema is up to date")
+	}
+	return err
+}
+
+func openDB(driver string) *gorm.DB {
+	switch driver {
+	case "sqlite":
+		return repository.NewSQLiteDB(viper.GetString("storage.sqlite.path"))
+	case "", "postgres":
+		return repository.NewPostgresDB(repository.Config{
+			Host:     viper.GetString("db.host"),
+			Port:     viper.GetString("db.port"),
+			Username: viper.GetString("db.username"),
+			DBName:   viper.GetString("db.dbname"),
+			SSLMode:  viper.GetString("db.sslmode"),
+			Password: os.Getenv("DB_PASSWORD"),
+		})
+	default:
+		log.Fatalf("unknown storage driver %q", driver)
+		return nil
+	}
+}
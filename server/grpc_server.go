@@ -1,47 +1,303 @@
 package grpcserver
 
 import (
+	"context"
+	"errors"
 	"grpc/proto"
+	"grpc/server/pkg/clientip"
 	"grpc/server/pkg/handler"
+	"grpc/server/pkg/health"
+	"grpc/server/pkg/metrics"
+	"grpc/server/pkg/policy"
+	"grpc/server/pkg/ratelimit"
 	"grpc/server/pkg/service"
-	"log"
+	"grpc/server/pkg/tlsconfig"
+	"log/slog"
 	"net"
-	"os"
-	"os/signal"
-	"syscall"
+	"sync"
+	"time"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
 )
 
-func RunServer(h *handler.Handler, s *service.Service) {
-	lis, err := net.Listen("tcp", ":50051")
+// Config holds the transport settings of the gRPC server.
+type Config struct {
+	// Address is the TCP address to listen on, ":50051" when empty.
+	Address string
+	// DefaultTimeout bounds unary calls that arrive without a deadline.
+	DefaultTimeout time.Duration
+	// TLS enables transport security when a certificate is configured.
+	TLS tlsconfig.ServerConfig
+	// TrustedProxies lists the addresses or CIDR ranges whose
+	// x-forwarded-for metadata names the client, such as the REST proxy.
+	TrustedProxies []string
+}
+
+// NewBookServer assembles the server for the user and book services with
+// the client address, error, deadline and auth interceptors. opts are applied after the
+// ones derived from cfg, so a test can, for example, pass its own listener.
+func NewBookServer(h *handler.Handler, s *service.Service, p *policy.Policy, cfg Config, opts ...Option) (*Server, error) {
+	clients, err := clientip.New(cfg.TrustedProxies)
 	if err != nil {
-		log.Fatalf("failed to listen: %v", err)
+		return nil, err
+	}
+	base := []Option{
+		WithAddress(cfg.Address),
+		WithClientIP(clients),
+		WithUnaryInterceptors(
+			handler.UnaryErrorInterceptor(),
+			handler.UnaryDeadlineInterceptor(cfg.DefaultTimeout),
+			handler.UnaryAuthInterceptor(s, p),
+			handler.UnaryLogUserInterceptor(),
+		),
+		WithStreamInterceptors(
+			handler.StreamErrorInterceptor(),
+			handler.StreamAuthInterceptor(s, p),
+			handler.StreamLogUserInterceptor(),
+		),
+		WithService(&proto.UserService_ServiceDesc, h.AuthHandler),
+		WithService(&proto.BookService_ServiceDesc, h.BookHandler),
+	}
+	if cfg.TLS.Enabled() {
+		tlsConfig, err := tlsconfig.Server(cfg.TLS)
+		if err != nil {
+			return nil, err
+		}
+		base = append(base, WithServerOptions(grpc.Creds(credentials.NewTLS(tlsConfig))))
+	}
+	return New(append(base, opts...)...), nil
+}
+
+type options struct {
+	address       string
+	listener      net.Listener
+	unary         []grpc.UnaryServerInterceptor
+	stream        []grpc.StreamServerInterceptor
+	serverOptions []grpc.ServerOption
+	services      []registration
+	health        *health.Monitor
+	metrics       *metrics.Metrics
+	clientIP      *clientip.Resolver
+	rateLimit     *ratelimit.Limiter
+	logger        *slog.Logger
+}
+
+type registration struct {
+	desc *grpc.ServiceDesc
+	impl interface{}
+}
+
+// Option configures a Server.
+type Option func(*options)
+
+// WithAddress sets the TCP address to listen on when no listener is given.
+func WithAddress(address string) Option {
+	return func(o *options) {
+		if address != "" {
+			o.address = address
+		}
+	}
+}
+
+// WithListener serves on lis instead of listening on the address.
+func WithListener(lis net.Listener) Option {
+	return func(o *options) { o.listener = lis }
+}
+
+// WithUnaryInterceptors appends unary interceptors; they run in the order
+// given.
+func WithUnaryInterceptors(interceptors ...grpc.UnaryServerInterceptor) Option {
+	return func(o *options) { o.unary = append(o.unary, interceptors...) }
+}
+
+// WithStreamInterceptors appends stream interceptors; they run in the order
+// given.
+func WithStreamInterceptors(interceptors ...grpc.StreamServerInterceptor) Option {
+	return func(o *options) { o.stream = append(o.stream, interceptors...) }
+}
+
+// WithServerOptions passes additional options to grpc.NewServer.
+func WithServerOptions(opts ...grpc.ServerOption) Option {
+	return func(o *options) { o.serverOptions = append(o.serverOptions, opts...) }
+}
+
+// WithService registers impl as the implementation of desc.
+func WithService(desc *grpc.ServiceDesc, impl interface{}) Option {
+	return func(o *options) { o.services = append(o.services, registration{desc: desc, impl: impl}) }
+}
+
+// WithHealth registers the grpc.health.v1 Health service backed by m. Stop
+// reports NOT_SERVING through it before draining connections.
+func WithHealth(m *health.Monitor) Option {
+	return func(o *options) {
+		o.health = m
+		o.services = append(o.services, registration{desc: &healthpb.Health_ServiceDesc, impl: m.Server()})
+	}
+}
+
+// WithMetrics records every call in m. Its interceptors run before all
+// others, so they see the final status code and the time spent in them.
+func WithMetrics(m *metrics.Metrics) Option {
+	return func(o *options) { o.metrics = m }
+}
+
+// WithClientIP resolves the client address of every call with r. Its
+// interceptors run before the ones added with WithUnaryInterceptors and
+// WithStreamInterceptors.
+func WithClientIP(r *clientip.Resolver) Option {
+	return func(o *options) { o.clientIP = r }
+}
+
+// WithRateLimit throttles calls with l. Its address rules are checked right
+// after the client address is resolved, before any authentication; its
+// other rules run after all other interceptors, so authenticated callers are
+// limited by user id.
+func WithRateLimit(l *ratelimit.Limiter) Option {
+	return func(o *options) { o.rateLimit = l }
+}
+
+// WithLogger sets the logger for lifecycle messages and the per-call log
+// lines; slog.Default() is used otherwise.
+func WithLogger(logger *slog.Logger) Option {
+	return func(o *options) { o.logger = logger }
+}
+
+// Server is a gRPC server with an explicit lifecycle: Start begins serving
+// in the background and Stop drains it.
+type Server struct {
+	grpcServer *grpc.Server
+	opts       options
+
+	mu       sync.Mutex
+	listener net.Listener
+	done     chan struct{}
+	err      error
+}
+
+func New(opts ...Option) *Server {
+	o := options{
+		address: ":50051",
+		logger:  slog.Default(),
+	}
+	for _, opt := range opts {
+		opt(&o)
 	}
 
-	grpcServer := grpc.NewServer(
-		grpc.UnaryInterceptor(handler.UnaryAuthInterceptor(s)),
-	)
+	if o.rateLimit != nil {
+		o.unary = append(o.unary, o.rateLimit.UnaryServerInterceptor())
+		o.stream = append(o.stream, o.rateLimit.StreamServerInterceptor())
+		o.unary = append([]grpc.UnaryServerInterceptor{o.rateLimit.AddressUnaryServerInterceptor()}, o.unary...)
+		o.stream = append([]grpc.StreamServerInterceptor{o.rateLimit.AddressStreamServerInterceptor()}, o.stream...)
+	}
+	if o.clientIP != nil {
+		o.unary = append([]grpc.UnaryServerInterceptor{o.clientIP.UnaryServerInterceptor()}, o.unary...)
+		o.stream = append([]grpc.StreamServerInterceptor{o.clientIP.StreamServerInterceptor()}, o.stream...)
+	}
+	// Every call is logged, and measured when metrics are enabled, before
+	// any other interceptor can reject it or translate its error.
+	o.unary = append([]grpc.UnaryServerInterceptor{handler.UnaryLoggingInterceptor(o.logger)}, o.unary...)
+	o.stream = append([]grpc.StreamServerInterceptor{handler.StreamLoggingInterceptor(o.logger)}, o.stream...)
+	if o.metrics != nil {
+		o.unary = append([]grpc.UnaryServerInterceptor{o.metrics.UnaryServerInterceptor()}, o.unary...)
+		o.stream = append([]grpc.StreamServerInterceptor{o.metrics.StreamServerInterceptor()}, o.stream...)
+	}
+
+	grpcServer := grpc.NewServer(append(o.serverOptions,
+		grpc.ChainUnaryInterceptor(o.unary...),
+		grpc.ChainStreamInterceptor(o.stream...),
+	)...)
+	for _, s := range o.services {
+		grpcServer.RegisterService(s.desc, s.impl)
+	}
+
+	return &Server{grpcServer: grpcServer, opts: o}
+}
 
-	proto.RegisterUserServiceServer(grpcServer, h.AuthHandler)
-	proto.RegisterBookServiceServer(grpcServer, h.BookHandler)
+// Start listens, unless a listener was provided, and serves in the
+// background. It returns once the server accepts connections.
+func (s *Server) Start(ctx context.Context) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done != nil {
+		return errors.New("server already started")
+	}
+
+	lis := s.opts.listener
+	if lis == nil {
+		var err error
+		lis, err = new(net.ListenConfig).Listen(ctx, "tcp", s.opts.address)
+		if err != nil {
+			return err
+		}
+	}
+	s.listener = lis
+	s.done = make(chan struct{})
 
-	// Запуск сервера в горутине
 	go func() {
-		log.Println("gRPC server running on :50051")
-		if err := grpcServer.Serve(lis); err != nil {
-			log.Fatalf("failed to serve: %v", err)
+		defer close(s.done)
+		if err := s.grpcServer.Serve(lis); err != nil {
+			s.opts.logger.Error("gRPC server failed", "error", err)
+			s.mu.Lock()
+			s.err = err
+			s.mu.Unlock()
 		}
 	}()
 
-	// Ловим сигналы OS
-	quit := make(chan os.Signal, 1)
-	signal.Notify(quit, syscall.SIGINT, syscall.SIGTERM)
-	<-quit
+	s.opts.logger.Info("gRPC server running", "address", lis.Addr().String())
+	return nil
+}
+
+// Addr returns the address the server listens on, or nil before Start.
+func (s *Server) Addr() net.Addr {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.listener == nil {
+		return nil
+	}
+	return s.listener.Addr()
+}
+
+// Done is closed when the server stops serving, whether through Stop or
+// because Serve failed; Err then reports the failure.
+func (s *Server) Done() <-chan struct{} {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	if s.done == nil {
+		return nil
+	}
+	return s.done
+}
+
+func (s *Server) Err() error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return s.err
+}
 
-	log.Println("Shutting down gRPC server...")
+// Stop stops accepting connections and waits for in-flight calls to finish.
+// If ctx expires first, the remaining calls are cancelled and ctx's error
+// is returned.
+func (s *Server) Stop(ctx context.Context) error {
+	if s.opts.health != nil {
+		s.opts.health.Shutdown()
+	}
 
-	grpcServer.GracefulStop()
+	stopped := make(chan struct{})
+	go func() {
+		s.grpcServer.GracefulStop()
+		close(stopped)
+	}()
 
-	log.Println("gRPC server stopped gracefully")
+	select {
+	case <-stopped:
+		s.opts.logger.Info("gRPC server stopped gracefully")
+		return nil
+	case <-ctx.Done():
+		s.grpcServer.Stop()
+		<-stopped
+		s.opts.logger.Warn("gRPC server stopped after shutdown timeout")
+		return ctx.Err()
+	}
 }
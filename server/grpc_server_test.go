@@ -0,0 +1,167 @@
+package grpcserver_test
+
+import (
+	"context"
+	"grpc/proto"
+	grpcserver "grpc/server"
+	"grpc/server/pkg/handler"
+	"grpc/server/pkg/health"
+	"grpc/server/pkg/policy"
+	"grpc/server/pkg/ratelimit"
+	"grpc/server/pkg/repository"
+	"grpc/server/pkg/service"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/credentials/insecure"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
+)
+
+func dial(t *testing.T, srv *grpcserver.Server) *grpc.ClientConn {
+	conn, err := grpc.NewClient(srv.Addr().String(), grpc.WithTransportCredentials(insecure.NewCredentials()))
+	require.NoError(t, err)
+	t.Cleanup(func() { conn.Close() })
+	return conn
+}
+
+func TestBookServer(t *testing.T) {
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	rules, err := policy.FromFile(proto.File_proto_book_proto)
+	require.NoError(t, err)
+	p, err := policy.New(append(rules, health.PolicyRule()))
+	require.NoError(t, err)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	repo := repository.NewMemoryRepository()
+	monitor := health.NewMonitor(repo.Ping, time.Hour, time.Second, proto.BookService_ServiceDesc.ServiceName)
+	go monitor.Run(ctx)
+
+	s := service.NewService(repo, service.Config{})
+	srv, err := grpcserver.NewBookServer(handler.NewHandler(s), s, p,
+		grpcserver.Config{Address: "127.0.0.1:0"}, grpcserver.WithHealth(monitor))
+	require.NoError(t, err)
+	assert.Nil(t, srv.Addr())
+
+	require.NoError(t, srv.Start(ctx))
+	assert.Error(t, srv.Start(ctx))
+
+	conn := dial(t, srv)
+	users := proto.NewUserServiceClient(conn)
+	books := proto.NewBookServiceClient(conn)
+
+	assert.Eventually(t, func() bool {
+		resp, err := healthpb.NewHealthClient(conn).Check(ctx, &healthpb.HealthCheckRequest{Service: "proto.BookService"})
+		return err == nil && resp.Status == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, 10*time.Millisecond)
+
+	_, err = books.GetBook(ctx, &proto.BookId{Id: 1})
+	assert.Equal(t, codes.Unauthenticated, status.Code(err))
+
+	signedUp, err := users.SignUp(ctx, &proto.User{Name: "Ann", Username: "ann", Password: "secret123"})
+	require.NoError(t, err)
+
+	// A taken username looks exactly like a new account.
+	taken, err := users.SignUp(ctx, &proto.User{Name: "Other Ann", Username: "ann", Password: "other-secret"})
+	require.NoError(t, err)
+	assert.True(t, protobuf.Equal(signedUp, taken))
+	auth, err := users.SignIn(ctx, &proto.SignInRequest{Username: "ann", Password: "secret123"})
+	require.NoError(t, err)
+
+	// An unknown username looks exactly like a wrong password.
+	_, wrongPassword := users.SignIn(ctx, &proto.SignInRequest{Username: "ann", Password: "wrong-password"})
+	_, unknownUser := users.SignIn(ctx, &proto.SignInRequest{Username: "nobody", Password: "wrong-password"})
+	assert.Equal(t, codes.Unauthenticated, status.Code(unknownUser))
+	assert.Equal(t, status.Convert(wrongPassword).Proto(), status.Convert(unknownUser).Proto())
+
+	authCtx := metadata.AppendToOutgoingContext(ctx, "authorization", "Bearer "+auth.Token)
+	id, err := books.CreateBook(authCtx, &proto.Book{Title: "Dune", Author: "Frank Herbert"})
+	require.NoError(t, err)
+	book, err := books.GetBook(authCtx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Dune", book.Title)
+
+	stopCtx, stopCancel := context.WithTimeout(ctx, 5*time.Second)
+	defer stopCancel()
+	require.NoError(t, srv.Stop(stopCtx))
+	<-srv.Done()
+	assert.NoError(t, srv.Err())
+
+	resp, err := monitor.Server().Check(ctx, &healthpb.HealthCheckRequest{})
+	require.NoError(t, err)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, resp.Status)
+}
+
+type blockingBooks struct {
+	proto.UnimplementedBookServiceServer
+	started chan struct{}
+}
+
+func (b *blockingBooks) GetBook(ctx context.Context, _ *proto.BookId) (*proto.Book, error) {
+	close(b.started)
+	<-ctx.Done()
+	return nil, ctx.Err()
+}
+
+func TestServer_StopTimeout(t *testing.T) {
+	books := &blockingBooks{started: make(chan struct{})}
+	srv := grpcserver.New(
+		grpcserver.WithAddress("127.0.0.1:0"),
+		grpcserver.WithService(&proto.BookService_ServiceDesc, books),
+	)
+	require.NoError(t, srv.Start(context.Background()))
+
+	callErr := make(chan error, 1)
+	go func() {
+		_, err := proto.NewBookServiceClient(dial(t, srv)).GetBook(context.Background(), &proto.BookId{Id: 1})
+		callErr <- err
+	}()
+	<-books.started
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+	assert.ErrorIs(t, srv.Stop(ctx), context.DeadlineExceeded)
+	assert.Error(t, <-callErr)
+}
+
+func TestBookServer_RateLimitsBeforeAuth(t *testing.T) {
+	t.Setenv("JWT_KEYS_DIR", "")
+	t.Setenv("JWT_SECRET", "test-secret")
+
+	rules, err := policy.FromFile(proto.File_proto_book_proto)
+	require.NoError(t, err)
+	p, err := policy.New(rules)
+	require.NoError(t, err)
+	limiter, err := ratelimit.New(ratelimit.Config{
+		AddressRules: []ratelimit.Rule{{Methods: []string{"/proto.BookService/*"}, Requests: 2, Per: time.Minute}},
+	})
+	require.NoError(t, err)
+
+	s := service.NewService(repository.NewMemoryRepository(), service.Config{})
+	srv, err := grpcserver.NewBookServer(handler.NewHandler(s), s, p,
+		grpcserver.Config{Address: "127.0.0.1:0"}, grpcserver.WithRateLimit(limiter))
+	require.NoError(t, err)
+	require.NoError(t, srv.Start(context.Background()))
+	t.Cleanup(func() { srv.Stop(context.Background()) })
+
+	// Calls with a bad token spend the address bucket before they are
+	// rejected, so a flood of them is throttled too.
+	books := proto.NewBookServiceClient(dial(t, srv))
+	ctx := metadata.AppendToOutgoingContext(context.Background(), "authorization", "Bearer not-a-token")
+	for i := 0; i < 2; i++ {
+		_, err = books.GetBook(ctx, &proto.BookId{Id: 1})
+		assert.Equal(t, codes.Unauthenticated, status.Code(err))
+	}
+	_, err = books.GetBook(ctx, &proto.BookId{Id: 1})
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
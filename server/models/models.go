@@ -1,25 +1,171 @@
 package models
 
+import "time"
+
+const (
+	RoleUser      = "user"
+	RoleModerator = "moderator"
+	RoleAdmin     = "admin"
+)
+
 type User struct {
 	ID       uint   `json:"id" gorm:"primaryKey"`
 	Name     string `json:"name" binding:"required"`
 	Username string `json:"username" gorm:"unique" validate:"required,min=3"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
+	Role     string `json:"role" gorm:"not null;default:user"`
+}
+
+// Principal is the authenticated caller of a request.
+type Principal struct {
+	UserID uint
+	Role   string
+	// AnyOwner lets the caller modify books owned by other users.
+	AnyOwner bool
+}
+
+func (p Principal) CanModify(book Book) bool {
+	return p.AnyOwner || p.UserID == book.UserId
+}
+
+// RefreshToken is the server-side record of an issued refresh token. Only a
+// hash of the token is stored; tokens rotated from the same sign-in share a
+// FamilyID so that a replayed token can revoke all of them.
+type RefreshToken struct {
+	ID        uint      `gorm:"primaryKey"`
+	UserID    uint      `gorm:"not null;index"`
+	FamilyID  string    `gorm:"not null;index"`
+	TokenHash string    `gorm:"not null;uniqueIndex"`
+	ExpiresAt time.Time `gorm:"not null"`
+	UsedAt    *time.Time
+	RevokedAt *time.Time
+	CreatedAt time.Time
+}
+
+// RevokedToken blocks an access token by its jti until the token would have
+// expired anyway, after which the entry can be pruned.
+type RevokedToken struct {
+	JTI       string    `gorm:"primaryKey"`
+	ExpiresAt time.Time `gorm:"not null;index"`
+	CreatedAt time.Time
+}
+
+// LoginAttempt counts the recent failed sign-ins for a key, which names a
+// username ("user:ann") or a client address ("ip:192.0.2.1").
+type LoginAttempt struct {
+	Key           string    `gorm:"primaryKey"`
+	Failures      int       `gorm:"not null"`
+	LastFailureAt time.Time `gorm:"not null"`
+	// LockedUntil is set while sign-ins for the key are refused.
+	LockedUntil *time.Time
+}
+
+// LockoutEvent records that a key was locked after too many failed sign-ins.
+type LockoutEvent struct {
+	ID          uint   `gorm:"primaryKey"`
+	Key         string `gorm:"not null;index"`
+	Username    string
+	ClientIP    string
+	Failures    int       `gorm:"not null"`
+	LockedUntil time.Time `gorm:"not null"`
+	CreatedAt   time.Time
+}
+
+type TokenPair struct {
+	AccessToken  string        `json:"access_token"`
+	RefreshToken string        `json:"refresh_token"`
+	ExpiresIn    time.Duration `json:"expires_in"`
+}
+
+// JWK is a public token verification key as published in the JWKS document.
+type JWK struct {
+	Kty string `json:"kty"`
+	Kid string `json:"kid"`
+	Use string `json:"use"`
+	Alg string `json:"alg"`
+	N   string `json:"n,omitempty"`
+	E   string `json:"e,omitempty"`
+	Crv string `json:"crv,omitempty"`
+	X   string `json:"x,omitempty"`
 }
 
 type SignInInput struct {
 	Username string `json:"username" gorm:"unique" validate:"required,min=3"`
-	Password string `json:"password" validate:"required,min=6"`
+	Password string `json:"password" validate:"required"`
 }
 
 type Book struct {
-	ID     uint   `json:"id" gorm:"primaryKey"`
-	Title  string `json:"title" gorm:"unique" validate:"required,min=4"`
-	Author string `json:"author" validate:"required,min=4"`
-	UserId uint   `json:"user_id"`
+	ID        uint      `json:"id" gorm:"primaryKey"`
+	Title     string    `json:"title" gorm:"unique" validate:"required,min=4"`
+	Author    string    `json:"author" validate:"required,min=4"`
+	UserId    uint      `json:"user_id"`
+	CreatedAt time.Time `json:"created_at" gorm:"not null;default:CURRENT_TIMESTAMP"`
 }
 
 type UpdateBook struct {
 	Title  *string `json:"title" gorm:"unique" validate:"omitempty,min=4"`
 	Author *string `json:"author" validate:"omitempty,min=4"`
 }
+
+type BookFilter struct {
+	Author      string `json:"author"`
+	UserId      uint   `json:"user_id"`
+	TitlePrefix string `json:"title_prefix"`
+}
+
+type ListBooksInput struct {
+	BookFilter
+	PageSize  int    `json:"page_size" validate:"gte=0,lte=1000"`
+	PageToken string `json:"page_token"`
+	OrderBy   string `json:"order_by" validate:"omitempty,oneof=title author id created 'title desc' 'author desc' 'id desc' 'created desc'"`
+}
+
+type BookPage struct {
+	Books         []Book `json:"books"`
+	NextPageToken string `json:"next_page_token"`
+	TotalSize     int64  `json:"total_size"`
+}
+
+// BookQuery is a single keyset page request as seen by the repository.
+type BookQuery struct {
+	BookFilter
+	OrderBy string
+	Desc    bool
+	After   *BookCursor
+	Limit   int
+	// Count asks for the total matching BookFilter, which may be an
+	// estimate. Without it the repository returns a total of 0.
+	Count bool
+}
+
+// BookCursor holds the sort key of the last book of the previous page.
+type BookCursor struct {
+	ID        uint      `json:"id"`
+	Title     string    `json:"t,omitempty"`
+	Author    string    `json:"a,omitempty"`
+	CreatedAt time.Time `json:"c,omitzero"`
+}
+
+// ImportRow is a validated book together with its position in the import.
+type ImportRow struct {
+	Row  int
+	Book Book
+}
+
+type ImportError struct {
+	Row     int    `json:"row"`
+	Field   string `json:"field,omitempty"`
+	Message string `json:"message"`
+}
+
+type ImportSummary struct {
+	CreatedIDs []uint        `json:"created_ids"`
+	Errors     []ImportError `json:"errors"`
+}
+
+// BatchResult is the outcome of inserting a single book of a batch,
+// ID is zero when Err is set.
+type BatchResult struct {
+	ID  uint
+	Err error
+}
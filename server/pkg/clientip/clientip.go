@@ -0,0 +1,150 @@
+// Package clientip determines the address of the client behind a gRPC call,
+// looking through trusted proxies such as the REST proxy.
+package clientip
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"net/netip"
+	"strings"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+// ForwardedForHeader carries the address of the original client when the
+// call comes through a proxy.
+const ForwardedForHeader = "x-forwarded-for"
+
+// Unknown is the address FromContext reports for calls without a peer.
+const Unknown = "unknown"
+
+type contextKey struct{}
+
+// Resolver trusts the x-forwarded-for metadata of calls from a fixed set of
+// proxy addresses and ignores it from everyone else.
+type Resolver struct {
+	trusted []netip.Prefix
+}
+
+// New accepts addresses such as "127.0.0.1" and CIDR ranges such as
+// "10.0.0.0/8".
+func New(trustedProxies []string) (*Resolver, error) {
+	r := &Resolver{}
+	for _, proxy := range trustedProxies {
+		prefix, err := parsePrefix(proxy)
+		if err != nil {
+			return nil, fmt.Errorf("invalid trusted proxy %q: %w", proxy, err)
+		}
+		r.trusted = append(r.trusted, prefix)
+	}
+	return r, nil
+}
+
+func parsePrefix(s string) (netip.Prefix, error) {
+	if strings.Contains(s, "/") {
+		return netip.ParsePrefix(s)
+	}
+	addr, err := netip.ParseAddr(s)
+	if err != nil {
+		return netip.Prefix{}, err
+	}
+	return netip.PrefixFrom(addr, addr.BitLen()), nil
+}
+
+// UnaryServerInterceptor stores the client address for FromContext.
+func (r *Resolver) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		return handler(NewContext(ctx, r.Resolve(ctx)), req)
+	}
+}
+
+func (r *Resolver) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		ctx := NewContext(ss.Context(), r.Resolve(ss.Context()))
+		return handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+	}
+}
+
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
+}
+
+// Resolve returns the address of the peer or, when the peer is a trusted
+// proxy, the client address it forwarded.
+func (r *Resolver) Resolve(ctx context.Context) string {
+	addr, ok := peerAddr(ctx)
+	if !ok || !r.isTrusted(addr) {
+		return peerString(ctx, addr, ok)
+	}
+
+	// The last entry was added by the trusted proxy; earlier ones were
+	// supplied by the client and cannot be trusted.
+	md, _ := metadata.FromIncomingContext(ctx)
+	if values := md.Get(ForwardedForHeader); len(values) > 0 {
+		hops := strings.Split(values[len(values)-1], ",")
+		if forwarded, err := netip.ParseAddr(strings.TrimSpace(hops[len(hops)-1])); err == nil {
+			return forwarded.Unmap().String()
+		}
+	}
+	return addr.String()
+}
+
+func (r *Resolver) isTrusted(addr netip.Addr) bool {
+	for _, prefix := range r.trusted {
+		if prefix.Contains(addr) {
+			return true
+		}
+	}
+	return false
+}
+
+// NewContext returns a copy of ctx that carries addr as the client address.
+func NewContext(ctx context.Context, addr string) context.Context {
+	return context.WithValue(ctx, contextKey{}, addr)
+}
+
+// FromContext returns the client address stored by the interceptors, or
+// the peer address of the call when they did not run.
+func FromContext(ctx context.Context) string {
+	if addr, ok := ctx.Value(contextKey{}).(string); ok {
+		return addr
+	}
+	addr, ok := peerAddr(ctx)
+	return peerString(ctx, addr, ok)
+}
+
+func peerAddr(ctx context.Context) (netip.Addr, bool) {
+	p, ok := peer.FromContext(ctx)
+	if !ok || p.Addr == nil {
+		return netip.Addr{}, false
+	}
+	host := p.Addr.String()
+	if h, _, err := net.SplitHostPort(host); err == nil {
+		host = h
+	}
+	addr, err := netip.ParseAddr(host)
+	if err != nil {
+		return netip.Addr{}, false
+	}
+	return addr.Unmap(), true
+}
+
+// peerString describes peers that are not IP addresses, such as Unix
+// sockets, by their raw address.
+func peerString(ctx context.Context, addr netip.Addr, ok bool) string {
+	if ok {
+		return addr.String()
+	}
+	if p, found := peer.FromContext(ctx); found && p.Addr != nil {
+		return p.Addr.String()
+	}
+	return Unknown
+}
@@ -0,0 +1,67 @@
+package clientip
+
+import (
+	"context"
+	"net"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+)
+
+func callFrom(addr net.Addr, md metadata.MD) context.Context {
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: addr})
+	return metadata.NewIncomingContext(ctx, md)
+}
+
+func tcp(ip string) net.Addr {
+	return &net.TCPAddr{IP: net.ParseIP(ip), Port: 40000}
+}
+
+func TestResolver_Resolve(t *testing.T) {
+	r, err := New([]string{"127.0.0.1", "10.1.0.0/16"})
+	require.NoError(t, err)
+
+	forwarded := metadata.Pairs(ForwardedForHeader, "192.0.2.1, 198.51.100.8")
+	tests := map[string]struct {
+		ctx  context.Context
+		want string
+	}{
+		"direct":                  {callFrom(tcp("192.0.2.1"), nil), "192.0.2.1"},
+		"untrusted forwarder":     {callFrom(tcp("192.0.2.1"), forwarded), "192.0.2.1"},
+		"trusted address":         {callFrom(tcp("127.0.0.1"), forwarded), "198.51.100.8"},
+		"trusted range":           {callFrom(tcp("10.1.2.3"), forwarded), "198.51.100.8"},
+		"mapped IPv4":             {callFrom(tcp("::ffff:127.0.0.1"), forwarded), "198.51.100.8"},
+		"trusted without header":  {callFrom(tcp("127.0.0.1"), nil), "127.0.0.1"},
+		"trusted with bad header": {callFrom(tcp("127.0.0.1"), metadata.Pairs(ForwardedForHeader, "nonsense")), "127.0.0.1"},
+		"unix socket":             {callFrom(&net.UnixAddr{Name: "/tmp/grpc.sock", Net: "unix"}, nil), "/tmp/grpc.sock"},
+		"no peer":                 {context.Background(), "unknown"},
+	}
+	for name, tt := range tests {
+		assert.Equal(t, tt.want, r.Resolve(tt.ctx), name)
+	}
+}
+
+func TestResolver_Interceptor(t *testing.T) {
+	r, err := New([]string{"127.0.0.1"})
+	require.NoError(t, err)
+
+	ctx := callFrom(tcp("127.0.0.1"), metadata.Pairs(ForwardedForHeader, "198.51.100.8"))
+	assert.Equal(t, "127.0.0.1", FromContext(ctx), "without the interceptor the peer is used")
+
+	resp, err := r.UnaryServerInterceptor()(ctx, nil, &grpc.UnaryServerInfo{}, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return FromContext(ctx), nil
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "198.51.100.8", resp)
+}
+
+func TestNew_InvalidProxy(t *testing.T) {
+	_, err := New([]string{"localhost"})
+	assert.Error(t, err)
+	_, err = New([]string{"10.0.0.0/33"})
+	assert.Error(t, err)
+}
@@ -0,0 +1,141 @@
+package events
+
+import (
+	"errors"
+	"grpc/server/models"
+	"sync"
+)
+
+type Type int
+
+const (
+	BookCreated Type = iota + 1
+	BookUpdated
+	BookDeleted
+)
+
+const subscriberBuffer = 64
+
+var (
+	ErrRevisionUnavailable = errors.New("requested revision is not available")
+	ErrSubscriberLagging   = errors.New("subscriber fell too far behind")
+)
+
+// BookEvent describes a successful change to a book. Revisions are assigned
+// by the bus, start at 1 and grow by one per event for the life of the process.
+type BookEvent struct {
+	Revision uint64
+	Type     Type
+	Book     models.Book
+}
+
+// Bus fans book events out to in-process subscribers and keeps the most
+// recent events so that a subscriber can resume from a known revision.
+// Nothing is shared between processes: with several replicas, a subscriber
+// only hears about changes made through its own replica, and the revisions
+// of different replicas are unrelated.
+type Bus struct {
+	mu       sync.Mutex
+	revision uint64
+	history  []BookEvent
+	limit    int
+	subs     map[*Subscription]struct{}
+}
+
+func NewBus(historySize int) *Bus {
+	return &Bus{
+		limit: historySize,
+		subs:  make(map[*Subscription]struct{}),
+	}
+}
+
+// Publish assigns the next revision to a change. Callers publish changes in
+// the order they were committed, since revisions are assigned in call order.
+func (b *Bus) Publish(t Type, book models.Book) BookEvent {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	b.revision++
+	event := BookEvent{Revision: b.revision, Type: t, Book: book}
+
+	if b.limit > 0 {
+		if len(b.history) == b.limit {
+			b.history = append(b.history[:0], b.history[1:]...)
+		}
+		b.history = append(b.history, event)
+	}
+
+	for sub := range b.subs {
+		select {
+		case sub.ch <- event:
+		default:
+			// Never block publishers on a slow reader; it can resume
+			// from its last revision with a new subscription.
+			sub.err = ErrSubscriberLagging
+			b.remove(sub)
+		}
+	}
+	return event
+}
+
+// Subscribe returns a subscription receiving every event after fromRevision.
+// A zero fromRevision starts at the next published event.
+func (b *Bus) Subscribe(fromRevision uint64) (*Subscription, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if fromRevision == 0 {
+		fromRevision = b.revision
+	}
+	if fromRevision > b.revision {
+		return nil, ErrRevisionUnavailable
+	}
+
+	var replay []BookEvent
+	if fromRevision < b.revision {
+		if len(b.history) == 0 || b.history[0].Revision > fromRevision+1 {
+			return nil, ErrRevisionUnavailable
+		}
+		replay = b.history[fromRevision+1-b.history[0].Revision:]
+	}
+
+	sub := &Subscription{
+		bus: b,
+		ch:  make(chan BookEvent, len(replay)+subscriberBuffer),
+	}
+	for _, event := range replay {
+		sub.ch <- event
+	}
+	b.subs[sub] = struct{}{}
+	return sub, nil
+}
+
+func (b *Bus) remove(sub *Subscription) {
+	if _, ok := b.subs[sub]; ok {
+		delete(b.subs, sub)
+		close(sub.ch)
+	}
+}
+
+type Subscription struct {
+	bus *Bus
+	ch  chan BookEvent
+	err error
+}
+
+// Events is closed when the subscription ends; Err then reports why.
+func (s *Subscription) Events() <-chan BookEvent {
+	return s.ch
+}
+
+func (s *Subscription) Err() error {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	return s.err
+}
+
+func (s *Subscription) Close() {
+	s.bus.mu.Lock()
+	defer s.bus.mu.Unlock()
+	s.bus.remove(s)
+}
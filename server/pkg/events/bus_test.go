@@ -0,0 +1,85 @@
+package events
+
+import (
+	"grpc/server/models"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestBus_PublishSubscribe(t *testing.T) {
+	bus := NewBus(10)
+
+	sub, err := bus.Subscribe(0)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	bus.Publish(BookCreated, models.Book{ID: 1})
+	bus.Publish(BookUpdated, models.Book{ID: 1, Title: "Updated"})
+
+	first := <-sub.Events()
+	second := <-sub.Events()
+
+	assert.Equal(t, uint64(1), first.Revision)
+	assert.Equal(t, BookCreated, first.Type)
+	assert.Equal(t, uint64(2), second.Revision)
+	assert.Equal(t, "Updated", second.Book.Title)
+}
+
+func TestBus_ResumeFromRevision(t *testing.T) {
+	bus := NewBus(10)
+
+	for i := 1; i <= 5; i++ {
+		bus.Publish(BookCreated, models.Book{ID: uint(i)})
+	}
+
+	sub, err := bus.Subscribe(3)
+	require.NoError(t, err)
+	defer sub.Close()
+
+	assert.Equal(t, uint64(4), (<-sub.Events()).Revision)
+	assert.Equal(t, uint64(5), (<-sub.Events()).Revision)
+
+	bus.Publish(BookDeleted, models.Book{ID: 2})
+	assert.Equal(t, uint64(6), (<-sub.Events()).Revision)
+}
+
+func TestBus_RevisionUnavailable(t *testing.T) {
+	bus := NewBus(2)
+
+	for i := 1; i <= 5; i++ {
+		bus.Publish(BookCreated, models.Book{ID: uint(i)})
+	}
+
+	_, err := bus.Subscribe(2)
+	assert.ErrorIs(t, err, ErrRevisionUnavailable)
+
+	_, err = bus.Subscribe(9)
+	assert.ErrorIs(t, err, ErrRevisionUnavailable)
+
+	sub, err := bus.Subscribe(3)
+	require.NoError(t, err)
+	sub.Close()
+}
+
+func TestBus_DropsLaggingSubscriber(t *testing.T) {
+	bus := NewBus(0)
+
+	sub, err := bus.Subscribe(0)
+	require.NoError(t, err)
+
+	for i := 0; i < subscriberBuffer+1; i++ {
+		bus.Publish(BookCreated, models.Book{ID: uint(i)})
+	}
+
+	received := 0
+	for range sub.Events() {
+		received++
+	}
+	assert.Equal(t, subscriberBuffer, received)
+	assert.ErrorIs(t, sub.Err(), ErrSubscriberLagging)
+
+	// Closing an already dropped subscription is a no-op.
+	sub.Close()
+}
@@ -2,11 +2,11 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"grpc/proto"
 	"grpc/server/models"
 	"grpc/server/pkg/service"
 
-	"github.com/go-playground/validator/v10"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
@@ -14,14 +14,17 @@ import (
 type AuthHandler struct {
 	proto.UnimplementedUserServiceServer
 	userService service.Authorization
+	lockout     service.Lockout
 }
 
-func NewAuthHandler(userService service.Authorization) *AuthHandler {
-	return &AuthHandler{userService: userService}
+func NewAuthHandler(userService service.Authorization, lockout service.Lockout) *AuthHandler {
+	return &AuthHandler{userService: userService, lockout: lockout}
 }
 
-var validate = validator.New()
-
+// SignUp answers a taken username exactly like a new account, so that
+// sign-up cannot be used to find out which usernames exist. The response
+// therefore never carries the new id; UserId.id is deprecated and clients
+// read the id from the user_id claim once they sign in.
 func (h *AuthHandler) SignUp(ctx context.Context, req *proto.User) (*proto.UserId, error) {
 	user := models.User{
 		Name:     req.Name,
@@ -30,14 +33,13 @@ func (h *AuthHandler) SignUp(ctx context.Context, req *proto.User) (*proto.UserI
 	}
 
 	if err := validate.Struct(user); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument(err)
 	}
 
-	id, err := h.userService.CreateUser(user)
-	if err != nil {
+	if _, err := h.userService.CreateUser(ctx, user); err != nil && !errors.Is(err, service.ErrAlreadyExists) {
 		return nil, err
 	}
-	return &proto.UserId{Id: uint32(id)}, nil
+	return &proto.UserId{}, nil
 }
 
 func (h *AuthHandler) SignIn(ctx context.Context, req *proto.SignInRequest) (*proto.AuthResponse, error) {
@@ -48,12 +50,72 @@ func (h *AuthHandler) SignIn(ctx context.Context, req *proto.SignInRequest) (*pr
 	}
 
 	if err := validate.Struct(signInInput); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument(err)
+	}
+
+	tokens, err := h.userService.GenerateToken(ctx, req.Username, req.Password)
+	if err != nil {
+		return nil, err
+	}
+	return toAuthResponse(tokens), nil
+}
+
+func (h *AuthHandler) RefreshToken(ctx context.Context, req *proto.RefreshTokenRequest) (*proto.AuthResponse, error) {
+	if req.RefreshToken == "" {
+		return nil, status.Error(codes.InvalidArgument, "refresh_token is required")
 	}
 
-	token, err := h.userService.GenerateToken(req.Username, req.Password)
+	tokens, err := h.userService.RefreshToken(ctx, req.RefreshToken)
+	if err != nil {
+		return nil, err
+	}
+	return toAuthResponse(tokens), nil
+}
+
+func (h *AuthHandler) SignOut(ctx context.Context, req *proto.SignOutRequest) (*proto.Empty, error) {
+	accessToken, err := bearerToken(ctx)
 	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
+
+	if err := h.userService.SignOut(ctx, accessToken, req.RefreshToken); err != nil {
+		return nil, err
+	}
+	return &proto.Empty{}, nil
+}
+
+func (h *AuthHandler) GetJWKS(ctx context.Context, req *proto.Empty) (*proto.JWKS, error) {
+	resp := &proto.JWKS{}
+	for _, key := range h.userService.JWKS() {
+		resp.Keys = append(resp.Keys, &proto.JSONWebKey{
+			Kty: key.Kty,
+			Kid: key.Kid,
+			Use: key.Use,
+			Alg: key.Alg,
+			N:   key.N,
+			E:   key.E,
+			Crv: key.Crv,
+			X:   key.X,
+		})
+	}
+	return resp, nil
+}
+
+func (h *AuthHandler) UnlockUser(ctx context.Context, req *proto.UnlockUserRequest) (*proto.Empty, error) {
+	if req.Username == "" {
+		return nil, status.Error(codes.InvalidArgument, "username is required")
+	}
+
+	if err := h.lockout.UnlockUser(ctx, req.Username); err != nil {
 		return nil, err
 	}
-	return &proto.AuthResponse{Token: token}, nil
+	return &proto.Empty{}, nil
+}
+
+func toAuthResponse(tokens models.TokenPair) *proto.AuthResponse {
+	return &proto.AuthResponse{
+		Token:        tokens.AccessToken,
+		RefreshToken: tokens.RefreshToken,
+		ExpiresIn:    int64(tokens.ExpiresIn.Seconds()),
+	}
 }
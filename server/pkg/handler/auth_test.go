@@ -1,18 +1,24 @@
 package handler_test
 
 import (
+	"bytes"
 	"context"
 	"errors"
+	"fmt"
 	"testing"
+	"time"
 
 	"grpc/proto"
 	"grpc/server/models"
 	"grpc/server/pkg/handler"
+	"grpc/server/pkg/service"
 	mock_service "grpc/server/pkg/service/mocks"
 
 	"github.com/golang/mock/gomock"
 	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
 	"google.golang.org/grpc/status"
+	protobuf "google.golang.org/protobuf/proto"
 )
 
 func TestAuthHandler_SignUp_Success(t *testing.T) {
@@ -20,7 +26,7 @@ func TestAuthHandler_SignUp_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
-	h := handler.NewAuthHandler(mockAuth)
+	h := handler.NewAuthHandler(mockAuth, nil)
 
 	req := &proto.User{
 		Name:     "John",
@@ -28,24 +34,60 @@ func TestAuthHandler_SignUp_Success(t *testing.T) {
 		Password: "pass123",
 	}
 
-	expectedID := uint(10)
-
 	mockAuth.
 		EXPECT().
-		CreateUser(models.User{
+		CreateUser(gomock.Any(), models.User{
 			Name:     "John",
 			Username: "john123",
 			Password: "pass123",
 		}).
-		Return(expectedID, nil)
+		Return(uint(10), nil)
 
 	resp, err := h.SignUp(context.Background(), req)
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
 
-	if resp.Id != uint32(expectedID) {
-		t.Fatalf("expected id %d, got %d", expectedID, resp.Id)
+	if resp == nil {
+		t.Fatal("expected a response")
+	}
+}
+
+// A taken username must get the same response as a new account, down to the
+// bytes on the wire, so the new id cannot be returned either.
+func TestAuthHandler_SignUp_TakenUsernameLooksNew(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	h := handler.NewAuthHandler(mockAuth, nil)
+	req := &proto.User{Name: "John", Username: "john123", Password: "pass123"}
+
+	gomock.InOrder(
+		mockAuth.EXPECT().CreateUser(gomock.Any(), gomock.Any()).Return(uint(10), nil),
+		mockAuth.EXPECT().CreateUser(gomock.Any(), gomock.Any()).
+			Return(uint(0), fmt.Errorf("user with this username %w", service.ErrAlreadyExists)),
+	)
+
+	created, err := h.SignUp(context.Background(), req)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	taken, err := h.SignUp(context.Background(), req)
+	if err != nil {
+		t.Fatalf("expected a taken username to look like a sign-up, got %v", err)
+	}
+
+	createdWire, err := protobuf.Marshal(created)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	takenWire, err := protobuf.Marshal(taken)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if !bytes.Equal(createdWire, takenWire) {
+		t.Fatalf("expected identical responses, got %x for a new account and %x for a taken username", createdWire, takenWire)
 	}
 }
 
@@ -54,12 +96,12 @@ func TestAuthHandler_SignUp_ValidationError(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
-	h := handler.NewAuthHandler(mockAuth)
+	h := handler.NewAuthHandler(mockAuth, nil)
 
 	req := &proto.User{
 		Name:     "",
 		Username: "user",
-		Password: "pass",
+		Password: "",
 	}
 
 	_, err := h.SignUp(context.Background(), req)
@@ -73,12 +115,31 @@ func TestAuthHandler_SignUp_ValidationError(t *testing.T) {
 	}
 }
 
+// The password policy alone decides which passwords are long enough.
+func TestAuthHandler_SignUp_PasswordLeftToPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	h := handler.NewAuthHandler(mockAuth, nil)
+
+	mockAuth.
+		EXPECT().
+		CreateUser(gomock.Any(), models.User{Name: "John", Username: "john123", Password: "abc"}).
+		Return(uint(0), fmt.Errorf("%w: must be at least 8 characters", service.ErrWeakPassword))
+
+	_, err := h.SignUp(context.Background(), &proto.User{Name: "John", Username: "john123", Password: "abc"})
+	if !errors.Is(err, service.ErrWeakPassword) {
+		t.Fatalf("expected the policy's error, got %v", err)
+	}
+}
+
 func TestAuthHandler_SignUp_CreateUserError(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
-	h := handler.NewAuthHandler(mockAuth)
+	h := handler.NewAuthHandler(mockAuth, nil)
 
 	req := &proto.User{
 		Name:     "John",
@@ -88,7 +149,7 @@ func TestAuthHandler_SignUp_CreateUserError(t *testing.T) {
 
 	mockAuth.
 		EXPECT().
-		CreateUser(gomock.Any()).
+		CreateUser(gomock.Any(), gomock.Any()).
 		Return(uint(0), errors.New("db error"))
 
 	_, err := h.SignUp(context.Background(), req)
@@ -102,7 +163,7 @@ func TestAuthHandler_SignIn_Success(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
-	h := handler.NewAuthHandler(mockAuth)
+	h := handler.NewAuthHandler(mockAuth, nil)
 
 	req := &proto.SignInRequest{
 		Username: "john123",
@@ -111,8 +172,12 @@ func TestAuthHandler_SignIn_Success(t *testing.T) {
 
 	mockAuth.
 		EXPECT().
-		GenerateToken("john123", "pass123").
-		Return("token_abc", nil)
+		GenerateToken(gomock.Any(), "john123", "pass123").
+		Return(models.TokenPair{
+			AccessToken:  "token_abc",
+			RefreshToken: "refresh_abc",
+			ExpiresIn:    15 * time.Minute,
+		}, nil)
 
 	resp, err := h.SignIn(context.Background(), req)
 	if err != nil {
@@ -122,6 +187,9 @@ func TestAuthHandler_SignIn_Success(t *testing.T) {
 	if resp.Token != "token_abc" {
 		t.Fatalf("expected token token_abc, got %s", resp.Token)
 	}
+	if resp.RefreshToken != "refresh_abc" || resp.ExpiresIn != 900 {
+		t.Fatalf("unexpected refresh token data: %s %d", resp.RefreshToken, resp.ExpiresIn)
+	}
 }
 
 func TestAuthHandler_SignIn_ValidationError(t *testing.T) {
@@ -129,7 +197,7 @@ func TestAuthHandler_SignIn_ValidationError(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
-	h := handler.NewAuthHandler(mockAuth)
+	h := handler.NewAuthHandler(mockAuth, nil)
 
 	req := &proto.SignInRequest{
 		Username: "",
@@ -152,7 +220,7 @@ func TestAuthHandler_SignIn_GenerateTokenError(t *testing.T) {
 	defer ctrl.Finish()
 
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
-	h := handler.NewAuthHandler(mockAuth)
+	h := handler.NewAuthHandler(mockAuth, nil)
 
 	req := &proto.SignInRequest{
 		Username: "john",
@@ -161,11 +229,128 @@ func TestAuthHandler_SignIn_GenerateTokenError(t *testing.T) {
 
 	mockAuth.
 		EXPECT().
-		GenerateToken("john", "pass123").
-		Return("", errors.New("invalid credentials"))
+		GenerateToken(gomock.Any(), "john", "pass123").
+		Return(models.TokenPair{}, errors.New("invalid credentials"))
 
 	_, err := h.SignIn(context.Background(), req)
 	if err == nil {
 		t.Fatal("expected error")
 	}
 }
+
+func TestAuthHandler_RefreshToken_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	h := handler.NewAuthHandler(mockAuth, nil)
+
+	mockAuth.
+		EXPECT().
+		RefreshToken(gomock.Any(), "refresh_abc").
+		Return(models.TokenPair{AccessToken: "token_new", RefreshToken: "refresh_new"}, nil)
+
+	resp, err := h.RefreshToken(context.Background(), &proto.RefreshTokenRequest{RefreshToken: "refresh_abc"})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if resp.Token != "token_new" || resp.RefreshToken != "refresh_new" {
+		t.Fatalf("unexpected tokens: %v", resp)
+	}
+}
+
+func TestAuthHandler_RefreshToken_Errors(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	h := handler.NewAuthHandler(mockAuth, nil)
+
+	_, err := h.RefreshToken(context.Background(), &proto.RefreshTokenRequest{})
+	if st, _ := status.FromError(err); st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", st.Code())
+	}
+
+	mockAuth.
+		EXPECT().
+		RefreshToken(gomock.Any(), "replayed").
+		Return(models.TokenPair{}, service.ErrRefreshTokenReused)
+
+	_, err = h.RefreshToken(context.Background(), &proto.RefreshTokenRequest{RefreshToken: "replayed"})
+	if !errors.Is(err, service.ErrRefreshTokenReused) {
+		t.Fatalf("expected ErrRefreshTokenReused, got %v", err)
+	}
+}
+
+func TestAuthHandler_SignOut(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	h := handler.NewAuthHandler(mockAuth, nil)
+
+	mockAuth.
+		EXPECT().
+		SignOut(gomock.Any(), "access_abc", "refresh_abc").
+		Return(nil)
+
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer access_abc"))
+
+	if _, err := h.SignOut(ctx, &proto.SignOutRequest{RefreshToken: "refresh_abc"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
+
+func TestAuthHandler_SignOut_MissingToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	h := handler.NewAuthHandler(mockAuth, nil)
+
+	_, err := h.SignOut(context.Background(), &proto.SignOutRequest{})
+	if st, _ := status.FromError(err); st.Code() != codes.Unauthenticated {
+		t.Fatalf("expected Unauthenticated, got %v", st.Code())
+	}
+}
+
+func TestAuthHandler_GetJWKS(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	h := handler.NewAuthHandler(mockAuth, nil)
+
+	mockAuth.
+		EXPECT().
+		JWKS().
+		Return([]models.JWK{{Kty: "OKP", Kid: "k1", Use: "sig", Alg: "EdDSA", Crv: "Ed25519", X: "abc"}})
+
+	resp, err := h.GetJWKS(context.Background(), &proto.Empty{})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(resp.Keys) != 1 || resp.Keys[0].Kid != "k1" || resp.Keys[0].X != "abc" {
+		t.Fatalf("unexpected keys: %v", resp.Keys)
+	}
+}
+
+func TestAuthHandler_UnlockUser(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockLockout := mock_service.NewMockLockout(ctrl)
+	h := handler.NewAuthHandler(nil, mockLockout)
+
+	mockLockout.EXPECT().UnlockUser(gomock.Any(), "ann").Return(nil)
+	if _, err := h.UnlockUser(context.Background(), &proto.UnlockUserRequest{Username: "ann"}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	_, err := h.UnlockUser(context.Background(), &proto.UnlockUserRequest{})
+	if status.Code(err) != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument for a missing username, got %v", err)
+	}
+}
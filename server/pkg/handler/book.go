@@ -2,15 +2,23 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"grpc/proto"
 	"grpc/server/models"
+	"grpc/server/pkg/events"
 	"grpc/server/pkg/service"
+	"io"
+	"sort"
+	"strings"
 
+	"github.com/go-playground/validator/v10"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
+const importBatchSize = 100
+
 type BookHandler struct {
 	proto.UnimplementedBookServiceServer
 	bookService service.Book
@@ -27,48 +35,190 @@ func (h *BookHandler) CreateBook(ctx context.Context, req *proto.Book) (*proto.B
 	}
 
 	if err := validate.Struct(book); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument(err)
 	}
 
 	userId, _ := UserIDFromContext(ctx)
 
 	book.UserId = userId
 
-	id, err := h.bookService.Create(book)
+	id, err := h.bookService.Create(ctx, book)
 	if err != nil {
 		return nil, err
 	}
 	return &proto.BookId{Id: uint32(id)}, nil
 }
 
+// ImportBooks validates every streamed book and passes the valid ones to the
+// service in batches of importBatchSize, so a bad row never fails the whole
+// import. Each batch is committed once it is full; if the stream breaks or is
+// cancelled, the batches committed before are kept.
+func (h *BookHandler) ImportBooks(stream proto.BookService_ImportBooksServer) error {
+	ctx := stream.Context()
+	userId, _ := UserIDFromContext(ctx)
+
+	var (
+		invalid []models.ImportError
+		total   int
+		done    bool
+	)
+
+	next := func() ([]models.ImportRow, error) {
+		var batch []models.ImportRow
+		for !done && len(batch) < importBatchSize {
+			req, err := stream.Recv()
+			if err == io.EOF {
+				done = true
+				break
+			}
+			if err != nil {
+				return nil, err
+			}
+			total++
+
+			book := models.Book{
+				Title:  req.Title,
+				Author: req.Author,
+				UserId: userId,
+			}
+			if err := validate.Struct(book); err != nil {
+				invalid = append(invalid, importValidationErrors(total, err)...)
+				continue
+			}
+			batch = append(batch, models.ImportRow{Row: total, Book: book})
+		}
+		if len(batch) == 0 {
+			return nil, io.EOF
+		}
+		return batch, nil
+	}
+
+	summary, err := h.bookService.Import(ctx, next)
+	if err != nil {
+		return err
+	}
+	summary.Errors = append(summary.Errors, invalid...)
+
+	sort.SliceStable(summary.Errors, func(i, j int) bool {
+		return summary.Errors[i].Row < summary.Errors[j].Row
+	})
+
+	resp := &proto.ImportBooksResponse{Total: uint32(total)}
+	for _, id := range summary.CreatedIDs {
+		resp.CreatedIds = append(resp.CreatedIds, uint32(id))
+	}
+	for _, e := range summary.Errors {
+		resp.Errors = append(resp.Errors, &proto.ImportError{
+			Row:     uint32(e.Row),
+			Field:   e.Field,
+			Message: e.Message,
+		})
+	}
+	return stream.SendAndClose(resp)
+}
+
+func importValidationErrors(row int, err error) []models.ImportError {
+	var fieldErrs validator.ValidationErrors
+	if !errors.As(err, &fieldErrs) {
+		return []models.ImportError{{Row: row, Message: err.Error()}}
+	}
+
+	out := make([]models.ImportError, 0, len(fieldErrs))
+	for _, fe := range fieldErrs {
+		out = append(out, models.ImportError{
+			Row:     row,
+			Field:   fe.Field(),
+			Message: describeFieldError(fe),
+		})
+	}
+	return out
+}
+
 func (h *BookHandler) GetBook(ctx context.Context, req *proto.BookId) (*proto.Book, error) {
-	book, err := h.bookService.GetById(uint(req.Id))
+	book, err := h.bookService.GetById(ctx, uint(req.Id))
 	if err != nil {
 		return nil, err
 	}
-	return &proto.Book{
-		Id:     uint32(book.ID),
-		Title:  book.Title,
-		Author: book.Author,
-		Userid: uint32(book.UserId),
-	}, nil
+	return toProtoBook(book), nil
 }
 
-func (h *BookHandler) GetBooks(ctx context.Context, req *proto.Empty) (*proto.BookList, error) {
-	books, err := h.bookService.GetAll()
+func (h *BookHandler) GetBooks(ctx context.Context, req *proto.ListBooksRequest) (*proto.BookList, error) {
+	input := models.ListBooksInput{
+		BookFilter: models.BookFilter{
+			Author:      req.Author,
+			UserId:      uint(req.UserId),
+			TitlePrefix: req.TitlePrefix,
+		},
+		PageSize:  int(req.PageSize),
+		PageToken: req.PageToken,
+		OrderBy:   strings.ToLower(strings.TrimSpace(req.OrderBy)),
+	}
+
+	if err := validate.Struct(input); err != nil {
+		return nil, invalidArgument(err)
+	}
+
+	page, err := h.bookService.List(ctx, input)
 	if err != nil {
 		return nil, err
 	}
 	var pbBooks []*proto.Book
-	for _, b := range books {
-		pbBooks = append(pbBooks, &proto.Book{
-			Id:     uint32(b.ID),
-			Title:  b.Title,
-			Author: b.Author,
-			Userid: uint32(b.UserId),
-		})
+	for _, b := range page.Books {
+		pbBooks = append(pbBooks, toProtoBook(b))
+	}
+	return &proto.BookList{
+		Books:         pbBooks,
+		NextPageToken: page.NextPageToken,
+		TotalSize:     page.TotalSize,
+	}, nil
+}
+
+func (h *BookHandler) StreamBooks(req *proto.StreamBooksRequest, stream proto.BookService_StreamBooksServer) error {
+	filter := models.BookFilter{
+		Author:      req.Author,
+		UserId:      uint(req.UserId),
+		TitlePrefix: req.TitlePrefix,
+	}
+
+	ctx := stream.Context()
+	return h.bookService.Stream(ctx, filter, int(req.BatchSize), func(book models.Book) error {
+		if err := ctx.Err(); err != nil {
+			return status.FromContextError(err).Err()
+		}
+		return stream.Send(toProtoBook(book))
+	})
+}
+
+// WatchBooks streams book changes until the client cancels. A client that
+// falls behind is disconnected with ResourceExhausted and should resume from
+// the last revision it received.
+func (h *BookHandler) WatchBooks(req *proto.WatchBooksRequest, stream proto.BookService_WatchBooksServer) error {
+	sub, err := h.bookService.Watch(req.FromRevision)
+	if err != nil {
+		if errors.Is(err, events.ErrRevisionUnavailable) {
+			return status.Error(codes.OutOfRange, err.Error())
+		}
+		return err
+	}
+	defer sub.Close()
+
+	ctx := stream.Context()
+	for {
+		select {
+		case <-ctx.Done():
+			return status.FromContextError(ctx.Err()).Err()
+		case event, ok := <-sub.Events():
+			if !ok {
+				return status.Error(codes.ResourceExhausted, sub.Err().Error())
+			}
+			if req.UserId != 0 && event.Book.UserId != uint(req.UserId) {
+				continue
+			}
+			if err := stream.Send(toProtoBookEvent(event)); err != nil {
+				return err
+			}
+		}
 	}
-	return &proto.BookList{Books: pbBooks}, nil
 }
 
 func (h *BookHandler) UpdateBook(ctx context.Context, req *proto.Book) (*proto.Book, error) {
@@ -78,12 +228,12 @@ func (h *BookHandler) UpdateBook(ctx context.Context, req *proto.Book) (*proto.B
 	}
 
 	if err := validate.Struct(updateBook); err != nil {
-		return nil, status.Error(codes.InvalidArgument, err.Error())
+		return nil, invalidArgument(err)
 	}
 
-	userId, _ := UserIDFromContext(ctx)
+	principal, _ := PrincipalFromContext(ctx)
 
-	err := h.bookService.Update(uint(userId), uint(req.Id), updateBook)
+	err := h.bookService.Update(ctx, principal, uint(req.Id), updateBook)
 	if err != nil {
 		return nil, err
 	}
@@ -91,13 +241,36 @@ func (h *BookHandler) UpdateBook(ctx context.Context, req *proto.Book) (*proto.B
 }
 
 func (h *BookHandler) DeleteBook(ctx context.Context, req *proto.BookId) (*proto.Empty, error) {
-	userId, _ := UserIDFromContext(ctx)
-	if err := h.bookService.Delete(uint(userId), uint(req.Id)); err != nil {
+	principal, _ := PrincipalFromContext(ctx)
+	if err := h.bookService.Delete(ctx, principal, uint(req.Id)); err != nil {
 		return nil, err
 	}
 	return &proto.Empty{}, nil
 }
 
+func toProtoBook(book models.Book) *proto.Book {
+	return &proto.Book{
+		Id:     uint32(book.ID),
+		Title:  book.Title,
+		Author: book.Author,
+		Userid: uint32(book.UserId),
+	}
+}
+
+var protoEventTypes = map[events.Type]proto.BookEvent_Type{
+	events.BookCreated: proto.BookEvent_CREATED,
+	events.BookUpdated: proto.BookEvent_UPDATED,
+	events.BookDeleted: proto.BookEvent_DELETED,
+}
+
+func toProtoBookEvent(event events.BookEvent) *proto.BookEvent {
+	return &proto.BookEvent{
+		Revision: event.Revision,
+		Type:     protoEventTypes[event.Type],
+		Book:     toProtoBook(event.Book),
+	}
+}
+
 func UserIDFromContext(ctx context.Context) (uint, error) {
 	id, ok := ctx.Value(userIDKey).(uint)
 	if !ok {
@@ -3,20 +3,26 @@ package handler_test
 import (
 	"context"
 	"errors"
+	"io"
+	"reflect"
 	"testing"
 
 	"grpc/proto"
 	"grpc/server/models"
+	"grpc/server/pkg/events"
 	"grpc/server/pkg/handler"
+	"grpc/server/pkg/service"
 	mock_service "grpc/server/pkg/service/mocks"
 
 	"github.com/golang/mock/gomock"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
 )
 
 func ctxWithUserID(id uint) context.Context {
-	return context.WithValue(context.Background(), handler.UserIDKey(), id)
+	return handler.ContextWithPrincipal(context.Background(), models.Principal{UserID: id, Role: models.RoleUser})
 }
 
 func TestBookHandler_CreateBook_Success(t *testing.T) {
@@ -36,7 +42,7 @@ func TestBookHandler_CreateBook_Success(t *testing.T) {
 
 	mockBook.
 		EXPECT().
-		Create(models.Book{
+		Create(gomock.Any(), models.Book{
 			Title:  "Go in Action",
 			Author: "John",
 			UserId: userId,
@@ -64,7 +70,7 @@ func TestBookHandler_CreateBook_ValidationError(t *testing.T) {
 
 	req := &proto.Book{
 		Title:  "",
-		Author: "Author",
+		Author: "Ann",
 	}
 
 	_, err := h.CreateBook(context.Background(), req)
@@ -76,6 +82,22 @@ func TestBookHandler_CreateBook_ValidationError(t *testing.T) {
 	if st.Code() != codes.InvalidArgument {
 		t.Fatalf("expected InvalidArgument, got %v", st.Code())
 	}
+
+	var violations []*errdetails.BadRequest_FieldViolation
+	for _, detail := range st.Details() {
+		if badRequest, ok := detail.(*errdetails.BadRequest); ok {
+			violations = append(violations, badRequest.FieldViolations...)
+		}
+	}
+	if len(violations) != 2 {
+		t.Fatalf("expected 2 field violations, got %v", violations)
+	}
+	if violations[0].Field != "title" || violations[0].Description != "title is required" {
+		t.Fatalf("unexpected title violation: %v", violations[0])
+	}
+	if violations[1].Field != "author" || violations[1].Description != "author must be at least 4 characters long" {
+		t.Fatalf("unexpected author violation: %v", violations[1])
+	}
 }
 
 func TestBookHandler_GetBook_Success(t *testing.T) {
@@ -94,7 +116,7 @@ func TestBookHandler_GetBook_Success(t *testing.T) {
 
 	mockBook.
 		EXPECT().
-		GetById(uint(5)).
+		GetById(gomock.Any(), uint(5)).
 		Return(expected, nil)
 
 	resp, err := h.GetBook(context.Background(), &proto.BookId{Id: 5})
@@ -116,7 +138,7 @@ func TestBookHandler_GetBook_Error(t *testing.T) {
 
 	mockBook.
 		EXPECT().
-		GetById(uint(99)).
+		GetById(gomock.Any(), uint(99)).
 		Return(models.Book{}, errors.New("not found"))
 
 	_, err := h.GetBook(context.Background(), &proto.BookId{Id: 99})
@@ -139,10 +161,18 @@ func TestBookHandler_GetBooks_Success(t *testing.T) {
 
 	mockBook.
 		EXPECT().
-		GetAll().
-		Return(books, nil)
+		List(gomock.Any(), models.ListBooksInput{
+			BookFilter: models.BookFilter{UserId: 1},
+			PageSize:   2,
+			OrderBy:    "title desc",
+		}).
+		Return(models.BookPage{Books: books, NextPageToken: "next", TotalSize: 3}, nil)
 
-	resp, err := h.GetBooks(context.Background(), &proto.Empty{})
+	resp, err := h.GetBooks(context.Background(), &proto.ListBooksRequest{
+		PageSize: 2,
+		OrderBy:  "Title DESC",
+		UserId:   1,
+	})
 	if err != nil {
 		t.Fatalf("unexpected error: %v", err)
 	}
@@ -150,6 +180,43 @@ func TestBookHandler_GetBooks_Success(t *testing.T) {
 	if len(resp.Books) != 2 {
 		t.Fatalf("expected 2 books, got %d", len(resp.Books))
 	}
+	if resp.NextPageToken != "next" || resp.TotalSize != 3 {
+		t.Fatalf("unexpected page metadata: %q %d", resp.NextPageToken, resp.TotalSize)
+	}
+}
+
+func TestBookHandler_GetBooks_InvalidOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_service.NewMockBook(ctrl)
+	h := handler.NewBookHandler(mockBook)
+
+	_, err := h.GetBooks(context.Background(), &proto.ListBooksRequest{OrderBy: "userid"})
+
+	st, _ := status.FromError(err)
+	if st.Code() != codes.InvalidArgument {
+		t.Fatalf("expected InvalidArgument, got %v", st.Code())
+	}
+}
+
+func TestBookHandler_GetBooks_InvalidPageToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_service.NewMockBook(ctrl)
+	h := handler.NewBookHandler(mockBook)
+
+	mockBook.
+		EXPECT().
+		List(gomock.Any(), gomock.Any()).
+		Return(models.BookPage{}, service.ErrInvalidPageToken)
+
+	_, err := h.GetBooks(context.Background(), &proto.ListBooksRequest{PageToken: "garbage"})
+
+	if !errors.Is(err, service.ErrInvalidPageToken) {
+		t.Fatalf("expected ErrInvalidPageToken, got %v", err)
+	}
 }
 
 func TestBookHandler_UpdateBook_Success(t *testing.T) {
@@ -169,7 +236,7 @@ func TestBookHandler_UpdateBook_Success(t *testing.T) {
 
 	mockBook.
 		EXPECT().
-		Update(userId, uint(10), models.UpdateBook{
+		Update(gomock.Any(), models.Principal{UserID: userId, Role: models.RoleUser}, uint(10), models.UpdateBook{
 			Title:  &req.Title,
 			Author: &req.Author,
 		}).
@@ -222,7 +289,7 @@ func TestBookHandler_DeleteBook_Success(t *testing.T) {
 
 	mockBook.
 		EXPECT().
-		Delete(userId, uint(10)).
+		Delete(gomock.Any(), models.Principal{UserID: userId, Role: models.RoleUser}, uint(10)).
 		Return(nil)
 
 	ctx := ctxWithUserID(userId)
@@ -244,7 +311,7 @@ func TestBookHandler_DeleteBook_Error(t *testing.T) {
 
 	mockBook.
 		EXPECT().
-		Delete(userId, uint(5)).
+		Delete(gomock.Any(), models.Principal{UserID: userId, Role: models.RoleUser}, uint(5)).
 		Return(errors.New("delete error"))
 
 	ctx := ctxWithUserID(userId)
@@ -254,3 +321,314 @@ func TestBookHandler_DeleteBook_Error(t *testing.T) {
 		t.Fatal("expected error")
 	}
 }
+
+type fakeBookStream struct {
+	grpc.ServerStream
+	ctx  context.Context
+	sent []*proto.Book
+}
+
+func (s *fakeBookStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeBookStream) Send(book *proto.Book) error {
+	s.sent = append(s.sent, book)
+	return nil
+}
+
+func TestBookHandler_StreamBooks_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_service.NewMockBook(ctrl)
+	h := handler.NewBookHandler(mockBook)
+
+	mockBook.
+		EXPECT().
+		Stream(gomock.Any(), models.BookFilter{Author: "Rob Pike"}, 100, gomock.Any()).
+		DoAndReturn(func(_ context.Context, filter models.BookFilter, batchSize int, fn func(models.Book) error) error {
+			for _, b := range []models.Book{{ID: 1, Title: "A"}, {ID: 2, Title: "B"}} {
+				if err := fn(b); err != nil {
+					return err
+				}
+			}
+			return nil
+		})
+
+	stream := &fakeBookStream{ctx: context.Background()}
+	err := h.StreamBooks(&proto.StreamBooksRequest{BatchSize: 100, Author: "Rob Pike"}, stream)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if len(stream.sent) != 2 || stream.sent[1].Title != "B" {
+		t.Fatalf("unexpected books sent: %v", stream.sent)
+	}
+}
+
+func TestBookHandler_StreamBooks_Cancelled(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_service.NewMockBook(ctrl)
+	h := handler.NewBookHandler(mockBook)
+
+	mockBook.
+		EXPECT().
+		Stream(gomock.Any(), models.BookFilter{}, 0, gomock.Any()).
+		DoAndReturn(func(_ context.Context, filter models.BookFilter, batchSize int, fn func(models.Book) error) error {
+			return fn(models.Book{ID: 1})
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	stream := &fakeBookStream{ctx: ctx}
+	err := h.StreamBooks(&proto.StreamBooksRequest{}, stream)
+
+	st, _ := status.FromError(err)
+	if st.Code() != codes.Canceled {
+		t.Fatalf("expected Canceled, got %v", st.Code())
+	}
+	if len(stream.sent) != 0 {
+		t.Fatal("expected nothing to be sent after cancellation")
+	}
+}
+
+type fakeImportStream struct {
+	grpc.ServerStream
+	ctx   context.Context
+	books []*proto.Book
+	// err is returned once books runs out, instead of io.EOF.
+	err  error
+	resp *proto.ImportBooksResponse
+}
+
+func (s *fakeImportStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeImportStream) Recv() (*proto.Book, error) {
+	if len(s.books) == 0 {
+		if s.err != nil {
+			return nil, s.err
+		}
+		return nil, io.EOF
+	}
+	book := s.books[0]
+	s.books = s.books[1:]
+	return book, nil
+}
+
+func (s *fakeImportStream) SendAndClose(resp *proto.ImportBooksResponse) error {
+	s.resp = resp
+	return nil
+}
+
+func TestBookHandler_ImportBooks(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_service.NewMockBook(ctrl)
+	h := handler.NewBookHandler(mockBook)
+
+	userId := uint(3)
+
+	var rows []models.ImportRow
+	mockBook.
+		EXPECT().
+		Import(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, next func() ([]models.ImportRow, error)) (models.ImportSummary, error) {
+			for {
+				batch, err := next()
+				if err == io.EOF {
+					break
+				}
+				if err != nil {
+					return models.ImportSummary{}, err
+				}
+				rows = append(rows, batch...)
+			}
+			return models.ImportSummary{
+				CreatedIDs: []uint{21},
+				Errors:     []models.ImportError{{Row: 3, Field: "title", Message: "duplicate"}},
+			}, nil
+		})
+
+	stream := &fakeImportStream{
+		ctx: ctxWithUserID(userId),
+		books: []*proto.Book{
+			{Title: "Go in Action", Author: "William"},
+			{Title: "Go", Author: ""},
+			{Title: "Go in Action", Author: "William"},
+		},
+	}
+
+	if err := h.ImportBooks(stream); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	wantRows := []models.ImportRow{
+		{Row: 1, Book: models.Book{Title: "Go in Action", Author: "William", UserId: userId}},
+		{Row: 3, Book: models.Book{Title: "Go in Action", Author: "William", UserId: userId}},
+	}
+	if !reflect.DeepEqual(rows, wantRows) {
+		t.Fatalf("expected valid rows %v, got %v", wantRows, rows)
+	}
+
+	if stream.resp.Total != 3 {
+		t.Fatalf("expected total 3, got %d", stream.resp.Total)
+	}
+	if len(stream.resp.CreatedIds) != 1 || stream.resp.CreatedIds[0] != 21 {
+		t.Fatalf("unexpected created ids: %v", stream.resp.CreatedIds)
+	}
+
+	var errRows []uint32
+	for _, e := range stream.resp.Errors {
+		errRows = append(errRows, e.Row)
+	}
+	if len(errRows) != 3 || errRows[0] != 2 || errRows[1] != 2 || errRows[2] != 3 {
+		t.Fatalf("expected errors for rows [2 2 3], got %v", errRows)
+	}
+}
+
+func TestBookHandler_ImportBooks_ServiceError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_service.NewMockBook(ctrl)
+	h := handler.NewBookHandler(mockBook)
+
+	mockBook.
+		EXPECT().
+		Import(gomock.Any(), gomock.Any()).
+		Return(models.ImportSummary{}, errors.New("db down"))
+
+	stream := &fakeImportStream{
+		ctx:   ctxWithUserID(1),
+		books: []*proto.Book{{Title: "Go in Action", Author: "William"}},
+	}
+
+	if err := h.ImportBooks(stream); err == nil {
+		t.Fatal("expected error")
+	}
+	if stream.resp != nil {
+		t.Fatal("expected no response on failure")
+	}
+}
+
+func TestBookHandler_ImportBooks_StreamError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_service.NewMockBook(ctrl)
+	h := handler.NewBookHandler(mockBook)
+
+	mockBook.
+		EXPECT().
+		Import(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, next func() ([]models.ImportRow, error)) (models.ImportSummary, error) {
+			for {
+				if _, err := next(); err != nil {
+					return models.ImportSummary{}, err
+				}
+			}
+		})
+
+	broken := status.Error(codes.Canceled, "context canceled")
+	stream := &fakeImportStream{
+		ctx:   ctxWithUserID(1),
+		books: []*proto.Book{{Title: "Go in Action", Author: "William"}},
+		err:   broken,
+	}
+
+	if err := h.ImportBooks(stream); !errors.Is(err, broken) {
+		t.Fatalf("expected the stream error, got %v", err)
+	}
+	if stream.resp != nil {
+		t.Fatal("expected no response on failure")
+	}
+}
+
+type fakeWatchStream struct {
+	grpc.ServerStream
+	ctx    context.Context
+	cancel context.CancelFunc
+	want   int
+	sent   []*proto.BookEvent
+}
+
+func (s *fakeWatchStream) Context() context.Context {
+	return s.ctx
+}
+
+func (s *fakeWatchStream) Send(event *proto.BookEvent) error {
+	s.sent = append(s.sent, event)
+	if len(s.sent) == s.want {
+		s.cancel()
+	}
+	return nil
+}
+
+func TestBookHandler_WatchBooks_FiltersByOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_service.NewMockBook(ctrl)
+	h := handler.NewBookHandler(mockBook)
+
+	bus := events.NewBus(10)
+	bus.Publish(events.BookCreated, models.Book{ID: 1, UserId: 1})
+	bus.Publish(events.BookCreated, models.Book{ID: 2, UserId: 2})
+	bus.Publish(events.BookDeleted, models.Book{ID: 1, UserId: 1})
+	bus.Publish(events.BookCreated, models.Book{ID: 3, UserId: 2})
+	bus.Publish(events.BookUpdated, models.Book{ID: 4, UserId: 1})
+	bus.Publish(events.BookDeleted, models.Book{ID: 3, UserId: 2})
+
+	mockBook.
+		EXPECT().
+		Watch(uint64(3)).
+		DoAndReturn(func(fromRevision uint64) (*events.Subscription, error) {
+			return bus.Subscribe(fromRevision)
+		})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	stream := &fakeWatchStream{ctx: ctx, cancel: cancel, want: 2}
+
+	err := h.WatchBooks(&proto.WatchBooksRequest{FromRevision: 3, UserId: 2}, stream)
+
+	st, _ := status.FromError(err)
+	if st.Code() != codes.Canceled {
+		t.Fatalf("expected Canceled, got %v", err)
+	}
+	if len(stream.sent) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(stream.sent))
+	}
+	if stream.sent[0].Type != proto.BookEvent_CREATED || stream.sent[1].Type != proto.BookEvent_DELETED {
+		t.Fatalf("unexpected event types: %v", stream.sent)
+	}
+	if stream.sent[0].Book.Id != 3 || stream.sent[0].Revision != 4 {
+		t.Fatalf("unexpected first event: %v", stream.sent[0])
+	}
+}
+
+func TestBookHandler_WatchBooks_RevisionUnavailable(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_service.NewMockBook(ctrl)
+	h := handler.NewBookHandler(mockBook)
+
+	mockBook.
+		EXPECT().
+		Watch(uint64(42)).
+		Return(nil, events.ErrRevisionUnavailable)
+
+	err := h.WatchBooks(&proto.WatchBooksRequest{FromRevision: 42}, &fakeWatchStream{ctx: context.Background()})
+
+	st, _ := status.FromError(err)
+	if st.Code() != codes.OutOfRange {
+		t.Fatalf("expected OutOfRange, got %v", st.Code())
+	}
+}
@@ -0,0 +1,28 @@
+package handler
+
+import (
+	"context"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// UnaryDeadlineInterceptor bounds calls that arrive without a deadline by
+// timeout, so abandoned requests stop their database queries. Streaming
+// calls are not bounded: their lifetime is up to the client. A non-positive
+// timeout disables the default.
+func UnaryDeadlineInterceptor(timeout time.Duration) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		if _, ok := ctx.Deadline(); ok || timeout <= 0 {
+			return handler(ctx, req)
+		}
+		ctx, cancel := context.WithTimeout(ctx, timeout)
+		defer cancel()
+		return handler(ctx, req)
+	}
+}
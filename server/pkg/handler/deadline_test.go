@@ -0,0 +1,40 @@
+package handler_test
+
+import (
+	"context"
+	"grpc/server/pkg/handler"
+	"testing"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+func TestUnaryDeadlineInterceptor(t *testing.T) {
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
+
+	deadlineOf := func(ctx context.Context, req interface{}) (interface{}, error) {
+		deadline, ok := ctx.Deadline()
+		if !ok {
+			return time.Time{}, nil
+		}
+		return deadline, nil
+	}
+
+	resp, _ := handler.UnaryDeadlineInterceptor(time.Second)(context.Background(), nil, info, deadlineOf)
+	if deadline := resp.(time.Time); deadline.IsZero() || time.Until(deadline) > time.Second {
+		t.Fatalf("expected a default deadline within 1s, got %v", deadline)
+	}
+
+	clientDeadline := time.Now().Add(time.Hour)
+	ctx, cancel := context.WithDeadline(context.Background(), clientDeadline)
+	defer cancel()
+	resp, _ = handler.UnaryDeadlineInterceptor(time.Second)(ctx, nil, info, deadlineOf)
+	if deadline := resp.(time.Time); !deadline.Equal(clientDeadline) {
+		t.Fatalf("expected the client deadline to be kept, got %v", deadline)
+	}
+
+	resp, _ = handler.UnaryDeadlineInterceptor(0)(context.Background(), nil, info, deadlineOf)
+	if deadline := resp.(time.Time); !deadline.IsZero() {
+		t.Fatalf("expected no deadline when disabled, got %v", deadline)
+	}
+}
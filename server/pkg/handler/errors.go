@@ -0,0 +1,128 @@
+package handler
+
+import (
+	"context"
+	"errors"
+	"grpc/server/pkg/service"
+	"log/slog"
+	"math"
+	"strconv"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/types/known/durationpb"
+)
+
+// errorCodes maps domain errors to the status code returned to clients.
+var errorCodes = []struct {
+	err  error
+	code codes.Code
+}{
+	{service.ErrNotFound, codes.NotFound},
+	{service.ErrPermissionDenied, codes.PermissionDenied},
+	{service.ErrAlreadyExists, codes.AlreadyExists},
+	{service.ErrInvalidCredentials, codes.Unauthenticated},
+	{service.ErrInvalidRefreshToken, codes.Unauthenticated},
+	{service.ErrRefreshTokenReused, codes.Unauthenticated},
+	{service.ErrTokenRevoked, codes.Unauthenticated},
+	{service.ErrInvalidToken, codes.Unauthenticated},
+	{service.ErrInvalidPageToken, codes.InvalidArgument},
+	{service.ErrWeakPassword, codes.InvalidArgument},
+}
+
+func UnaryErrorInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		resp, err := handler(ctx, req)
+		if err != nil {
+			if wait, ok := lockedFor(err); ok {
+				grpc.SetTrailer(ctx, RetryAfter(wait))
+			}
+			return nil, toStatusError(ctx, info.FullMethod, err)
+		}
+		return resp, nil
+	}
+}
+
+func StreamErrorInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		if err := handler(srv, ss); err != nil {
+			if wait, ok := lockedFor(err); ok {
+				ss.SetTrailer(RetryAfter(wait))
+			}
+			return toStatusError(ss.Context(), info.FullMethod, err)
+		}
+		return nil
+	}
+}
+
+// toStatusError translates err into a gRPC status error. Errors that already
+// carry a status pass through; errors that are not domain errors are logged
+// and reported as Internal so that database details never reach clients.
+// Lockouts carry a google.rpc.RetryInfo detail.
+func toStatusError(ctx context.Context, fullMethod string, err error) error {
+	if _, ok := status.FromError(err); ok {
+		return err
+	}
+	if wait, ok := lockedFor(err); ok {
+		return ResourceExhausted(err.Error(), wait)
+	}
+	for _, e := range errorCodes {
+		if errors.Is(err, e.err) {
+			return status.Error(e.code, err.Error())
+		}
+	}
+	if errors.Is(err, context.Canceled) || errors.Is(err, context.DeadlineExceeded) {
+		return status.FromContextError(err).Err()
+	}
+
+	requestID, _ := RequestIDFromContext(ctx)
+	slog.ErrorContext(ctx, "internal error", "method", fullMethod, "request_id", requestID, "error", err)
+	return status.Error(codes.Internal, "internal error")
+}
+
+func lockedFor(err error) (time.Duration, bool) {
+	var locked *service.LockedError
+	if !errors.As(err, &locked) {
+		return 0, false
+	}
+	return locked.RetryAfter, true
+}
+
+// RetryAfterTrailer holds the number of seconds a throttled or locked out
+// caller should wait before trying again.
+const RetryAfterTrailer = "retry-after"
+
+// ResourceExhausted returns a ResourceExhausted error whose
+// google.rpc.RetryInfo detail tells the caller to wait.
+func ResourceExhausted(message string, wait time.Duration) error {
+	st := status.New(codes.ResourceExhausted, message)
+	if withInfo, err := st.WithDetails(&errdetails.RetryInfo{RetryDelay: durationpb.New(wait)}); err == nil {
+		st = withInfo
+	}
+	return st.Err()
+}
+
+// RetryAfter returns the trailer that goes with ResourceExhausted.
+func RetryAfter(wait time.Duration) metadata.MD {
+	return metadata.Pairs(RetryAfterTrailer, strconv.Itoa(RetryAfterSeconds(wait)))
+}
+
+// RetryAfterSeconds rounds up so that a caller waiting that long is let
+// through.
+func RetryAfterSeconds(wait time.Duration) int {
+	return int(math.Ceil(wait.Seconds()))
+}
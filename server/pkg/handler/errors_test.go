@@ -0,0 +1,89 @@
+package handler_test
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grpc/server/pkg/handler"
+	"grpc/server/pkg/service"
+	"testing"
+	"time"
+
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func TestUnaryErrorInterceptor(t *testing.T) {
+	interceptor := handler.UnaryErrorInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
+
+	cases := []struct {
+		err     error
+		code    codes.Code
+		message string
+	}{
+		{fmt.Errorf("book with id 5 %w", service.ErrNotFound), codes.NotFound, "book with id 5 not found"},
+		{fmt.Errorf("deleting book 5: %w", service.ErrPermissionDenied), codes.PermissionDenied, "deleting book 5: permission denied"},
+		{fmt.Errorf("user with this username %w", service.ErrAlreadyExists), codes.AlreadyExists, "user with this username already exists"},
+		{service.ErrInvalidCredentials, codes.Unauthenticated, "invalid username or password"},
+		{service.ErrRefreshTokenReused, codes.Unauthenticated, "refresh token reuse detected"},
+		{service.ErrInvalidPageToken, codes.InvalidArgument, "invalid page token"},
+		{fmt.Errorf("%w: must contain a digit", service.ErrWeakPassword), codes.InvalidArgument, "password does not meet the policy: must contain a digit"},
+		{&service.LockedError{RetryAfter: 90 * time.Second}, codes.ResourceExhausted, "too many failed sign-in attempts, try again in 1m30s"},
+		{status.Error(codes.InvalidArgument, "bad title"), codes.InvalidArgument, "bad title"},
+		{context.DeadlineExceeded, codes.DeadlineExceeded, context.DeadlineExceeded.Error()},
+		{errors.New(`failed to find book: pq: relation "books" does not exist`), codes.Internal, "internal error"},
+	}
+
+	for _, c := range cases {
+		_, err := interceptor(context.Background(), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return nil, c.err
+		})
+		st := status.Convert(err)
+		if st.Code() != c.code || st.Message() != c.message {
+			t.Errorf("%v: got %v %q, want %v %q", c.err, st.Code(), st.Message(), c.code, c.message)
+		}
+	}
+}
+
+func TestUnaryErrorInterceptor_LockoutRetryInfo(t *testing.T) {
+	interceptor := handler.UnaryErrorInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/SignIn"}
+
+	stream := &fakeTransportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	_, err := interceptor(ctx, nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, &service.LockedError{RetryAfter: time.Minute}
+	})
+	if got := stream.trailer.Get(handler.RetryAfterTrailer); len(got) != 1 || got[0] != "60" {
+		t.Fatalf("expected a retry-after trailer of 60 seconds, got %v", got)
+	}
+	details := status.Convert(err).Details()
+	if len(details) != 1 {
+		t.Fatalf("expected one detail, got %v", details)
+	}
+	if retry, ok := details[0].(*errdetails.RetryInfo); !ok || retry.RetryDelay.AsDuration() != time.Minute {
+		t.Fatalf("expected a one minute RetryInfo, got %v", details[0])
+	}
+}
+
+func TestStreamErrorInterceptor(t *testing.T) {
+	interceptor := handler.StreamErrorInterceptor()
+	info := &grpc.StreamServerInfo{FullMethod: "/proto.BookService/StreamBooks", IsServerStream: true}
+
+	err := interceptor(nil, &fakeServerStream{ctx: context.Background()}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return errors.New("connection reset by peer")
+	})
+	if st := status.Convert(err); st.Code() != codes.Internal || st.Message() != "internal error" {
+		t.Fatalf("expected Internal error, got %v", err)
+	}
+
+	err = interceptor(nil, &fakeServerStream{ctx: context.Background()}, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
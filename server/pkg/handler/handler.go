@@ -11,7 +11,7 @@ type Handler struct {
 
 func NewHandler(services *service.Service) *Handler {
 	return &Handler{
-		AuthHandler: NewAuthHandler(services.Authorization),
+		AuthHandler: NewAuthHandler(services.Authorization, services.Lockout),
 		BookHandler: NewBookHandler(services.Book),
 	}
 }
@@ -0,0 +1,221 @@
+package handler
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"time"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
+	"google.golang.org/protobuf/proto"
+)
+
+// RequestIDHeader carries the request id in both directions. A client may
+// choose the id; otherwise the server generates one.
+const RequestIDHeader = "x-request-id"
+
+const maxRequestIDLength = 128
+
+const requestLogKey contextKey = "request_log"
+
+// requestLog collects what inner interceptors learn about a call, such as
+// the authenticated user, for the log line written when the call ends.
+type requestLog struct {
+	requestID string
+	userID    uint
+}
+
+// UnaryLoggingInterceptor logs one line per call with its method, request
+// id, user id, duration and status code. Request and response payloads are
+// added at debug level with passwords and tokens redacted. It must run
+// before the error and auth interceptors to see final codes and every
+// rejected call; UnaryLogUserInterceptor, placed after auth, supplies the
+// user id.
+func UnaryLoggingInterceptor(logger *slog.Logger) grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		ctx, entry := startRequestLog(ctx)
+		grpc.SetHeader(ctx, metadata.Pairs(RequestIDHeader, entry.requestID))
+
+		start := time.Now()
+		resp, err := handler(ctx, req)
+
+		attrs := entry.attrs(info.FullMethod, start, err)
+		if logger.Enabled(ctx, slog.LevelDebug) {
+			attrs = append(attrs, slog.Any("request", redactedPayload(req)))
+			if err == nil {
+				attrs = append(attrs, slog.Any("response", redactedPayload(resp)))
+			}
+		}
+		logger.LogAttrs(ctx, levelFor(err), "rpc finished", attrs...)
+		return resp, err
+	}
+}
+
+func StreamLoggingInterceptor(logger *slog.Logger) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		ctx, entry := startRequestLog(ss.Context())
+		ss.SetHeader(metadata.Pairs(RequestIDHeader, entry.requestID))
+
+		start := time.Now()
+		err := handler(srv, &contextStream{ServerStream: ss, ctx: ctx})
+		logger.LogAttrs(ctx, levelFor(err), "rpc finished", entry.attrs(info.FullMethod, start, err)...)
+		return err
+	}
+}
+
+// RequestIDFromContext returns the id of the call ctx belongs to.
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	entry, ok := ctx.Value(requestLogKey).(*requestLog)
+	if !ok {
+		return "", false
+	}
+	return entry.requestID, true
+}
+
+func startRequestLog(ctx context.Context) (context.Context, *requestLog) {
+	entry := &requestLog{requestID: incomingRequestID(ctx)}
+	if entry.requestID == "" {
+		entry.requestID = newRequestID()
+	}
+	return context.WithValue(ctx, requestLogKey, entry), entry
+}
+
+// UnaryLogUserInterceptor notes the authenticated user for the line
+// UnaryLoggingInterceptor writes. It must run after the auth interceptor.
+func UnaryLogUserInterceptor() grpc.UnaryServerInterceptor {
+	return func(
+		ctx context.Context,
+		req interface{},
+		info *grpc.UnaryServerInfo,
+		handler grpc.UnaryHandler,
+	) (interface{}, error) {
+		recordUser(ctx)
+		return handler(ctx, req)
+	}
+}
+
+func StreamLogUserInterceptor() grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		recordUser(ss.Context())
+		return handler(srv, ss)
+	}
+}
+
+func recordUser(ctx context.Context) {
+	entry, ok := ctx.Value(requestLogKey).(*requestLog)
+	if !ok {
+		return
+	}
+	if principal, err := PrincipalFromContext(ctx); err == nil {
+		entry.userID = principal.UserID
+	}
+}
+
+func (e *requestLog) attrs(method string, start time.Time, err error) []slog.Attr {
+	attrs := []slog.Attr{
+		slog.String("method", method),
+		slog.String("request_id", e.requestID),
+		slog.Duration("duration", time.Since(start)),
+		slog.String("code", status.Code(err).String()),
+	}
+	if e.userID != 0 {
+		attrs = append(attrs, slog.Uint64("user_id", uint64(e.userID)))
+	}
+	if err != nil {
+		attrs = append(attrs, slog.String("error", status.Convert(err).Message()))
+	}
+	return attrs
+}
+
+func incomingRequestID(ctx context.Context) string {
+	md, _ := metadata.FromIncomingContext(ctx)
+	ids := md.Get(RequestIDHeader)
+	if len(ids) == 0 || len(ids[0]) > maxRequestIDLength {
+		return ""
+	}
+	return ids[0]
+}
+
+func newRequestID() string {
+	b := make([]byte, 16)
+	rand.Read(b)
+	return hex.EncodeToString(b)
+}
+
+// levelFor logs failures the server is responsible for as errors and those
+// caused by the caller as warnings.
+func levelFor(err error) slog.Level {
+	switch status.Code(err) {
+	case codes.OK:
+		return slog.LevelInfo
+	case codes.Internal, codes.Unknown, codes.DataLoss, codes.Unavailable, codes.Unimplemented:
+		return slog.LevelError
+	default:
+		return slog.LevelWarn
+	}
+}
+
+const redacted = "[REDACTED]"
+
+// redactedPayload renders a message as JSON values with every field whose
+// name mentions a password, token or secret replaced.
+func redactedPayload(payload interface{}) interface{} {
+	msg, ok := payload.(proto.Message)
+	if !ok {
+		return nil
+	}
+	b, err := protojson.Marshal(msg)
+	if err != nil {
+		return nil
+	}
+	var value interface{}
+	if err := json.Unmarshal(b, &value); err != nil {
+		return nil
+	}
+	return redact(value)
+}
+
+func redact(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, field := range v {
+			if isSensitive(key) {
+				v[key] = redacted
+			} else {
+				v[key] = redact(field)
+			}
+		}
+	case []interface{}:
+		for i := range v {
+			v[i] = redact(v[i])
+		}
+	}
+	return value
+}
+
+func isSensitive(field string) bool {
+	field = strings.ToLower(field)
+	return strings.Contains(field, "password") || strings.Contains(field, "token") || strings.Contains(field, "secret")
+}
@@ -0,0 +1,164 @@
+package handler_test
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"grpc/proto"
+	"grpc/server/models"
+	"grpc/server/pkg/handler"
+	"log/slog"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// fakeTransportStream captures the headers and trailers set by an
+// interceptor.
+type fakeTransportStream struct {
+	header  metadata.MD
+	trailer metadata.MD
+}
+
+func (s *fakeTransportStream) Method() string { return "" }
+
+func (s *fakeTransportStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func (s *fakeTransportStream) SendHeader(md metadata.MD) error { return s.SetHeader(md) }
+
+func (s *fakeTransportStream) SetTrailer(md metadata.MD) error {
+	s.trailer = metadata.Join(s.trailer, md)
+	return nil
+}
+
+func jsonLogger(level slog.Level) (*slog.Logger, *bytes.Buffer) {
+	var buf bytes.Buffer
+	return slog.New(slog.NewJSONHandler(&buf, &slog.HandlerOptions{Level: level})), &buf
+}
+
+func logEntry(t *testing.T, buf *bytes.Buffer) map[string]interface{} {
+	t.Helper()
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry), "expected one JSON log line, got %q", buf.String())
+	return entry
+}
+
+func TestUnaryLoggingInterceptor_GeneratesRequestID(t *testing.T) {
+	logger, buf := jsonLogger(slog.LevelInfo)
+	stream := &fakeTransportStream{}
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), stream)
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
+
+	var seen string
+	_, err := handler.UnaryLoggingInterceptor(logger)(ctx, &proto.BookId{Id: 1}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		seen, _ = handler.RequestIDFromContext(ctx)
+		return &proto.Book{Id: 1}, nil
+	})
+	require.NoError(t, err)
+
+	ids := stream.header.Get(handler.RequestIDHeader)
+	require.Len(t, ids, 1)
+	assert.Len(t, ids[0], 32)
+	assert.Equal(t, seen, ids[0], "the generated request id must be in the header and context")
+
+	entry := logEntry(t, buf)
+	assert.Equal(t, "INFO", entry["level"])
+	assert.Equal(t, info.FullMethod, entry["method"])
+	assert.Equal(t, "OK", entry["code"])
+	assert.Equal(t, seen, entry["request_id"])
+	assert.NotContains(t, entry, "user_id", "anonymous calls have no user id")
+	assert.NotContains(t, entry, "request", "payloads must only be logged at debug level")
+}
+
+func TestUnaryLogUserInterceptor(t *testing.T) {
+	logger, buf := jsonLogger(slog.LevelInfo)
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), &fakeTransportStream{})
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
+
+	_, err := handler.UnaryLoggingInterceptor(logger)(ctx, &proto.BookId{Id: 1}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		// The auth interceptor adds the principal between the two.
+		ctx = handler.ContextWithPrincipal(ctx, models.Principal{UserID: 7, Role: models.RoleUser})
+		return handler.UnaryLogUserInterceptor()(ctx, req, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			return &proto.Book{Id: 1}, nil
+		})
+	})
+	require.NoError(t, err)
+
+	assert.Equal(t, float64(7), logEntry(t, buf)["user_id"])
+}
+
+func TestUnaryLoggingInterceptor_KeepsClientRequestIDAndLogsErrors(t *testing.T) {
+	logger, buf := jsonLogger(slog.LevelInfo)
+	stream := &fakeTransportStream{}
+	ctx := metadata.NewIncomingContext(context.Background(), metadata.Pairs(handler.RequestIDHeader, "abc-123"))
+	ctx = grpc.NewContextWithServerTransportStream(ctx, stream)
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
+
+	_, err := handler.UnaryLoggingInterceptor(logger)(ctx, &proto.BookId{Id: 1}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "book not found")
+	})
+	assert.Equal(t, codes.NotFound, status.Code(err), "the handler error must pass through")
+
+	assert.Equal(t, []string{"abc-123"}, stream.header.Get(handler.RequestIDHeader), "the client's request id must be echoed")
+	entry := logEntry(t, buf)
+	assert.Equal(t, "WARN", entry["level"])
+	assert.Equal(t, "NotFound", entry["code"])
+	assert.Equal(t, "abc-123", entry["request_id"])
+	assert.Equal(t, "book not found", entry["error"])
+}
+
+func TestUnaryLoggingInterceptor_RedactsPayloads(t *testing.T) {
+	logger, buf := jsonLogger(slog.LevelDebug)
+	ctx := grpc.NewContextWithServerTransportStream(context.Background(), &fakeTransportStream{})
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/SignIn"}
+
+	_, err := handler.UnaryLoggingInterceptor(logger)(ctx, &proto.SignInRequest{Username: "ann", Password: "hunter22"}, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+		return &proto.AuthResponse{Token: "access.jwt", RefreshToken: "refresh-secret", ExpiresIn: 900}, nil
+	})
+	require.NoError(t, err)
+
+	for _, secret := range []string{"hunter22", "access.jwt", "refresh-secret"} {
+		assert.NotContains(t, buf.String(), secret, "the log line must not leak secrets")
+	}
+	entry := logEntry(t, buf)
+	assert.Equal(t, map[string]interface{}{"username": "ann", "password": "[REDACTED]"}, entry["request"])
+	assert.Equal(t, map[string]interface{}{"token": "[REDACTED]", "refreshToken": "[REDACTED]", "expiresIn": "900"}, entry["response"])
+}
+
+type headerServerStream struct {
+	fakeServerStream
+	header metadata.MD
+}
+
+func (s *headerServerStream) SetHeader(md metadata.MD) error {
+	s.header = metadata.Join(s.header, md)
+	return nil
+}
+
+func TestStreamLoggingInterceptor(t *testing.T) {
+	logger, buf := jsonLogger(slog.LevelInfo)
+	ss := &headerServerStream{fakeServerStream: fakeServerStream{ctx: context.Background()}}
+	info := &grpc.StreamServerInfo{FullMethod: "/proto.BookService/StreamBooks", IsServerStream: true}
+
+	err := handler.StreamLoggingInterceptor(logger)(nil, ss, info, func(srv interface{}, stream grpc.ServerStream) error {
+		_, ok := handler.RequestIDFromContext(stream.Context())
+		assert.True(t, ok, "expected a request id in the stream context")
+		return status.Error(codes.Internal, "boom")
+	})
+	assert.Equal(t, codes.Internal, status.Code(err), "the handler error must pass through")
+
+	ids := ss.header.Get(handler.RequestIDHeader)
+	require.Len(t, ids, 1)
+	entry := logEntry(t, buf)
+	assert.Equal(t, ids[0], entry["request_id"])
+	assert.Equal(t, "ERROR", entry["level"])
+	assert.Equal(t, "Internal", entry["code"])
+}
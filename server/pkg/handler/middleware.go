@@ -2,49 +2,128 @@ package handler
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"grpc/server/models"
+	"grpc/server/pkg/policy"
 	"grpc/server/pkg/service"
 	"strings"
 
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
 type contextKey string
 
-const userIDKey contextKey = "user_id"
+const (
+	userIDKey    contextKey = "user_id"
+	principalKey contextKey = "principal"
+)
 
-func UnaryAuthInterceptor(service *service.Service) grpc.UnaryServerInterceptor {
+func UnaryAuthInterceptor(service *service.Service, policy *policy.Policy) grpc.UnaryServerInterceptor {
 	return func(
 		ctx context.Context,
 		req interface{},
 		info *grpc.UnaryServerInfo,
 		handler grpc.UnaryHandler,
 	) (interface{}, error) {
-
-		if info.FullMethod == "/proto.UserService/SignUp" ||
-			info.FullMethod == "/proto.UserService/SignIn" {
-			return handler(ctx, req)
+		newCtx, err := authenticate(ctx, service, policy, info.FullMethod)
+		if err != nil {
+			return nil, err
 		}
+		return handler(newCtx, req)
+	}
+}
 
-		md, ok := metadata.FromIncomingContext(ctx)
-		if !ok {
-			return nil, fmt.Errorf("missing metadata")
+func StreamAuthInterceptor(service *service.Service, policy *policy.Policy) grpc.StreamServerInterceptor {
+	return func(
+		srv interface{},
+		ss grpc.ServerStream,
+		info *grpc.StreamServerInfo,
+		handler grpc.StreamHandler,
+	) error {
+		newCtx, err := authenticate(ss.Context(), service, policy, info.FullMethod)
+		if err != nil {
+			return err
 		}
+		return handler(srv, &contextStream{ServerStream: ss, ctx: newCtx})
+	}
+}
 
-		tokens := md.Get("authorization")
-		if len(tokens) == 0 {
-			return nil, fmt.Errorf("missing token")
-		}
+// authenticate checks the caller against the policy of fullMethod and
+// returns ctx carrying the caller's principal, or ctx unchanged for public
+// methods.
+func authenticate(ctx context.Context, services *service.Service, policy *policy.Policy, fullMethod string) (context.Context, error) {
+	rule, ok := policy.Lookup(fullMethod)
+	if !ok {
+		return nil, status.Errorf(codes.PermissionDenied, "no access policy for %s", fullMethod)
+	}
+	if rule.Public {
+		return ctx, nil
+	}
 
-		tokenStr := strings.TrimPrefix(tokens[0], "Bearer ")
+	tokenStr, err := bearerToken(ctx)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, err.Error())
+	}
 
-		userID, err := service.Authorization.ParseToken(tokenStr)
-		if err != nil {
-			return nil, fmt.Errorf("invalid token: %v", err)
-		}
+	// Only token problems are the caller's; anything else, such as a failed
+	// revocation lookup, is left to the error interceptor to report as
+	// Internal without its details.
+	principal, err := services.Authorization.ParseToken(ctx, tokenStr)
+	switch {
+	case errors.Is(err, service.ErrTokenRevoked):
+		return nil, status.Error(codes.Unauthenticated, service.ErrTokenRevoked.Error())
+	case errors.Is(err, service.ErrInvalidToken):
+		return nil, status.Error(codes.Unauthenticated, service.ErrInvalidToken.Error())
+	case err != nil:
+		return nil, err
+	}
 
-		newCtx := context.WithValue(ctx, userIDKey, userID)
-		return handler(newCtx, req)
+	if !rule.Allows(principal.Role) {
+		return nil, status.Errorf(codes.PermissionDenied, "role %q may not call %s", principal.Role, fullMethod)
 	}
+	principal.AnyOwner = rule.CanActOnAnyOwner(principal.Role)
+
+	return ContextWithPrincipal(ctx, principal), nil
+}
+
+func ContextWithPrincipal(ctx context.Context, principal models.Principal) context.Context {
+	ctx = context.WithValue(ctx, userIDKey, principal.UserID)
+	return context.WithValue(ctx, principalKey, principal)
+}
+
+func PrincipalFromContext(ctx context.Context) (models.Principal, error) {
+	principal, ok := ctx.Value(principalKey).(models.Principal)
+	if !ok {
+		return models.Principal{}, fmt.Errorf("principal not found in context")
+	}
+	return principal, nil
+}
+
+func bearerToken(ctx context.Context) (string, error) {
+	md, ok := metadata.FromIncomingContext(ctx)
+	if !ok {
+		return "", fmt.Errorf("missing metadata")
+	}
+
+	tokens := md.Get("authorization")
+	if len(tokens) == 0 {
+		return "", fmt.Errorf("missing token")
+	}
+
+	return strings.TrimPrefix(tokens[0], "Bearer "), nil
+}
+
+// contextStream overrides Context so stream handlers see values added by
+// interceptors, such as the user id.
+type contextStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *contextStream) Context() context.Context {
+	return s.ctx
 }
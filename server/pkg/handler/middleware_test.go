@@ -3,16 +3,44 @@ package handler_test
 import (
 	"context"
 	"errors"
+	"fmt"
+	"grpc/server/models"
 	"grpc/server/pkg/handler"
+	"grpc/server/pkg/policy"
 	"grpc/server/pkg/service"
 	mock_service "grpc/server/pkg/service/mocks"
 	"testing"
 
 	"github.com/golang/mock/gomock"
 	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
 )
 
+func testPolicy(t *testing.T) *policy.Policy {
+	t.Helper()
+	p, err := policy.New([]policy.Rule{
+		{
+			Methods: []string{"/proto.UserService/SignUp", "/proto.UserService/SignIn", "/proto.UserService/RefreshToken"},
+			Public:  true,
+		},
+		{
+			Methods: []string{"/proto.BookService/*"},
+			Roles:   []string{models.RoleUser, models.RoleAdmin},
+		},
+		{
+			Methods:  []string{"/proto.BookService/DeleteBook"},
+			Roles:    []string{models.RoleUser, models.RoleAdmin},
+			AnyOwner: []string{models.RoleAdmin},
+		},
+	})
+	if err != nil {
+		t.Fatalf("invalid test policy: %v", err)
+	}
+	return p
+}
+
 func ctxWithMetadata(token string) context.Context {
 	return metadata.NewIncomingContext(context.Background(), metadata.Pairs("authorization", "Bearer "+token))
 }
@@ -28,7 +56,7 @@ func TestUnaryAuthInterceptor_SignUpPassThrough(t *testing.T) {
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
 	srv := &service.Service{Authorization: mockAuth}
 
-	interceptor := handler.UnaryAuthInterceptor(srv)
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
 
 	info := &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/SignUp"}
 	ctx := context.WithValue(context.Background(), "ok", true)
@@ -49,7 +77,7 @@ func TestUnaryAuthInterceptor_SignInPassThrough(t *testing.T) {
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
 	srv := &service.Service{Authorization: mockAuth}
 
-	interceptor := handler.UnaryAuthInterceptor(srv)
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
 
 	info := &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/SignIn"}
 	ctx := context.WithValue(context.Background(), "ok", true)
@@ -63,6 +91,27 @@ func TestUnaryAuthInterceptor_SignInPassThrough(t *testing.T) {
 	}
 }
 
+func TestUnaryAuthInterceptor_RefreshTokenPassThrough(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	srv := &service.Service{Authorization: mockAuth}
+
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
+
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/RefreshToken"}
+	ctx := context.WithValue(context.Background(), "ok", true)
+
+	resp, err := interceptor(ctx, nil, info, fakeHandler)
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+	if resp != true {
+		t.Fatal("expected handler to run")
+	}
+}
+
 func TestUnaryAuthInterceptor_MissingMetadata(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -70,11 +119,11 @@ func TestUnaryAuthInterceptor_MissingMetadata(t *testing.T) {
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
 	srv := &service.Service{Authorization: mockAuth}
 
-	interceptor := handler.UnaryAuthInterceptor(srv)
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
 	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
 
 	_, err := interceptor(context.Background(), nil, info, fakeHandler)
-	if err == nil || err.Error() != "missing metadata" {
+	if st := status.Convert(err); err == nil || st.Code() != codes.Unauthenticated || st.Message() != "missing metadata" {
 		t.Fatalf("expected missing metadata error, got %v", err)
 	}
 }
@@ -86,13 +135,13 @@ func TestUnaryAuthInterceptor_MissingToken(t *testing.T) {
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
 	srv := &service.Service{Authorization: mockAuth}
 
-	interceptor := handler.UnaryAuthInterceptor(srv)
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
 	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
 
 	ctx := metadata.NewIncomingContext(context.Background(), metadata.MD{}) // нет токена
 
 	_, err := interceptor(ctx, nil, info, fakeHandler)
-	if err == nil || err.Error() != "missing token" {
+	if st := status.Convert(err); err == nil || st.Code() != codes.Unauthenticated || st.Message() != "missing token" {
 		t.Fatalf("expected missing token error, got %v", err)
 	}
 }
@@ -104,21 +153,43 @@ func TestUnaryAuthInterceptor_InvalidToken(t *testing.T) {
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
 	srv := &service.Service{Authorization: mockAuth}
 
-	interceptor := handler.UnaryAuthInterceptor(srv)
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
 	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
 
 	ctx := ctxWithMetadata("badtoken")
 
 	mockAuth.EXPECT().
-		ParseToken("badtoken").
-		Return(uint(0), errors.New("invalid"))
+		ParseToken(gomock.Any(), "badtoken").
+		Return(models.Principal{}, fmt.Errorf("user_id not found in token: %w", service.ErrInvalidToken))
 
 	_, err := interceptor(ctx, nil, info, fakeHandler)
-	if err == nil || err.Error() != "invalid token: invalid" {
+	if st := status.Convert(err); err == nil || st.Code() != codes.Unauthenticated || st.Message() != "invalid token" {
 		t.Fatalf("expected invalid token error, got %v", err)
 	}
 }
 
+func TestUnaryAuthInterceptor_RevocationCheckFails(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	srv := &service.Service{Authorization: mockAuth}
+
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
+
+	dbErr := errors.New("pq: connection refused")
+	mockAuth.EXPECT().
+		ParseToken(gomock.Any(), "goodtoken").
+		Return(models.Principal{}, dbErr)
+
+	// Left to the error interceptor, which reports it as Internal.
+	_, err := interceptor(ctxWithMetadata("goodtoken"), nil, info, fakeHandler)
+	if !errors.Is(err, dbErr) {
+		t.Fatalf("expected the database error to be passed on, got %v", err)
+	}
+}
+
 func TestUnaryAuthInterceptor_Success(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
@@ -126,14 +197,14 @@ func TestUnaryAuthInterceptor_Success(t *testing.T) {
 	mockAuth := mock_service.NewMockAuthorization(ctrl)
 	srv := &service.Service{Authorization: mockAuth}
 
-	interceptor := handler.UnaryAuthInterceptor(srv)
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
 	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
 
 	ctx := ctxWithMetadata("goodtoken")
 
 	mockAuth.EXPECT().
-		ParseToken("goodtoken").
-		Return(uint(42), nil)
+		ParseToken(gomock.Any(), "goodtoken").
+		Return(models.Principal{UserID: 42, Role: models.RoleUser}, nil)
 
 	handlerFn := func(ctx context.Context, req interface{}) (interface{}, error) {
 		// Проверяем, что userID положен в контекст
@@ -152,3 +223,138 @@ func TestUnaryAuthInterceptor_Success(t *testing.T) {
 		t.Fatalf("expected 'ok', got %v", resp)
 	}
 }
+
+func TestUnaryAuthInterceptor_MethodWithoutPolicy(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	srv := &service.Service{Authorization: mockAuth}
+
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/SignOut"}
+
+	_, err := interceptor(ctxWithMetadata("goodtoken"), nil, info, fakeHandler)
+	if st, _ := status.FromError(err); st.Code() != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+}
+
+func TestUnaryAuthInterceptor_RoleNotAllowed(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	srv := &service.Service{Authorization: mockAuth}
+
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
+
+	mockAuth.EXPECT().
+		ParseToken(gomock.Any(), "goodtoken").
+		Return(models.Principal{UserID: 3, Role: models.RoleModerator}, nil)
+
+	_, err := interceptor(ctxWithMetadata("goodtoken"), nil, info, fakeHandler)
+	if st, _ := status.FromError(err); st.Code() != codes.PermissionDenied {
+		t.Fatalf("expected PermissionDenied, got %v", err)
+	}
+}
+
+func TestUnaryAuthInterceptor_AnyOwner(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	srv := &service.Service{Authorization: mockAuth}
+
+	interceptor := handler.UnaryAuthInterceptor(srv, testPolicy(t))
+
+	cases := []struct {
+		method   string
+		role     string
+		anyOwner bool
+	}{
+		{"/proto.BookService/DeleteBook", models.RoleAdmin, true},
+		{"/proto.BookService/DeleteBook", models.RoleUser, false},
+		{"/proto.BookService/UpdateBook", models.RoleAdmin, false},
+	}
+
+	for _, c := range cases {
+		mockAuth.EXPECT().
+			ParseToken(gomock.Any(), "goodtoken").
+			Return(models.Principal{UserID: 1, Role: c.role}, nil)
+
+		info := &grpc.UnaryServerInfo{FullMethod: c.method}
+		_, err := interceptor(ctxWithMetadata("goodtoken"), nil, info, func(ctx context.Context, req interface{}) (interface{}, error) {
+			principal, err := handler.PrincipalFromContext(ctx)
+			if err != nil {
+				t.Fatalf("expected principal in context: %v", err)
+			}
+			if principal.AnyOwner != c.anyOwner {
+				t.Fatalf("%s as %s: expected AnyOwner=%v", c.method, c.role, c.anyOwner)
+			}
+			return nil, nil
+		})
+		if err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+	}
+}
+
+type fakeServerStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *fakeServerStream) Context() context.Context {
+	return s.ctx
+}
+
+func TestStreamAuthInterceptor_MissingToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	srv := &service.Service{Authorization: mockAuth}
+
+	interceptor := handler.StreamAuthInterceptor(srv, testPolicy(t))
+	info := &grpc.StreamServerInfo{FullMethod: "/proto.BookService/StreamBooks", IsServerStream: true}
+
+	ss := &fakeServerStream{ctx: metadata.NewIncomingContext(context.Background(), metadata.MD{})}
+
+	err := interceptor(nil, ss, info, func(srv interface{}, stream grpc.ServerStream) error {
+		t.Fatal("handler must not run without a token")
+		return nil
+	})
+	if st := status.Convert(err); err == nil || st.Code() != codes.Unauthenticated || st.Message() != "missing token" {
+		t.Fatalf("expected missing token error, got %v", err)
+	}
+}
+
+func TestStreamAuthInterceptor_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockAuth := mock_service.NewMockAuthorization(ctrl)
+	srv := &service.Service{Authorization: mockAuth}
+
+	interceptor := handler.StreamAuthInterceptor(srv, testPolicy(t))
+	info := &grpc.StreamServerInfo{FullMethod: "/proto.BookService/StreamBooks", IsServerStream: true}
+
+	mockAuth.EXPECT().
+		ParseToken(gomock.Any(), "goodtoken").
+		Return(models.Principal{UserID: 7, Role: models.RoleUser}, nil)
+
+	ss := &fakeServerStream{ctx: ctxWithMetadata("goodtoken")}
+
+	err := interceptor(nil, ss, info, func(srv interface{}, stream grpc.ServerStream) error {
+		userID, err := handler.UserIDFromContext(stream.Context())
+		if err != nil || userID != 7 {
+			t.Fatalf("expected userID=7 in stream context, got %v (%v)", userID, err)
+		}
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+}
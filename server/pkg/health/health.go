@@ -0,0 +1,116 @@
+// Package health reports the serving status of the gRPC services through the
+// standard grpc.health.v1 Health service, following a periodic check of the
+// storage they depend on.
+package health
+
+import (
+	"context"
+	"grpc/server/pkg/policy"
+	"log/slog"
+	"time"
+
+	"google.golang.org/grpc/health"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+// PolicyRule makes the Health service public, so probes need no token.
+func PolicyRule() policy.Rule {
+	return policy.Rule{
+		Methods: []string{"/" + healthpb.Health_ServiceDesc.ServiceName + "/*"},
+		Public:  true,
+	}
+}
+
+const (
+	defaultInterval = 5 * time.Second
+	defaultTimeout  = 2 * time.Second
+)
+
+// Check returns an error when the services cannot serve requests.
+type Check func(ctx context.Context) error
+
+// Monitor runs a Check at a fixed interval and marks the overall server ("")
+// and every named service SERVING or NOT_SERVING accordingly.
+type Monitor struct {
+	server   *health.Server
+	check    Check
+	services []string
+	interval time.Duration
+	timeout  time.Duration
+}
+
+// NewMonitor reports every service as NOT_SERVING until the first check
+// passes. timeout bounds each check; non-positive durations fall back to
+// defaultInterval and defaultTimeout.
+func NewMonitor(check Check, interval, timeout time.Duration, services ...string) *Monitor {
+	if interval <= 0 {
+		interval = defaultInterval
+	}
+	if timeout <= 0 {
+		timeout = defaultTimeout
+	}
+	m := &Monitor{
+		server:   health.NewServer(),
+		check:    check,
+		services: append([]string{""}, services...),
+		interval: interval,
+		timeout:  timeout,
+	}
+	m.set(healthpb.HealthCheckResponse_NOT_SERVING)
+	return m
+}
+
+// Server is the Health service implementation to register on the gRPC
+// server.
+func (m *Monitor) Server() *health.Server {
+	return m.server
+}
+
+// Run checks immediately and then every interval until ctx is cancelled.
+func (m *Monitor) Run(ctx context.Context) {
+	ticker := time.NewTicker(m.interval)
+	defer ticker.Stop()
+
+	serving := false
+	for {
+		ok := m.probe(ctx)
+		if ok != serving {
+			serving = ok
+			if ok {
+				slog.InfoContext(ctx, "storage reachable, serving", "services", m.services)
+				m.set(healthpb.HealthCheckResponse_SERVING)
+			} else {
+				slog.WarnContext(ctx, "storage unreachable, not serving", "services", m.services)
+				m.set(healthpb.HealthCheckResponse_NOT_SERVING)
+			}
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+func (m *Monitor) probe(ctx context.Context) bool {
+	ctx, cancel := context.WithTimeout(ctx, m.timeout)
+	defer cancel()
+	if err := m.check(ctx); err != nil {
+		slog.WarnContext(ctx, "storage health check failed", "timeout", m.timeout, "error", err)
+		return false
+	}
+	return true
+}
+
+// Shutdown reports NOT_SERVING for good, so load balancers drain the server
+// before it stops.
+func (m *Monitor) Shutdown() {
+	m.server.Shutdown()
+}
+
+func (m *Monitor) set(status healthpb.HealthCheckResponse_ServingStatus) {
+	for _, service := range m.services {
+		m.server.SetServingStatus(service, status)
+	}
+}
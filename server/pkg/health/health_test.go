@@ -0,0 +1,67 @@
+package health
+
+import (
+	"context"
+	"errors"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	healthpb "google.golang.org/grpc/health/grpc_health_v1"
+)
+
+func status(t *testing.T, m *Monitor, service string) healthpb.HealthCheckResponse_ServingStatus {
+	resp, err := m.Server().Check(context.Background(), &healthpb.HealthCheckRequest{Service: service})
+	require.NoError(t, err)
+	return resp.Status
+}
+
+func TestMonitor(t *testing.T) {
+	var failing atomic.Bool
+	check := func(ctx context.Context) error {
+		if failing.Load() {
+			return errors.New("connection refused")
+		}
+		return nil
+	}
+
+	m := NewMonitor(check, 5*time.Millisecond, time.Second, "proto.BookService")
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, m, "proto.BookService"))
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	go m.Run(ctx)
+
+	for _, service := range []string{"", "proto.BookService"} {
+		assert.Eventually(t, func() bool {
+			return status(t, m, service) == healthpb.HealthCheckResponse_SERVING
+		}, time.Second, time.Millisecond, service)
+	}
+
+	failing.Store(true)
+	assert.Eventually(t, func() bool {
+		return status(t, m, "proto.BookService") == healthpb.HealthCheckResponse_NOT_SERVING
+	}, time.Second, time.Millisecond)
+
+	failing.Store(false)
+	assert.Eventually(t, func() bool {
+		return status(t, m, "proto.BookService") == healthpb.HealthCheckResponse_SERVING
+	}, time.Second, time.Millisecond)
+
+	m.Shutdown()
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, m, ""))
+	time.Sleep(20 * time.Millisecond)
+	assert.Equal(t, healthpb.HealthCheckResponse_NOT_SERVING, status(t, m, "proto.BookService"))
+}
+
+func TestMonitor_CheckTimeout(t *testing.T) {
+	check := func(ctx context.Context) error {
+		<-ctx.Done()
+		return ctx.Err()
+	}
+	m := NewMonitor(check, time.Hour, 5*time.Millisecond)
+
+	assert.False(t, m.probe(context.Background()))
+}
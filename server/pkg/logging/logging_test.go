@@ -0,0 +1,55 @@
+package logging
+
+import (
+	"bytes"
+	"encoding/json"
+	"log/slog"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestNew(t *testing.T) {
+	var buf bytes.Buffer
+	level := new(slog.LevelVar)
+	logger, err := New(&buf, "json", level)
+	require.NoError(t, err)
+
+	logger.Debug("hidden")
+	assert.Zero(t, buf.Len())
+
+	level.Set(slog.LevelDebug)
+	logger.Debug("shown", "key", "value")
+	var entry map[string]interface{}
+	require.NoError(t, json.Unmarshal(buf.Bytes(), &entry))
+	assert.Equal(t, "shown", entry["msg"])
+	assert.Equal(t, "value", entry["key"])
+
+	buf.Reset()
+	logger, err = New(&buf, "text", level)
+	require.NoError(t, err)
+	logger.Info("plain")
+	assert.True(t, strings.Contains(buf.String(), "msg=plain"), buf.String())
+
+	_, err = New(&buf, "xml", level)
+	assert.Error(t, err)
+}
+
+func TestParseLevel(t *testing.T) {
+	for input, want := range map[string]slog.Level{
+		"":       slog.LevelInfo,
+		"debug":  slog.LevelDebug,
+		"WARN":   slog.LevelWarn,
+		"error":  slog.LevelError,
+		"info+2": slog.LevelInfo + 2,
+	} {
+		got, err := ParseLevel(input)
+		require.NoError(t, err, input)
+		assert.Equal(t, want, got, input)
+	}
+
+	_, err := ParseLevel("verbose")
+	assert.Error(t, err)
+}
@@ -0,0 +1,212 @@
+// Package metrics exports Prometheus metrics for the gRPC server, its
+// database queries and authentication outcomes.
+package metrics
+
+import (
+	"context"
+	"errors"
+	"grpc/server/models"
+	"grpc/server/pkg/service"
+	"log"
+	"net/http"
+	"strings"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/promhttp"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/status"
+	"gorm.io/gorm"
+)
+
+type Metrics struct {
+	rpcHandled    *prometheus.CounterVec
+	rpcDuration   *prometheus.HistogramVec
+	queryDuration *prometheus.HistogramVec
+	queryErrors   *prometheus.CounterVec
+	signIns       *prometheus.CounterVec
+	tokenFailures *prometheus.CounterVec
+}
+
+// New creates the collectors and registers them with reg.
+func New(reg prometheus.Registerer) *Metrics {
+	m := &Metrics{
+		rpcHandled: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "grpc_server_handled_total",
+			Help: "RPCs completed on the server, by method and status code.",
+		}, []string{"grpc_type", "grpc_service", "grpc_method", "grpc_code"}),
+		rpcDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "grpc_server_handling_seconds",
+			Help:    "Time taken to complete RPCs on the server.",
+			Buckets: prometheus.DefBuckets,
+		}, []string{"grpc_type", "grpc_service", "grpc_method"}),
+		queryDuration: prometheus.NewHistogramVec(prometheus.HistogramOpts{
+			Name:    "db_query_duration_seconds",
+			Help:    "Time taken by database queries, by table and operation.",
+			Buckets: []float64{.0005, .001, .0025, .005, .01, .025, .05, .1, .25, .5, 1},
+		}, []string{"table", "operation"}),
+		queryErrors: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "db_query_errors_total",
+			Help: "Database queries that failed, by table and operation. Missing rows are not errors.",
+		}, []string{"table", "operation"}),
+		signIns: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_sign_ins_total",
+			Help: "Sign-in attempts by result: success, invalid_credentials, locked or error.",
+		}, []string{"result"}),
+		tokenFailures: prometheus.NewCounterVec(prometheus.CounterOpts{
+			Name: "auth_token_validation_failures_total",
+			Help: "Access tokens rejected by the server, by reason: invalid or revoked.",
+		}, []string{"reason"}),
+	}
+	reg.MustRegister(m.rpcHandled, m.rpcDuration, m.queryDuration, m.queryErrors, m.signIns, m.tokenFailures)
+
+	// Export the auth series at zero so that rate() works from the start.
+	for _, result := range []string{"success", "invalid_credentials", "locked", "error"} {
+		m.signIns.WithLabelValues(result)
+	}
+	for _, reason := range []string{"invalid", "revoked"} {
+		m.tokenFailures.WithLabelValues(reason)
+	}
+	return m
+}
+
+// Serve exposes the metrics gathered by g on address under /metrics.
+func Serve(address string, g prometheus.Gatherer) *http.Server {
+	mux := http.NewServeMux()
+	mux.Handle("/metrics", promhttp.HandlerFor(g, promhttp.HandlerOpts{}))
+	srv := &http.Server{Addr: address, Handler: mux}
+
+	go func() {
+		log.Printf("metrics available on %s/metrics", address)
+		if err := srv.ListenAndServe(); err != nil && err != http.ErrServerClosed {
+			log.Printf("metrics server failed: %v", err)
+		}
+	}()
+	return srv
+}
+
+// UnaryServerInterceptor records every unary call. It must run before the
+// error interceptor so that it sees the final status code.
+func (m *Metrics) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		start := time.Now()
+		resp, err := handler(ctx, req)
+		m.observeRPC("unary", info.FullMethod, start, err)
+		return resp, err
+	}
+}
+
+func (m *Metrics) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		start := time.Now()
+		err := handler(srv, ss)
+		m.observeRPC(streamType(info), info.FullMethod, start, err)
+		return err
+	}
+}
+
+func (m *Metrics) observeRPC(rpcType, fullMethod string, start time.Time, err error) {
+	service, method := splitMethod(fullMethod)
+	m.rpcHandled.WithLabelValues(rpcType, service, method, status.Code(err).String()).Inc()
+	m.rpcDuration.WithLabelValues(rpcType, service, method).Observe(time.Since(start).Seconds())
+}
+
+func streamType(info *grpc.StreamServerInfo) string {
+	switch {
+	case info.IsClientStream && info.IsServerStream:
+		return "bidi_stream"
+	case info.IsClientStream:
+		return "client_stream"
+	default:
+		return "server_stream"
+	}
+}
+
+// splitMethod splits "/proto.BookService/GetBook" into its service and
+// method names.
+func splitMethod(fullMethod string) (string, string) {
+	fullMethod = strings.TrimPrefix(fullMethod, "/")
+	if i := strings.LastIndex(fullMethod, "/"); i >= 0 {
+		return fullMethod[:i], fullMethod[i+1:]
+	}
+	return "unknown", fullMethod
+}
+
+const startKey = "metrics:start"
+
+// InstrumentGORM registers callbacks on db that time every query.
+func (m *Metrics) InstrumentGORM(db *gorm.DB) error {
+	before := func(tx *gorm.DB) { tx.InstanceSet(startKey, time.Now()) }
+	cb := db.Callback()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("metrics:before_create", before),
+		cb.Create().After("gorm:create").Register("metrics:after_create", m.afterQuery("create")),
+		cb.Query().Before("gorm:query").Register("metrics:before_query", before),
+		cb.Query().After("gorm:query").Register("metrics:after_query", m.afterQuery("select")),
+		cb.Update().Before("gorm:update").Register("metrics:before_update", before),
+		cb.Update().After("gorm:update").Register("metrics:after_update", m.afterQuery("update")),
+		cb.Delete().Before("gorm:delete").Register("metrics:before_delete", before),
+		cb.Delete().After("gorm:delete").Register("metrics:after_delete", m.afterQuery("delete")),
+		cb.Row().Before("gorm:row").Register("metrics:before_row", before),
+		cb.Row().After("gorm:row").Register("metrics:after_row", m.afterQuery("row")),
+		cb.Raw().Before("gorm:raw").Register("metrics:before_raw", before),
+		cb.Raw().After("gorm:raw").Register("metrics:after_raw", m.afterQuery("raw")),
+	)
+}
+
+func (m *Metrics) afterQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(startKey)
+		if !ok {
+			return
+		}
+		start := value.(time.Time)
+
+		table := tx.Statement.Table
+		if table == "" {
+			table = "none"
+		}
+		m.queryDuration.WithLabelValues(table, operation).Observe(time.Since(start).Seconds())
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			m.queryErrors.WithLabelValues(table, operation).Inc()
+		}
+	}
+}
+
+// InstrumentAuth wraps auth so that sign-ins and rejected access tokens are
+// counted.
+func (m *Metrics) InstrumentAuth(auth service.Authorization) service.Authorization {
+	return &instrumentedAuth{Authorization: auth, metrics: m}
+}
+
+type instrumentedAuth struct {
+	service.Authorization
+	metrics *Metrics
+}
+
+func (a *instrumentedAuth) GenerateToken(ctx context.Context, username, password string) (models.TokenPair, error) {
+	pair, err := a.Authorization.GenerateToken(ctx, username, password)
+
+	result := "success"
+	switch {
+	case errors.Is(err, service.ErrInvalidCredentials):
+		result = "invalid_credentials"
+	case errors.Is(err, service.ErrLoginLocked):
+		result = "locked"
+	case err != nil:
+		result = "error"
+	}
+	a.metrics.signIns.WithLabelValues(result).Inc()
+	return pair, err
+}
+
+func (a *instrumentedAuth) ParseToken(ctx context.Context, token string) (models.Principal, error) {
+	principal, err := a.Authorization.ParseToken(ctx, token)
+	switch {
+	case errors.Is(err, service.ErrTokenRevoked):
+		a.metrics.tokenFailures.WithLabelValues("revoked").Inc()
+	case errors.Is(err, service.ErrInvalidToken):
+		a.metrics.tokenFailures.WithLabelValues("invalid").Inc()
+	}
+	return principal, err
+}
@@ -0,0 +1,127 @@
+package metrics
+
+import (
+	"context"
+	"errors"
+	"grpc/server/models"
+	"grpc/server/pkg/service"
+	mock_service "grpc/server/pkg/service/mocks"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang/mock/gomock"
+	"github.com/prometheus/client_golang/prometheus"
+	"github.com/prometheus/client_golang/prometheus/testutil"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	interceptor := m.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
+
+	ok := func(ctx context.Context, req interface{}) (interface{}, error) { return "book", nil }
+	notFound := func(ctx context.Context, req interface{}) (interface{}, error) {
+		return nil, status.Error(codes.NotFound, "book not found")
+	}
+
+	_, err := interceptor(context.Background(), nil, info, ok)
+	require.NoError(t, err)
+	_, err = interceptor(context.Background(), nil, info, notFound)
+	require.Error(t, err)
+	_, err = interceptor(context.Background(), nil, info, notFound)
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.rpcHandled.WithLabelValues("unary", "proto.BookService", "GetBook", "OK")))
+	assert.Equal(t, 2.0, testutil.ToFloat64(m.rpcHandled.WithLabelValues("unary", "proto.BookService", "GetBook", "NotFound")))
+	assert.Equal(t, 1, testutil.CollectAndCount(m.rpcDuration))
+}
+
+func TestStreamServerInterceptor(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	info := &grpc.StreamServerInfo{FullMethod: "/proto.BookService/ImportBooks", IsClientStream: true}
+
+	err := m.StreamServerInterceptor()(nil, nil, info, func(srv interface{}, stream grpc.ServerStream) error {
+		return status.Error(codes.Canceled, "client went away")
+	})
+	require.Error(t, err)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.rpcHandled.WithLabelValues("client_stream", "proto.BookService", "ImportBooks", "Canceled")))
+}
+
+func TestInstrumentGORM(t *testing.T) {
+	m := New(prometheus.NewRegistry())
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE books (id integer PRIMARY KEY, title text UNIQUE, author text, user_id integer, created_at datetime)").Error)
+	require.NoError(t, m.InstrumentGORM(db))
+
+	require.NoError(t, db.Create(&models.Book{Title: "Dune", Author: "Frank Herbert"}).Error)
+	assert.Error(t, db.Create(&models.Book{Title: "Dune", Author: "Frank Herbert"}).Error)
+	var book models.Book
+	assert.ErrorIs(t, db.First(&book, 42).Error, gorm.ErrRecordNotFound)
+
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.queryErrors.WithLabelValues("books", "create")))
+	assert.Equal(t, 0.0, testutil.ToFloat64(m.queryErrors.WithLabelValues("books", "select")))
+
+	count, err := testutil.GatherAndCount(wrap(m.queryDuration), "db_query_duration_seconds")
+	require.NoError(t, err)
+	assert.Equal(t, 2, count)
+}
+
+func wrap(c prometheus.Collector) prometheus.Gatherer {
+	reg := prometheus.NewRegistry()
+	reg.MustRegister(c)
+	return reg
+}
+
+func TestInstrumentAuth(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	auth := mock_service.NewMockAuthorization(ctrl)
+	m := New(prometheus.NewRegistry())
+	instrumented := m.InstrumentAuth(auth)
+	ctx := context.Background()
+
+	auth.EXPECT().GenerateToken(ctx, "ann", "secret123").Return(models.TokenPair{AccessToken: "token"}, nil)
+	auth.EXPECT().GenerateToken(ctx, "ann", "wrong").Return(models.TokenPair{}, service.ErrInvalidCredentials)
+	auth.EXPECT().GenerateToken(ctx, "bob", "secret123").Return(models.TokenPair{}, errors.New("database is down"))
+	auth.EXPECT().GenerateToken(ctx, "eve", "guess").Return(models.TokenPair{}, &service.LockedError{RetryAfter: time.Minute})
+	auth.EXPECT().ParseToken(ctx, "revoked").Return(models.Principal{}, service.ErrTokenRevoked)
+	auth.EXPECT().ParseToken(ctx, "garbage").Return(models.Principal{}, service.ErrInvalidToken)
+	auth.EXPECT().ParseToken(ctx, "token").Return(models.Principal{UserID: 1, Role: models.RoleUser}, nil)
+
+	pair, err := instrumented.GenerateToken(ctx, "ann", "secret123")
+	require.NoError(t, err)
+	assert.Equal(t, "token", pair.AccessToken)
+	instrumented.GenerateToken(ctx, "ann", "wrong")
+	instrumented.GenerateToken(ctx, "bob", "secret123")
+	instrumented.GenerateToken(ctx, "eve", "guess")
+	instrumented.ParseToken(ctx, "revoked")
+	instrumented.ParseToken(ctx, "garbage")
+	principal, err := instrumented.ParseToken(ctx, "token")
+	require.NoError(t, err)
+	assert.Equal(t, uint(1), principal.UserID)
+
+	for result, want := range map[string]float64{"success": 1, "invalid_credentials": 1, "locked": 1, "error": 1} {
+		assert.Equal(t, want, testutil.ToFloat64(m.signIns.WithLabelValues(result)), result)
+	}
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tokenFailures.WithLabelValues("revoked")))
+	assert.Equal(t, 1.0, testutil.ToFloat64(m.tokenFailures.WithLabelValues("invalid")))
+}
+
+func TestSplitMethod(t *testing.T) {
+	service, method := splitMethod("/proto.BookService/GetBook")
+	assert.Equal(t, "proto.BookService", service)
+	assert.Equal(t, "GetBook", method)
+
+	service, method = splitMethod("GetBook")
+	assert.Equal(t, "unknown", service)
+	assert.Equal(t, "GetBook", method)
+}
@@ -0,0 +1,164 @@
+package migrate
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+func newTestDB(t *testing.T) *gorm.DB {
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	return db
+}
+
+func TestUpDownStatus(t *testing.T) {
+	ctx := context.Background()
+	db := newTestDB(t)
+	sqlDB, err := db.DB()
+	require.NoError(t, err)
+
+	m, err := newMigrator(sqlDB, "sqlite", fstest.MapFS{
+		"sql/sqlite/0001_things.up.sql":    {Data: []byte("CREATE TABLE things (id integer PRIMARY KEY);")},
+		"sql/sqlite/0001_things.down.sql":  {Data: []byte("DROP TABLE things;")},
+		"sql/sqlite/0002_widgets.up.sql":   {Data: []byte("CREATE TABLE widgets (id integer PRIMARY KEY); CREATE INDEX idx_widgets ON widgets (id);")},
+		"sql/sqlite/0002_widgets.down.sql": {Data: []byte("DROP TABLE widgets;")},
+	})
+	require.NoError(t, err)
+
+	applied, err := m.Up(ctx)
+	require.NoError(t, err)
+	require.Len(t, applied, 2)
+	assert.Equal(t, int64(1), applied[0].Version)
+	assert.Equal(t, "widgets", applied[1].Name)
+	assert.True(t, db.Migrator().HasTable("widgets"))
+
+	applied, err = m.Up(ctx)
+	require.NoError(t, err)
+	assert.Empty(t, applied)
+
+	reverted, err := m.Down(ctx, 1)
+	require.NoError(t, err)
+	require.Len(t, reverted, 1)
+	assert.Equal(t, int64(2), reverted[0].Version)
+	assert.False(t, db.Migrator().HasTable("widgets"))
+	assert.True(t, db.Migrator().HasTable("things"))
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	require.Len(t, statuses, 2)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+}
+
+func TestUp_FailedMigrationRollsBack(t *testing.T) {
+	ctx := context.Background()
+	sqlDB, err := newTestDB(t).DB()
+	require.NoError(t, err)
+
+	m, err := newMigrator(sqlDB, "sqlite", fstest.MapFS{
+		"sql/sqlite/0001_ok.up.sql":    {Data: []byte("CREATE TABLE ok (id integer);")},
+		"sql/sqlite/0001_ok.down.sql":  {Data: []byte("DROP TABLE ok;")},
+		"sql/sqlite/0002_bad.up.sql":   {Data: []byte("CREATE TABLE bad (id integer); NOT SQL;")},
+		"sql/sqlite/0002_bad.down.sql": {Data: []byte("DROP TABLE bad;")},
+	})
+	require.NoError(t, err)
+
+	_, err = m.Up(ctx)
+	assert.ErrorContains(t, err, "migration 2_bad")
+
+	statuses, err := m.Status(ctx)
+	require.NoError(t, err)
+	assert.NotNil(t, statuses[0].AppliedAt)
+	assert.Nil(t, statuses[1].AppliedAt)
+}
+
+func TestLoad_Invalid(t *testing.T) {
+	tests := map[string]fstest.MapFS{
+		"missing down": {
+			"sql/sqlite/0001_a.up.sql": {Data: []byte("SELECT 1;")},
+		},
+		"conflicting names": {
+			"sql/sqlite/0001_a.up.sql":   {Data: []byte("SELECT 1;")},
+			"sql/sqlite/0001_b.down.sql": {Data: []byte("SELECT 1;")},
+		},
+		"unexpected file": {
+			"sql/sqlite/README.md": {Data: []byte("notes")},
+		},
+	}
+	for name, fsys := range tests {
+		t.Run(name, func(t *testing.T) {
+			_, err := load(fsys, "sql/sqlite")
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestEmbeddedMigrations(t *testing.T) {
+	for _, dialect := range []string{"postgres", "sqlite"} {
+		migrations, err := load(scripts, "sql/"+dialect)
+		require.NoError(t, err, dialect)
+		assert.NotEmpty(t, migrations, dialect)
+	}
+}
+
+// baselineUser and baselineBook are the models as the AutoMigrate setup
+// created them, before roles and creation times existed.
+type baselineUser struct {
+	ID       uint `gorm:"primaryKey"`
+	Name     string
+	Username string `gorm:"unique"`
+	Password string
+}
+
+func (baselineUser) TableName() string { return "users" }
+
+type baselineBook struct {
+	ID     uint   `gorm:"primaryKey"`
+	Title  string `gorm:"unique"`
+	Author string
+	UserId uint
+}
+
+func (baselineBook) TableName() string { return "books" }
+
+func TestUp_AdoptsAutoMigrateSchema(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	ctx := context.Background()
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	require.NoError(t, err)
+
+	m, err := New(db)
+	require.NoError(t, err)
+	require.NoError(t, db.Migrator().DropTable("schema_migrations", "users", "books", "refresh_tokens", "revoked_tokens",
+		"login_attempts", "lockout_events"))
+	t.Cleanup(func() { m.Down(ctx, len(m.migrations)) })
+
+	require.NoError(t, db.AutoMigrate(&baselineUser{}, &baselineBook{}))
+	require.NoError(t, db.Create(&baselineUser{Name: "Ann", Username: "ann", Password: "hash"}).Error)
+	require.NoError(t, db.Create(&baselineBook{Title: "Go in Action", Author: "William", UserId: 1}).Error)
+
+	_, err = m.Up(ctx)
+	require.NoError(t, err)
+
+	var role string
+	require.NoError(t, db.Raw("SELECT role FROM users WHERE username = ?", "ann").Scan(&role).Error)
+	assert.Equal(t, "user", role)
+	require.NoError(t, db.Exec("INSERT INTO users (name, username, password, role) VALUES (?, ?, ?, ?)",
+		"Bob", "bob", "hash", "admin").Error)
+
+	var books int64
+	require.NoError(t, db.Raw("SELECT count(*) FROM books WHERE created_at IS NOT NULL").Scan(&books).Error)
+	assert.Equal(t, int64(1), books)
+}
@@ -0,0 +1,2 @@
+DROP TABLE IF EXISTS lockout_events;
+DROP TABLE IF EXISTS login_attempts;
@@ -0,0 +1,18 @@
+-- Failed sign-ins per username or client address, and the lockouts they caused.
+CREATE TABLE login_attempts (
+    key             text PRIMARY KEY,
+    failures        integer NOT NULL,
+    last_failure_at timestamptz NOT NULL,
+    locked_until    timestamptz
+);
+
+CREATE TABLE lockout_events (
+    id           bigserial PRIMARY KEY,
+    key          text NOT NULL,
+    username     text,
+    client_ip    text,
+    failures     integer NOT NULL,
+    locked_until timestamptz NOT NULL,
+    created_at   timestamptz
+);
+CREATE INDEX idx_lockout_events_key ON lockout_events (key);
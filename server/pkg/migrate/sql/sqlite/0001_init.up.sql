@@ -0,0 +1,42 @@
+-- Baseline schema. IF NOT EXISTS lets databases created by the former
+-- AutoMigrate setup adopt the migration history unchanged: SQLite storage
+-- only ever ran AutoMigrate on models that already had users.role and
+-- books.created_at.
+CREATE TABLE IF NOT EXISTS users (
+    id       integer PRIMARY KEY AUTOINCREMENT,
+    name     text,
+    username text,
+    password text,
+    role     text NOT NULL DEFAULT 'user',
+    CONSTRAINT uni_users_username UNIQUE (username)
+);
+
+CREATE TABLE IF NOT EXISTS books (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    title      text,
+    author     text,
+    user_id    integer,
+    created_at datetime NOT NULL DEFAULT CURRENT_TIMESTAMP,
+    CONSTRAINT uni_books_title UNIQUE (title)
+);
+
+CREATE TABLE IF NOT EXISTS refresh_tokens (
+    id         integer PRIMARY KEY AUTOINCREMENT,
+    user_id    integer NOT NULL,
+    family_id  text NOT NULL,
+    token_hash text NOT NULL,
+    expires_at datetime NOT NULL,
+    used_at    datetime,
+    revoked_at datetime,
+    created_at datetime
+);
+CREATE UNIQUE INDEX IF NOT EXISTS idx_refresh_tokens_token_hash ON refresh_tokens (token_hash);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_family_id ON refresh_tokens (family_id);
+CREATE INDEX IF NOT EXISTS idx_refresh_tokens_user_id ON refresh_tokens (user_id);
+
+CREATE TABLE IF NOT EXISTS revoked_tokens (
+    jti        text PRIMARY KEY,
+    expires_at datetime NOT NULL,
+    created_at datetime
+);
+CREATE INDEX IF NOT EXISTS idx_revoked_tokens_expires_at ON revoked_tokens (expires_at);
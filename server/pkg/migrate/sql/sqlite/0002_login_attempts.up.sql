@@ -0,0 +1,18 @@
+-- Failed sign-ins per username or client address, and the lockouts they caused.
+CREATE TABLE login_attempts (
+    key             text PRIMARY KEY,
+    failures        integer NOT NULL,
+    last_failure_at datetime NOT NULL,
+    locked_until    datetime
+);
+
+CREATE TABLE lockout_events (
+    id           integer PRIMARY KEY AUTOINCREMENT,
+    key          text NOT NULL,
+    username     text,
+    client_ip    text,
+    failures     integer NOT NULL,
+    locked_until datetime NOT NULL,
+    created_at   datetime
+);
+CREATE INDEX idx_lockout_events_key ON lockout_events (key);
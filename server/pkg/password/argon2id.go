@@ -0,0 +1,135 @@
+package password
+
+import (
+	"crypto/rand"
+	"crypto/subtle"
+	"encoding/base64"
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/argon2"
+)
+
+type Argon2idParams struct {
+	// Memory is in KiB.
+	Memory      uint32 `mapstructure:"memory"`
+	Iterations  uint32 `mapstructure:"iterations"`
+	Parallelism uint8  `mapstructure:"parallelism"`
+	SaltLength  uint32 `mapstructure:"salt_length"`
+	KeyLength   uint32 `mapstructure:"key_length"`
+}
+
+// DefaultArgon2idParams follows the OWASP recommendation of 19 MiB and two
+// iterations.
+func DefaultArgon2idParams() Argon2idParams {
+	return Argon2idParams{
+		Memory:      19 * 1024,
+		Iterations:  2,
+		Parallelism: 1,
+		SaltLength:  16,
+		KeyLength:   32,
+	}
+}
+
+const argon2idPrefix = "$argon2id$"
+
+// maxArgon2idFactor bounds the memory, iterations and threads accepted from
+// a stored hash to this multiple of the configured values, or of the
+// defaults when those are higher, so that a tampered hash cannot make one
+// sign-in allocate gigabytes or run for minutes.
+const maxArgon2idFactor = 4
+
+// argon2idScheme produces hashes in the PHC string format used by the
+// reference implementation:
+//
+//	$argon2id$v=19$m=19456,t=2,p=1$<salt>$<key>
+//
+// with salt and key in unpadded base64.
+type argon2idScheme struct {
+	params Argon2idParams
+	// limits holds the largest Memory, Iterations and Parallelism accepted
+	// when verifying.
+	limits Argon2idParams
+}
+
+func newArgon2id(p Argon2idParams) (*argon2idScheme, error) {
+	if p.Memory < 8*uint32(p.Parallelism) || p.Iterations < 1 || p.Parallelism < 1 {
+		return nil, errors.New("argon2id needs at least one iteration and thread, and 8 KiB of memory per thread")
+	}
+	if p.SaltLength < 8 || p.KeyLength < 16 {
+		return nil, errors.New("argon2id salt_length must be at least 8 and key_length at least 16")
+	}
+	d := DefaultArgon2idParams()
+	limits := Argon2idParams{
+		Memory:      maxArgon2idFactor * max(p.Memory, d.Memory),
+		Iterations:  maxArgon2idFactor * max(p.Iterations, d.Iterations),
+		Parallelism: uint8(min(maxArgon2idFactor*uint32(max(p.Parallelism, d.Parallelism)), 255)),
+	}
+	return &argon2idScheme{params: p, limits: limits}, nil
+}
+
+func (s *argon2idScheme) Hash(password string) (string, error) {
+	salt := make([]byte, s.params.SaltLength)
+	if _, err := rand.Read(salt); err != nil {
+		return "", err
+	}
+	p := s.params
+	key := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return fmt.Sprintf("%sv=%d$m=%d,t=%d,p=%d$%s$%s", argon2idPrefix, argon2.Version,
+		p.Memory, p.Iterations, p.Parallelism,
+		base64.RawStdEncoding.EncodeToString(salt), base64.RawStdEncoding.EncodeToString(key)), nil
+}
+
+func (s *argon2idScheme) Verify(encoded, password string) (bool, error) {
+	p, salt, key, err := decodeArgon2id(encoded)
+	if err != nil {
+		return false, err
+	}
+	if p.Memory > s.limits.Memory || p.Iterations > s.limits.Iterations || p.Parallelism > s.limits.Parallelism {
+		return false, fmt.Errorf("%w: argon2id parameters m=%d,t=%d,p=%d exceed the allowed maximum",
+			ErrUnknownHash, p.Memory, p.Iterations, p.Parallelism)
+	}
+	other := argon2.IDKey([]byte(password), salt, p.Iterations, p.Memory, p.Parallelism, p.KeyLength)
+	return subtle.ConstantTimeCompare(key, other) == 1, nil
+}
+
+func (s *argon2idScheme) NeedsRehash(encoded string) bool {
+	p, _, _, err := decodeArgon2id(encoded)
+	return err != nil || p != s.params
+}
+
+func (s *argon2idScheme) identifies(encoded string) bool {
+	return strings.HasPrefix(encoded, argon2idPrefix)
+}
+
+func decodeArgon2id(encoded string) (Argon2idParams, []byte, []byte, error) {
+	var p Argon2idParams
+	parts := strings.Split(encoded, "$")
+	if len(parts) != 6 || parts[1] != AlgorithmArgon2id {
+		return p, nil, nil, ErrUnknownHash
+	}
+
+	var version int
+	if _, err := fmt.Sscanf(parts[2], "v=%d", &version); err != nil || version != argon2.Version {
+		return p, nil, nil, fmt.Errorf("%w: unsupported argon2 version", ErrUnknownHash)
+	}
+	if _, err := fmt.Sscanf(parts[3], "m=%d,t=%d,p=%d", &p.Memory, &p.Iterations, &p.Parallelism); err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	if p.Iterations < 1 || p.Parallelism < 1 {
+		return p, nil, nil, fmt.Errorf("%w: invalid argon2id parameters", ErrUnknownHash)
+	}
+
+	salt, err := base64.RawStdEncoding.DecodeString(parts[4])
+	if err != nil {
+		return p, nil, nil, fmt.Errorf("%w: %v", ErrUnknownHash, err)
+	}
+	key, err := base64.RawStdEncoding.DecodeString(parts[5])
+	if err != nil || len(key) == 0 {
+		return p, nil, nil, fmt.Errorf("%w: invalid argon2id key", ErrUnknownHash)
+	}
+	p.SaltLength = uint32(len(salt))
+	p.KeyLength = uint32(len(key))
+	return p, salt, key, nil
+}
@@ -0,0 +1,62 @@
+package password
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+
+	"golang.org/x/crypto/bcrypt"
+)
+
+type BcryptParams struct {
+	Cost int `mapstructure:"cost"`
+}
+
+func DefaultBcryptParams() BcryptParams {
+	return BcryptParams{Cost: bcrypt.DefaultCost}
+}
+
+// bcryptScheme produces the usual "$2a$<cost>$..." hashes.
+type bcryptScheme struct {
+	cost int
+}
+
+func newBcrypt(p BcryptParams) (*bcryptScheme, error) {
+	if p.Cost < bcrypt.MinCost || p.Cost > bcrypt.MaxCost {
+		return nil, fmt.Errorf("bcrypt cost must be between %d and %d", bcrypt.MinCost, bcrypt.MaxCost)
+	}
+	return &bcryptScheme{cost: p.Cost}, nil
+}
+
+func (s *bcryptScheme) Hash(password string) (string, error) {
+	hash, err := bcrypt.GenerateFromPassword([]byte(password), s.cost)
+	if errors.Is(err, bcrypt.ErrPasswordTooLong) {
+		return "", fmt.Errorf("%w: bcrypt accepts at most 72 bytes", ErrWeakPassword)
+	}
+	if err != nil {
+		return "", err
+	}
+	return string(hash), nil
+}
+
+func (s *bcryptScheme) Verify(encoded, password string) (bool, error) {
+	err := bcrypt.CompareHashAndPassword([]byte(encoded), []byte(password))
+	if errors.Is(err, bcrypt.ErrMismatchedHashAndPassword) {
+		return false, nil
+	}
+	return err == nil, err
+}
+
+func (s *bcryptScheme) NeedsRehash(encoded string) bool {
+	cost, err := bcrypt.Cost([]byte(encoded))
+	return err != nil || cost != s.cost
+}
+
+func (s *bcryptScheme) identifies(encoded string) bool {
+	for _, prefix := range []string{"$2a$", "$2b$", "$2y$"} {
+		if strings.HasPrefix(encoded, prefix) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,125 @@
+// Package password hashes user passwords and checks new ones against a
+// policy. Hashes are stored in an encoded form that names the algorithm and
+// its parameters, so hashes made with older settings keep verifying and can
+// be replaced when their owner next signs in.
+package password
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"time"
+)
+
+// ErrUnknownHash is returned for stored hashes that no algorithm recognises.
+var ErrUnknownHash = errors.New("unrecognised password hash")
+
+const (
+	AlgorithmBcrypt   = "bcrypt"
+	AlgorithmArgon2id = "argon2id"
+)
+
+type Hasher interface {
+	// Hash encodes password with a fresh salt.
+	Hash(password string) (string, error)
+	// Verify reports whether password matches the encoded hash.
+	Verify(encoded, password string) (bool, error)
+	// NeedsRehash reports whether encoded was made with another algorithm
+	// or other parameters than Hash uses now.
+	NeedsRehash(encoded string) bool
+	// DummyHash returns a hash of a random password that takes as long to
+	// Verify as the slowest algorithm with its configured parameters, for
+	// checking passwords given for users that do not exist.
+	DummyHash() (string, error)
+}
+
+// scheme hashes with one algorithm and recognises its own hashes.
+type scheme interface {
+	Hash(password string) (string, error)
+	Verify(encoded, password string) (bool, error)
+	NeedsRehash(encoded string) bool
+	identifies(encoded string) bool
+}
+
+// Config selects the algorithm for new hashes. Hashes of the other
+// algorithm still verify.
+type Config struct {
+	// Algorithm is "bcrypt" or "argon2id".
+	Algorithm string         `mapstructure:"algorithm"`
+	Bcrypt    BcryptParams   `mapstructure:"bcrypt"`
+	Argon2id  Argon2idParams `mapstructure:"argon2id"`
+}
+
+func DefaultConfig() Config {
+	return Config{
+		Algorithm: AlgorithmArgon2id,
+		Bcrypt:    DefaultBcryptParams(),
+		Argon2id:  DefaultArgon2idParams(),
+	}
+}
+
+type hasher struct {
+	current scheme
+	schemes []scheme
+}
+
+func New(cfg Config) (Hasher, error) {
+	b, err := newBcrypt(cfg.Bcrypt)
+	if err != nil {
+		return nil, err
+	}
+	a, err := newArgon2id(cfg.Argon2id)
+	if err != nil {
+		return nil, err
+	}
+
+	h := &hasher{schemes: []scheme{b, a}}
+	switch cfg.Algorithm {
+	case AlgorithmBcrypt:
+		h.current = b
+	case AlgorithmArgon2id:
+		h.current = a
+	default:
+		return nil, fmt.Errorf("unknown password hashing algorithm %q", cfg.Algorithm)
+	}
+	return h, nil
+}
+
+func (h *hasher) Hash(password string) (string, error) {
+	return h.current.Hash(password)
+}
+
+func (h *hasher) Verify(encoded, password string) (bool, error) {
+	for _, s := range h.schemes {
+		if s.identifies(encoded) {
+			return s.Verify(encoded, password)
+		}
+	}
+	return false, ErrUnknownHash
+}
+
+// DummyHash times one verification per algorithm rather than using the
+// current one, since stored hashes of the other algorithm, such as bcrypt
+// hashes from before argon2id, may be slower to check.
+func (h *hasher) DummyHash() (string, error) {
+	var slowest string
+	var longest time.Duration
+	for _, s := range h.schemes {
+		encoded, err := s.Hash(rand.Text())
+		if err != nil {
+			return "", err
+		}
+		start := time.Now()
+		if _, err := s.Verify(encoded, "dummy password"); err != nil {
+			return "", err
+		}
+		if took := time.Since(start); slowest == "" || took > longest {
+			slowest, longest = encoded, took
+		}
+	}
+	return slowest, nil
+}
+
+func (h *hasher) NeedsRehash(encoded string) bool {
+	return !h.current.identifies(encoded) || h.current.NeedsRehash(encoded)
+}
@@ -0,0 +1,155 @@
+package password
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"golang.org/x/crypto/bcrypt"
+)
+
+// testConfig keeps hashing cheap; the parameters matter only for rehashing.
+func testConfig(algorithm string) Config {
+	return Config{
+		Algorithm: algorithm,
+		Bcrypt:    BcryptParams{Cost: bcrypt.MinCost},
+		Argon2id:  Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	}
+}
+
+func TestHasher_RoundTrip(t *testing.T) {
+	for _, algorithm := range []string{AlgorithmBcrypt, AlgorithmArgon2id} {
+		t.Run(algorithm, func(t *testing.T) {
+			h, err := New(testConfig(algorithm))
+			require.NoError(t, err)
+
+			encoded, err := h.Hash("correct horse")
+			require.NoError(t, err)
+			other, err := h.Hash("correct horse")
+			require.NoError(t, err)
+			assert.NotEqual(t, encoded, other, "every hash gets its own salt")
+
+			ok, err := h.Verify(encoded, "correct horse")
+			require.NoError(t, err)
+			assert.True(t, ok)
+			ok, err = h.Verify(encoded, "wrong horse")
+			require.NoError(t, err)
+			assert.False(t, ok)
+			assert.False(t, h.NeedsRehash(encoded))
+		})
+	}
+}
+
+func TestHasher_Argon2idEncoding(t *testing.T) {
+	h, err := New(testConfig(AlgorithmArgon2id))
+	require.NoError(t, err)
+
+	encoded, err := h.Hash("secret")
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(encoded, "$argon2id$v=19$m=64,t=1,p=1$"), encoded)
+
+	// A test vector of the reference implementation, with other parameters.
+	reference := "$argon2id$v=19$m=65536,t=2,p=1$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+	ok, err := h.Verify(reference, "password")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, h.NeedsRehash(reference))
+}
+
+func TestHasher_NeedsRehash(t *testing.T) {
+	bcryptHasher, err := New(testConfig(AlgorithmBcrypt))
+	require.NoError(t, err)
+	argonHasher, err := New(testConfig(AlgorithmArgon2id))
+	require.NoError(t, err)
+
+	bcryptHash, err := bcryptHasher.Hash("secret")
+	require.NoError(t, err)
+	argonHash, err := argonHasher.Hash("secret")
+	require.NoError(t, err)
+
+	// Either hasher verifies both kinds of hash but wants its own.
+	ok, err := argonHasher.Verify(bcryptHash, "secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, argonHasher.NeedsRehash(bcryptHash))
+	ok, err = bcryptHasher.Verify(argonHash, "secret")
+	require.NoError(t, err)
+	assert.True(t, ok)
+	assert.True(t, bcryptHasher.NeedsRehash(argonHash))
+
+	stronger := testConfig(AlgorithmArgon2id)
+	stronger.Argon2id.Iterations = 2
+	stronger.Bcrypt.Cost++
+	h, err := New(stronger)
+	require.NoError(t, err)
+	assert.True(t, h.NeedsRehash(argonHash))
+
+	stronger.Algorithm = AlgorithmBcrypt
+	h, err = New(stronger)
+	require.NoError(t, err)
+	assert.True(t, h.NeedsRehash(bcryptHash))
+}
+
+func TestHasher_UnknownHash(t *testing.T) {
+	h, err := New(testConfig(AlgorithmArgon2id))
+	require.NoError(t, err)
+
+	for _, encoded := range []string{"", "plaintext", "$argon2i$v=19$m=64,t=1,p=1$c2FsdA$a2V5", "$argon2id$v=16$m=64,t=1,p=1$c2FsdA$a2V5"} {
+		_, err := h.Verify(encoded, "secret")
+		assert.ErrorIs(t, err, ErrUnknownHash, encoded)
+		assert.True(t, h.NeedsRehash(encoded), encoded)
+	}
+}
+
+func TestHasher_Argon2idParameterCeiling(t *testing.T) {
+	h, err := New(testConfig(AlgorithmArgon2id))
+	require.NoError(t, err)
+
+	// The ceiling is relative to the defaults here, since the test
+	// parameters are lower.
+	for _, params := range []string{"m=1048576,t=1,p=1", "m=64,t=100,p=1", "m=64,t=1,p=64"} {
+		encoded := "$argon2id$v=19$" + params + "$c29tZXNhbHQ$CTFhFdXPJO1aFaMaO6Mm5c8y7cJHAph8ArZWb2GRPPc"
+		_, err := h.Verify(encoded, "password")
+		assert.ErrorIs(t, err, ErrUnknownHash, params)
+	}
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	for name, mutate := range map[string]func(*Config){
+		"algorithm":   func(c *Config) { c.Algorithm = "md5" },
+		"bcrypt cost": func(c *Config) { c.Bcrypt.Cost = 40 },
+		"iterations":  func(c *Config) { c.Argon2id.Iterations = 0 },
+		"memory":      func(c *Config) { c.Argon2id.Memory = 4 },
+		"salt":        func(c *Config) { c.Argon2id.SaltLength = 4 },
+	} {
+		cfg := testConfig(AlgorithmArgon2id)
+		mutate(&cfg)
+		_, err := New(cfg)
+		assert.Error(t, err, name)
+	}
+
+	_, err := New(DefaultConfig())
+	assert.NoError(t, err)
+}
+
+func TestHasher_DummyHashIsSlowestAlgorithm(t *testing.T) {
+	// Stored bcrypt hashes can outlast a switch to a cheaper argon2id.
+	slowBcrypt := testConfig(AlgorithmArgon2id)
+	slowBcrypt.Bcrypt.Cost = 11
+	slowArgon2id := testConfig(AlgorithmBcrypt)
+	slowArgon2id.Argon2id.Memory = 64 * 1024
+	slowArgon2id.Argon2id.Iterations = 3
+
+	for prefix, cfg := range map[string]Config{"$2a$11$": slowBcrypt, "$argon2id$v=19$m=65536,t=3": slowArgon2id} {
+		h, err := New(cfg)
+		require.NoError(t, err)
+
+		dummy, err := h.DummyHash()
+		require.NoError(t, err)
+		assert.True(t, strings.HasPrefix(dummy, prefix), dummy)
+		ok, err := h.Verify(dummy, "dummy password")
+		require.NoError(t, err)
+		assert.False(t, ok)
+	}
+}
@@ -0,0 +1,112 @@
+package password
+
+import (
+	"bufio"
+	"errors"
+	"fmt"
+	"os"
+	"strings"
+	"unicode"
+	"unicode/utf8"
+)
+
+// ErrWeakPassword is wrapped by every policy violation, which describes
+// what the password lacks.
+var ErrWeakPassword = errors.New("password does not meet the policy")
+
+// Policy lists the requirements for new passwords. Lengths count
+// characters, not bytes.
+type Policy struct {
+	MinLength     int  `mapstructure:"min_length"`
+	MaxLength     int  `mapstructure:"max_length"`
+	RequireUpper  bool `mapstructure:"require_upper"`
+	RequireLower  bool `mapstructure:"require_lower"`
+	RequireDigit  bool `mapstructure:"require_digit"`
+	RequireSymbol bool `mapstructure:"require_symbol"`
+	// BreachedList is a file with one known breached password per line;
+	// passwords on it are refused. Empty disables the check.
+	BreachedList string `mapstructure:"breached_list"`
+}
+
+func DefaultPolicy() Policy {
+	return Policy{MinLength: 8, MaxLength: 64}
+}
+
+// Checker enforces a Policy, with the breached passwords held in memory.
+type Checker struct {
+	policy   Policy
+	breached map[string]struct{}
+}
+
+func NewChecker(p Policy) (*Checker, error) {
+	if p.MaxLength > 0 && p.MaxLength < p.MinLength {
+		return nil, fmt.Errorf("password max_length %d is below min_length %d", p.MaxLength, p.MinLength)
+	}
+	c := &Checker{policy: p}
+	if p.BreachedList == "" {
+		return c, nil
+	}
+
+	f, err := os.Open(p.BreachedList)
+	if err != nil {
+		return nil, fmt.Errorf("loading breached passwords: %w", err)
+	}
+	defer f.Close()
+
+	c.breached = make(map[string]struct{})
+	scanner := bufio.NewScanner(f)
+	for scanner.Scan() {
+		if line := strings.TrimRight(scanner.Text(), "\r"); line != "" {
+			c.breached[line] = struct{}{}
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("loading breached passwords: %w", err)
+	}
+	return c, nil
+}
+
+// Check returns an error wrapping ErrWeakPassword for the first requirement
+// password fails.
+func (c *Checker) Check(password string) error {
+	p := c.policy
+	length := utf8.RuneCountInString(password)
+	if length < p.MinLength {
+		return fmt.Errorf("%w: must be at least %d characters", ErrWeakPassword, p.MinLength)
+	}
+	if p.MaxLength > 0 && length > p.MaxLength {
+		return fmt.Errorf("%w: must be at most %d characters", ErrWeakPassword, p.MaxLength)
+	}
+
+	var upper, lower, digit, symbol bool
+	for _, r := range password {
+		switch {
+		case unicode.IsUpper(r):
+			upper = true
+		case unicode.IsLower(r):
+			lower = true
+		case unicode.IsDigit(r):
+			digit = true
+		case unicode.IsPunct(r) || unicode.IsSymbol(r) || unicode.IsSpace(r):
+			symbol = true
+		}
+	}
+	for _, class := range []struct {
+		required, present bool
+		name              string
+	}{
+		{p.RequireUpper, upper, "an uppercase letter"},
+		{p.RequireLower, lower, "a lowercase letter"},
+		{p.RequireDigit, digit, "a digit"},
+		{p.RequireSymbol, symbol, "a symbol"},
+	} {
+		if class.required && !class.present {
+			return fmt.Errorf("%w: must contain %s", ErrWeakPassword, class.name)
+		}
+	}
+
+	if _, ok := c.breached[password]; ok {
+		return fmt.Errorf("%w: it appears in a list of breached passwords", ErrWeakPassword)
+	}
+	return nil
+}
@@ -0,0 +1,60 @@
+package password
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestChecker_Check(t *testing.T) {
+	list := filepath.Join(t.TempDir(), "breached.txt")
+	require.NoError(t, os.WriteFile(list, []byte("Password1!\r\n\nQwerty123$\n"), 0o600))
+
+	c, err := NewChecker(Policy{
+		MinLength:     8,
+		MaxLength:     12,
+		RequireUpper:  true,
+		RequireLower:  true,
+		RequireDigit:  true,
+		RequireSymbol: true,
+		BreachedList:  list,
+	})
+	require.NoError(t, err)
+
+	assert.NoError(t, c.Check("Tr0ub4dor&3"))
+	assert.NoError(t, c.Check("Ünïcödé1!"), "lengths count characters")
+
+	for password, reason := range map[string]string{
+		"Sh0rt!":        "must be at least 8 characters",
+		"Much2Long!!!!": "must be at most 12 characters",
+		"tr0ub4dor&3":   "must contain an uppercase letter",
+		"TR0UB4DOR&3":   "must contain a lowercase letter",
+		"Troubador&x":   "must contain a digit",
+		"Tr0ub4dorx3":   "must contain a symbol",
+		"Password1!":    "it appears in a list of breached passwords",
+		"Qwerty123$":    "it appears in a list of breached passwords",
+	} {
+		err := c.Check(password)
+		assert.ErrorIs(t, err, ErrWeakPassword, password)
+		assert.EqualError(t, err, "password does not meet the policy: "+reason, password)
+	}
+}
+
+func TestChecker_DefaultPolicy(t *testing.T) {
+	c, err := NewChecker(DefaultPolicy())
+	require.NoError(t, err)
+
+	assert.NoError(t, c.Check("all lowercase is fine"))
+	assert.ErrorIs(t, c.Check("short"), ErrWeakPassword)
+}
+
+func TestNewChecker_Invalid(t *testing.T) {
+	_, err := NewChecker(Policy{MinLength: 10, MaxLength: 8})
+	assert.Error(t, err)
+
+	_, err = NewChecker(Policy{BreachedList: filepath.Join(t.TempDir(), "missing.txt")})
+	assert.Error(t, err)
+}
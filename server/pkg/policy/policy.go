@@ -0,0 +1,67 @@
+package policy
+
+import (
+	"fmt"
+	"slices"
+	"strings"
+)
+
+// Rule declares who may call a set of gRPC methods. Methods are full method
+// names such as "/proto.BookService/GetBook", or "/proto.BookService/*" for
+// every method of a service not matched by a more specific rule.
+type Rule struct {
+	Methods []string `mapstructure:"methods"`
+	// Public methods are served without a token.
+	Public bool `mapstructure:"public"`
+	// Roles lists the roles allowed to call the methods.
+	Roles []string `mapstructure:"roles"`
+	// AnyOwner lists the roles that may act on resources owned by other users.
+	AnyOwner []string `mapstructure:"any_owner"`
+}
+
+func (r Rule) Allows(role string) bool {
+	return r.Public || slices.Contains(r.Roles, role)
+}
+
+func (r Rule) CanActOnAnyOwner(role string) bool {
+	return slices.Contains(r.AnyOwner, role)
+}
+
+// Policy resolves the rule of a gRPC method. Methods without a rule are
+// denied.
+type Policy struct {
+	rules map[string]Rule
+}
+
+func New(rules []Rule) (*Policy, error) {
+	p := &Policy{rules: make(map[string]Rule)}
+	for _, rule := range rules {
+		if len(rule.Methods) == 0 {
+			return nil, fmt.Errorf("policy rule without methods")
+		}
+		for _, method := range rule.Methods {
+			if !strings.HasPrefix(method, "/") {
+				return nil, fmt.Errorf("policy method %q must be a full method name", method)
+			}
+			if !rule.Public && len(rule.Roles) == 0 {
+				return nil, fmt.Errorf("policy for %s is neither public nor grants any role", method)
+			}
+			if _, ok := p.rules[method]; ok {
+				return nil, fmt.Errorf("duplicate policy for %s", method)
+			}
+			p.rules[method] = rule
+		}
+	}
+	return p, nil
+}
+
+func (p *Policy) Lookup(fullMethod string) (Rule, bool) {
+	if rule, ok := p.rules[fullMethod]; ok {
+		return rule, true
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		rule, ok := p.rules[fullMethod[:i]+"/*"]
+		return rule, ok
+	}
+	return Rule{}, false
+}
@@ -0,0 +1,137 @@
+package policy
+
+import (
+	"testing"
+
+	pb "grpc/proto"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protodesc"
+	"google.golang.org/protobuf/types/descriptorpb"
+)
+
+func TestPolicy_Lookup(t *testing.T) {
+	p, err := New([]Rule{
+		{Methods: []string{"/proto.UserService/SignIn"}, Public: true},
+		{Methods: []string{"/proto.BookService/*"}, Roles: []string{"user", "admin"}},
+		{Methods: []string{"/proto.BookService/DeleteBook"}, Roles: []string{"user", "admin"}, AnyOwner: []string{"admin"}},
+	})
+	require.NoError(t, err)
+
+	rule, ok := p.Lookup("/proto.UserService/SignIn")
+	assert.True(t, ok)
+	assert.True(t, rule.Allows(""))
+
+	rule, ok = p.Lookup("/proto.BookService/GetBook")
+	assert.True(t, ok)
+	assert.True(t, rule.Allows("user"))
+	assert.False(t, rule.Allows("guest"))
+	assert.False(t, rule.CanActOnAnyOwner("admin"))
+
+	rule, ok = p.Lookup("/proto.BookService/DeleteBook")
+	assert.True(t, ok)
+	assert.True(t, rule.CanActOnAnyOwner("admin"))
+	assert.False(t, rule.CanActOnAnyOwner("user"))
+
+	_, ok = p.Lookup("/proto.UserService/SignOut")
+	assert.False(t, ok)
+}
+
+func TestNew_InvalidRules(t *testing.T) {
+	cases := map[string][]Rule{
+		"no methods":     {{Public: true}},
+		"relative name":  {{Methods: []string{"BookService/GetBook"}, Public: true}},
+		"grants nothing": {{Methods: []string{"/proto.BookService/GetBook"}}},
+		"duplicate": {
+			{Methods: []string{"/proto.BookService/GetBook"}, Public: true},
+			{Methods: []string{"/proto.BookService/GetBook"}, Roles: []string{"user"}},
+		},
+	}
+	for name, rules := range cases {
+		_, err := New(rules)
+		assert.Error(t, err, name)
+	}
+}
+
+func TestFromServices(t *testing.T) {
+	rules, err := FromFile(pb.File_proto_book_proto)
+	require.NoError(t, err)
+
+	p, err := New(rules)
+	require.NoError(t, err)
+
+	rule, ok := p.Lookup("/proto.UserService/SignIn")
+	assert.True(t, ok)
+	assert.True(t, rule.Public)
+
+	rule, ok = p.Lookup("/proto.BookService/DeleteBook")
+	assert.True(t, ok)
+	assert.False(t, rule.Public)
+	assert.True(t, rule.Allows("user"))
+	assert.True(t, rule.CanActOnAnyOwner("admin"))
+	assert.False(t, rule.CanActOnAnyOwner("user"))
+
+	services := pb.File_proto_book_proto.Services()
+	for j := 0; j < services.Len(); j++ {
+		svc := services.Get(j)
+		methods := svc.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			_, ok := p.Lookup("/" + string(svc.FullName()) + "/" + string(methods.Get(i).Name()))
+			assert.True(t, ok, methods.Get(i).FullName())
+		}
+	}
+}
+
+func TestFromServices_MissingOption(t *testing.T) {
+	fd, err := protodesc.NewFile(&descriptorpb.FileDescriptorProto{
+		Name:    proto.String("undeclared.proto"),
+		Package: proto.String("test"),
+		Syntax:  proto.String("proto3"),
+		MessageType: []*descriptorpb.DescriptorProto{
+			{Name: proto.String("Empty")},
+		},
+		Service: []*descriptorpb.ServiceDescriptorProto{{
+			Name: proto.String("Svc"),
+			Method: []*descriptorpb.MethodDescriptorProto{{
+				Name:       proto.String("Call"),
+				InputType:  proto.String(".test.Empty"),
+				OutputType: proto.String(".test.Empty"),
+			}},
+		}},
+	}, nil)
+	require.NoError(t, err)
+
+	_, err = FromServices(fd.Services().Get(0))
+	assert.ErrorContains(t, err, "/test.Svc/Call")
+}
+
+func TestFromServices_NilService(t *testing.T) {
+	_, err := FromServices(pb.File_proto_book_proto.Services().ByName("NoSuchService"))
+	assert.Error(t, err)
+}
+
+func TestOverride(t *testing.T) {
+	base := []Rule{
+		{Methods: []string{"/proto.BookService/GetBook"}, Roles: []string{"user"}},
+		{Methods: []string{"/proto.BookService/DeleteBook"}, Roles: []string{"user"}},
+		{Methods: []string{"/proto.UserService/SignIn"}, Public: true},
+	}
+
+	p, err := New(Override(base, []Rule{
+		{Methods: []string{"/proto.BookService/DeleteBook"}, Roles: []string{"user"}, AnyOwner: []string{"moderator"}},
+	}))
+	require.NoError(t, err)
+	rule, _ := p.Lookup("/proto.BookService/DeleteBook")
+	assert.True(t, rule.CanActOnAnyOwner("moderator"))
+
+	p, err = New(Override(base, []Rule{
+		{Methods: []string{"/proto.BookService/*"}, Roles: []string{"admin"}},
+	}))
+	require.NoError(t, err)
+	rule, _ = p.Lookup("/proto.BookService/GetBook")
+	assert.False(t, rule.Allows("user"))
+	rule, _ = p.Lookup("/proto.UserService/SignIn")
+	assert.True(t, rule.Public)
+}
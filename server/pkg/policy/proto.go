@@ -0,0 +1,82 @@
+package policy
+
+import (
+	"fmt"
+	"strings"
+
+	pb "grpc/proto"
+
+	"google.golang.org/protobuf/proto"
+	"google.golang.org/protobuf/reflect/protoreflect"
+)
+
+// FromFile builds the rules of every service declared in file, so a service
+// added to the proto file cannot skip the check that its RPCs declare (auth).
+func FromFile(file protoreflect.FileDescriptor) ([]Rule, error) {
+	services := file.Services()
+	descriptors := make([]protoreflect.ServiceDescriptor, services.Len())
+	for i := range descriptors {
+		descriptors[i] = services.Get(i)
+	}
+	return FromServices(descriptors...)
+}
+
+// FromServices builds one rule per RPC from the (auth) method option declared
+// in the proto files. Every RPC must declare the option.
+func FromServices(services ...protoreflect.ServiceDescriptor) ([]Rule, error) {
+	var rules []Rule
+	for _, svc := range services {
+		if svc == nil {
+			return nil, fmt.Errorf("missing service descriptor")
+		}
+		methods := svc.Methods()
+		for i := 0; i < methods.Len(); i++ {
+			method := methods.Get(i)
+			fullMethod := fmt.Sprintf("/%s/%s", svc.FullName(), method.Name())
+
+			opts := method.Options()
+			if opts == nil || !proto.HasExtension(opts, pb.E_Auth) {
+				return nil, fmt.Errorf("rpc %s has no (auth) option", fullMethod)
+			}
+			auth := proto.GetExtension(opts, pb.E_Auth).(*pb.AuthRule)
+
+			rules = append(rules, Rule{
+				Methods:  []string{fullMethod},
+				Public:   auth.GetPublic(),
+				Roles:    auth.GetRoles(),
+				AnyOwner: auth.GetAnyOwner(),
+			})
+		}
+	}
+	return rules, nil
+}
+
+// Override replaces the base rules of every method named by an override. A
+// "/Svc/*" override replaces the rules of all methods of that service.
+func Override(base, overrides []Rule) []Rule {
+	replaced := func(method string) bool {
+		for _, rule := range overrides {
+			for _, m := range rule.Methods {
+				if m == method || (strings.HasSuffix(m, "/*") && strings.HasPrefix(method, strings.TrimSuffix(m, "*"))) {
+					return true
+				}
+			}
+		}
+		return false
+	}
+
+	var rules []Rule
+	for _, rule := range base {
+		var methods []string
+		for _, m := range rule.Methods {
+			if !replaced(m) {
+				methods = append(methods, m)
+			}
+		}
+		if len(methods) > 0 {
+			rule.Methods = methods
+			rules = append(rules, rule)
+		}
+	}
+	return append(rules, overrides...)
+}
@@ -0,0 +1,237 @@
+// Package ratelimit throttles gRPC calls with token buckets, one per method
+// and caller. Address rules limit every client address before the call is
+// authenticated; the other rules run after authentication and identify
+// authenticated callers by user id, anonymous ones by their client address.
+package ratelimit
+
+import (
+	"context"
+	"fmt"
+	"grpc/server/pkg/clientip"
+	"grpc/server/pkg/handler"
+	"math"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc"
+)
+
+// Rule limits each caller of Methods to Requests calls per Per, with bursts
+// of up to Burst calls. Methods are full method names or "/pkg.Service/*"
+// for every method of a service without a more specific rule; every method
+// has its own buckets.
+type Rule struct {
+	Methods  []string      `mapstructure:"methods"`
+	Requests int           `mapstructure:"requests"`
+	Per      time.Duration `mapstructure:"per"`
+	// Burst defaults to Requests.
+	Burst int `mapstructure:"burst"`
+}
+
+type Config struct {
+	Rules []Rule `mapstructure:"rules"`
+	// AddressRules are checked per client address before authentication,
+	// so that floods of calls with missing or invalid tokens are throttled
+	// too.
+	AddressRules []Rule `mapstructure:"address_rules"`
+}
+
+type limit struct {
+	rate  float64 // tokens per second
+	burst float64
+}
+
+type bucket struct {
+	limit
+	tokens float64
+	last   time.Time
+}
+
+// Limiter holds the buckets of every caller seen recently. Methods without
+// a rule are not limited.
+type Limiter struct {
+	limits        map[string]limit
+	addressLimits map[string]limit
+	now           func() time.Time
+
+	mu        sync.Mutex
+	buckets   map[string]*bucket
+	lastSweep time.Time
+}
+
+func New(cfg Config) (*Limiter, error) {
+	limits, err := parseRules(cfg.Rules)
+	if err != nil {
+		return nil, err
+	}
+	addressLimits, err := parseRules(cfg.AddressRules)
+	if err != nil {
+		return nil, err
+	}
+	return &Limiter{
+		limits:        limits,
+		addressLimits: addressLimits,
+		now:           time.Now,
+		buckets:       make(map[string]*bucket),
+	}, nil
+}
+
+func parseRules(rules []Rule) (map[string]limit, error) {
+	limits := make(map[string]limit)
+	for _, rule := range rules {
+		if len(rule.Methods) == 0 {
+			return nil, fmt.Errorf("rate limit rule without methods")
+		}
+		if rule.Requests <= 0 || rule.Per <= 0 {
+			return nil, fmt.Errorf("rate limit for %v needs positive requests and per", rule.Methods)
+		}
+		burst := rule.Burst
+		if burst <= 0 {
+			burst = rule.Requests
+		}
+		for _, method := range rule.Methods {
+			if !strings.HasPrefix(method, "/") {
+				return nil, fmt.Errorf("rate limit method %q must be a full method name", method)
+			}
+			if _, ok := limits[method]; ok {
+				return nil, fmt.Errorf("duplicate rate limit for %s", method)
+			}
+			limits[method] = limit{
+				rate:  float64(rule.Requests) / rule.Per.Seconds(),
+				burst: float64(burst),
+			}
+		}
+	}
+	return limits, nil
+}
+
+func lookup(limits map[string]limit, fullMethod string) (limit, bool) {
+	if lim, ok := limits[fullMethod]; ok {
+		return lim, true
+	}
+	if i := strings.LastIndex(fullMethod, "/"); i > 0 {
+		lim, ok := limits[fullMethod[:i]+"/*"]
+		return lim, ok
+	}
+	return limit{}, false
+}
+
+// Allow takes a token from the bucket of caller for fullMethod under the
+// rules checked after authentication. When the bucket is empty it returns
+// false and how long until a token is available.
+func (l *Limiter) Allow(fullMethod, caller string) (bool, time.Duration) {
+	return l.take(l.limits, fullMethod, caller)
+}
+
+// AllowAddress is Allow for the address rules, which limit the client
+// address addr whether or not the call is authenticated.
+func (l *Limiter) AllowAddress(fullMethod, addr string) (bool, time.Duration) {
+	return l.take(l.addressLimits, fullMethod, "addr:"+addr)
+}
+
+func (l *Limiter) take(limits map[string]limit, fullMethod, caller string) (bool, time.Duration) {
+	lim, ok := lookup(limits, fullMethod)
+	if !ok {
+		return true, 0
+	}
+
+	l.mu.Lock()
+	defer l.mu.Unlock()
+	now := l.now()
+	l.sweep(now)
+
+	key := fullMethod + " " + caller
+	b, ok := l.buckets[key]
+	if !ok {
+		b = &bucket{limit: lim, tokens: lim.burst, last: now}
+		l.buckets[key] = b
+	}
+	b.tokens = math.Min(b.burst, b.tokens+now.Sub(b.last).Seconds()*b.rate)
+	b.last = now
+
+	if b.tokens < 1 {
+		wait := time.Duration((1 - b.tokens) / b.rate * float64(time.Second))
+		return false, wait.Round(time.Millisecond)
+	}
+	b.tokens--
+	return true, 0
+}
+
+// sweep drops, at most once a minute, the buckets that have refilled
+// completely and so behave exactly like new ones.
+func (l *Limiter) sweep(now time.Time) {
+	if now.Sub(l.lastSweep) < time.Minute {
+		return
+	}
+	l.lastSweep = now
+	for key, b := range l.buckets {
+		if b.tokens+now.Sub(b.last).Seconds()*b.rate >= b.burst {
+			delete(l.buckets, key)
+		}
+	}
+}
+
+// UnaryServerInterceptor rejects calls over the limit with ResourceExhausted.
+// It must run after the auth interceptor so that authenticated callers are
+// limited by user id rather than address.
+func (l *Limiter) UnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return unaryInterceptor(func(ctx context.Context, method string) (bool, time.Duration) {
+		return l.Allow(method, caller(ctx))
+	})
+}
+
+func (l *Limiter) StreamServerInterceptor() grpc.StreamServerInterceptor {
+	return streamInterceptor(func(ctx context.Context, method string) (bool, time.Duration) {
+		return l.Allow(method, caller(ctx))
+	})
+}
+
+// AddressUnaryServerInterceptor applies the address rules. It must run
+// after the clientip interceptor and before the auth interceptor, so that
+// calls are limited before their tokens are checked.
+func (l *Limiter) AddressUnaryServerInterceptor() grpc.UnaryServerInterceptor {
+	return unaryInterceptor(func(ctx context.Context, method string) (bool, time.Duration) {
+		return l.AllowAddress(method, clientip.FromContext(ctx))
+	})
+}
+
+func (l *Limiter) AddressStreamServerInterceptor() grpc.StreamServerInterceptor {
+	return streamInterceptor(func(ctx context.Context, method string) (bool, time.Duration) {
+		return l.AllowAddress(method, clientip.FromContext(ctx))
+	})
+}
+
+func unaryInterceptor(allow func(ctx context.Context, method string) (bool, time.Duration)) grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, next grpc.UnaryHandler) (interface{}, error) {
+		if ok, wait := allow(ctx, info.FullMethod); !ok {
+			grpc.SetTrailer(ctx, handler.RetryAfter(wait))
+			return nil, exhausted(wait)
+		}
+		return next(ctx, req)
+	}
+}
+
+func streamInterceptor(allow func(ctx context.Context, method string) (bool, time.Duration)) grpc.StreamServerInterceptor {
+	return func(srv interface{}, ss grpc.ServerStream, info *grpc.StreamServerInfo, next grpc.StreamHandler) error {
+		if ok, wait := allow(ss.Context(), info.FullMethod); !ok {
+			ss.SetTrailer(handler.RetryAfter(wait))
+			return exhausted(wait)
+		}
+		return next(srv, ss)
+	}
+}
+
+// caller identifies the user of an authenticated call, or else the client
+// address.
+func caller(ctx context.Context) string {
+	if userID, err := handler.UserIDFromContext(ctx); err == nil {
+		return "user:" + strconv.FormatUint(uint64(userID), 10)
+	}
+	return "ip:" + clientip.FromContext(ctx)
+}
+
+func exhausted(wait time.Duration) error {
+	return handler.ResourceExhausted(fmt.Sprintf("rate limit exceeded, retry in %ds", handler.RetryAfterSeconds(wait)), wait)
+}
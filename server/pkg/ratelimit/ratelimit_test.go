@@ -0,0 +1,186 @@
+package ratelimit
+
+import (
+	"context"
+	"grpc/server/models"
+	"grpc/server/pkg/clientip"
+	"grpc/server/pkg/handler"
+	"net"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"google.golang.org/genproto/googleapis/rpc/errdetails"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/peer"
+	"google.golang.org/grpc/status"
+)
+
+type fakeClock struct{ t time.Time }
+
+func (c *fakeClock) now() time.Time          { return c.t }
+func (c *fakeClock) advance(d time.Duration) { c.t = c.t.Add(d) }
+
+func newLimiter(t *testing.T, cfg Config) (*Limiter, *fakeClock) {
+	t.Helper()
+	l, err := New(cfg)
+	require.NoError(t, err)
+	clock := &fakeClock{t: time.Unix(1_700_000_000, 0)}
+	l.now = clock.now
+	return l, clock
+}
+
+func TestLimiter_Allow(t *testing.T) {
+	l, clock := newLimiter(t, Config{Rules: []Rule{
+		{Methods: []string{"/proto.UserService/SignIn"}, Requests: 6, Per: time.Minute, Burst: 2},
+		{Methods: []string{"/proto.BookService/*"}, Requests: 1, Per: time.Second},
+	}})
+
+	allowed, _ := l.Allow("/proto.UserService/SignIn", "ip:10.0.0.1")
+	assert.True(t, allowed)
+	allowed, _ = l.Allow("/proto.UserService/SignIn", "ip:10.0.0.1")
+	assert.True(t, allowed)
+	allowed, wait := l.Allow("/proto.UserService/SignIn", "ip:10.0.0.1")
+	assert.False(t, allowed)
+	assert.Equal(t, 10*time.Second, wait)
+
+	// Other callers and other methods have their own buckets.
+	allowed, _ = l.Allow("/proto.UserService/SignIn", "ip:10.0.0.2")
+	assert.True(t, allowed)
+	allowed, _ = l.Allow("/proto.UserService/SignUp", "ip:10.0.0.1")
+	assert.True(t, allowed, "methods without a rule are not limited")
+
+	clock.advance(4 * time.Second)
+	_, wait = l.Allow("/proto.UserService/SignIn", "ip:10.0.0.1")
+	assert.Equal(t, 6*time.Second, wait)
+	clock.advance(6 * time.Second)
+	allowed, _ = l.Allow("/proto.UserService/SignIn", "ip:10.0.0.1")
+	assert.True(t, allowed)
+
+	// A wildcard rule gives every method of the service its own bucket.
+	allowed, _ = l.Allow("/proto.BookService/CreateBook", "user:1")
+	assert.True(t, allowed)
+	allowed, _ = l.Allow("/proto.BookService/CreateBook", "user:1")
+	assert.False(t, allowed)
+	allowed, _ = l.Allow("/proto.BookService/GetBook", "user:1")
+	assert.True(t, allowed)
+}
+
+func TestLimiter_SweepsFullBuckets(t *testing.T) {
+	l, clock := newLimiter(t, Config{Rules: []Rule{
+		{Methods: []string{"/proto.UserService/SignIn"}, Requests: 1, Per: time.Hour},
+	}})
+
+	l.Allow("/proto.UserService/SignIn", "ip:10.0.0.1")
+	clock.advance(2 * time.Minute)
+	l.Allow("/proto.UserService/SignIn", "ip:10.0.0.2")
+	assert.Len(t, l.buckets, 2, "partially refilled buckets are kept")
+
+	clock.advance(2 * time.Hour)
+	l.Allow("/proto.UserService/GetJWKS", "ip:10.0.0.3")
+	l.Allow("/proto.UserService/SignIn", "ip:10.0.0.3")
+	assert.Len(t, l.buckets, 1)
+}
+
+func TestNew_InvalidConfig(t *testing.T) {
+	for name, cfg := range map[string]Config{
+		"no methods":    {Rules: []Rule{{Requests: 1, Per: time.Second}}},
+		"no rate":       {Rules: []Rule{{Methods: []string{"/a.B/C"}, Per: time.Second}}},
+		"no period":     {Rules: []Rule{{Methods: []string{"/a.B/C"}, Requests: 1}}},
+		"relative name": {Rules: []Rule{{Methods: []string{"a.B/C"}, Requests: 1, Per: time.Second}}},
+		"duplicate": {Rules: []Rule{
+			{Methods: []string{"/a.B/C"}, Requests: 1, Per: time.Second},
+			{Methods: []string{"/a.B/C"}, Requests: 2, Per: time.Second},
+		}},
+		"invalid address rule": {AddressRules: []Rule{{Methods: []string{"/a.B/C"}, Per: time.Second}}},
+	} {
+		_, err := New(cfg)
+		assert.Error(t, err, name)
+	}
+}
+
+type trailerStream struct {
+	trailer metadata.MD
+}
+
+func (s *trailerStream) Method() string                  { return "" }
+func (s *trailerStream) SetHeader(metadata.MD) error     { return nil }
+func (s *trailerStream) SendHeader(metadata.MD) error    { return nil }
+func (s *trailerStream) SetTrailer(md metadata.MD) error { s.trailer = md; return nil }
+
+func callFrom(addr string) (context.Context, *trailerStream) {
+	stream := &trailerStream{}
+	ctx := peer.NewContext(context.Background(), &peer.Peer{Addr: &net.TCPAddr{IP: net.ParseIP(addr), Port: 40000}})
+	return grpc.NewContextWithServerTransportStream(ctx, stream), stream
+}
+
+func TestUnaryServerInterceptor(t *testing.T) {
+	l, _ := newLimiter(t, Config{
+		Rules: []Rule{{Methods: []string{"/proto.UserService/*"}, Requests: 1, Per: 30 * time.Second}},
+	})
+	interceptor := l.UnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.UserService/SignIn"}
+	ok := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	ctx, _ := callFrom("192.0.2.1")
+	_, err := interceptor(ctx, nil, info, ok)
+	require.NoError(t, err)
+
+	ctx, stream := callFrom("192.0.2.1")
+	_, err = interceptor(ctx, nil, info, ok)
+	st := status.Convert(err)
+	require.Equal(t, codes.ResourceExhausted, st.Code())
+	assert.Equal(t, []string{"30"}, stream.trailer.Get(handler.RetryAfterTrailer))
+	require.Len(t, st.Details(), 1)
+	assert.Equal(t, 30*time.Second, st.Details()[0].(*errdetails.RetryInfo).RetryDelay.AsDuration())
+
+	// The address resolved by the clientip interceptors wins over the peer.
+	ctx, _ = callFrom("127.0.0.1")
+	_, err = interceptor(clientip.NewContext(ctx, "198.51.100.8"), nil, info, ok)
+	assert.NoError(t, err)
+	ctx, _ = callFrom("127.0.0.1")
+	_, err = interceptor(clientip.NewContext(ctx, "198.51.100.8"), nil, info, ok)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+
+	// Authenticated callers are limited by user id wherever they connect from.
+	ctx, _ = callFrom("192.0.2.1")
+	ctx = handler.ContextWithPrincipal(ctx, models.Principal{UserID: 9, Role: models.RoleUser})
+	_, err = interceptor(ctx, nil, info, ok)
+	assert.NoError(t, err)
+	ctx, _ = callFrom("192.0.2.2")
+	ctx = handler.ContextWithPrincipal(ctx, models.Principal{UserID: 9, Role: models.RoleUser})
+	_, err = interceptor(ctx, nil, info, ok)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+}
+
+func TestAddressUnaryServerInterceptor(t *testing.T) {
+	l, _ := newLimiter(t, Config{
+		AddressRules: []Rule{{Methods: []string{"/proto.BookService/*"}, Requests: 1, Per: 30 * time.Second}},
+	})
+	interceptor := l.AddressUnaryServerInterceptor()
+	info := &grpc.UnaryServerInfo{FullMethod: "/proto.BookService/GetBook"}
+	ok := func(context.Context, interface{}) (interface{}, error) { return "ok", nil }
+
+	ctx, _ := callFrom("192.0.2.1")
+	_, err := interceptor(ctx, nil, info, ok)
+	require.NoError(t, err)
+
+	// Address rules apply whoever the caller claims to be.
+	ctx, stream := callFrom("192.0.2.1")
+	ctx = handler.ContextWithPrincipal(ctx, models.Principal{UserID: 9, Role: models.RoleUser})
+	_, err = interceptor(ctx, nil, info, ok)
+	assert.Equal(t, codes.ResourceExhausted, status.Code(err))
+	assert.Equal(t, []string{"30"}, stream.trailer.Get(handler.RetryAfterTrailer))
+
+	ctx, _ = callFrom("192.0.2.2")
+	_, err = interceptor(ctx, nil, info, ok)
+	assert.NoError(t, err)
+
+	// They do not spend the buckets of the rules checked after
+	// authentication.
+	allowed, _ := l.Allow(info.FullMethod, "ip:192.0.2.1")
+	assert.True(t, allowed)
+}
@@ -0,0 +1,75 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"grpc/server/models"
+	"sync"
+)
+
+// AuthMemory keeps users in process memory. Usernames are unique, as in
+// AuthPostgres.
+type AuthMemory struct {
+	mu     sync.RWMutex
+	users  map[uint]models.User
+	nextID uint
+}
+
+func NewAuthMemory() *AuthMemory {
+	return &AuthMemory{users: make(map[uint]models.User)}
+}
+
+func (r *AuthMemory) CreateUser(ctx context.Context, user models.User) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.users {
+		if existing.Username == user.Username {
+			return 0, fmt.Errorf("user with this username %w", ErrAlreadyExists)
+		}
+	}
+
+	r.nextID++
+	user.ID = r.nextID
+	if user.Role == "" {
+		user.Role = models.RoleUser
+	}
+	r.users[user.ID] = user
+	return user.ID, nil
+}
+
+func (r *AuthMemory) GetUser(ctx context.Context, username string) (models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	for _, user := range r.users {
+		if user.Username == username {
+			return user, nil
+		}
+	}
+	return models.User{}, fmt.Errorf("user %w", ErrNotFound)
+}
+
+func (r *AuthMemory) GetUserById(ctx context.Context, id uint) (models.User, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return models.User{}, fmt.Errorf("user %w", ErrNotFound)
+	}
+	return user, nil
+}
+
+func (r *AuthMemory) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	user, ok := r.users[id]
+	if !ok {
+		return fmt.Errorf("user %w", ErrNotFound)
+	}
+	user.Password = hash
+	r.users[id] = user
+	return nil
+}
@@ -1,6 +1,8 @@
 package repository
 
 import (
+	"context"
+	"fmt"
 	"grpc/server/models"
 
 	"gorm.io/gorm"
@@ -14,17 +16,36 @@ func NewAuthPostgres(db *gorm.DB) *AuthPostgres {
 	return &AuthPostgres{db: db}
 }
 
-func (r *AuthPostgres) CreateUser(user models.User) (uint, error) {
-	if err := r.db.Create(&user).Error; err != nil {
-		return 0, err
+func (r *AuthPostgres) CreateUser(ctx context.Context, user models.User) (uint, error) {
+	if err := r.db.WithContext(ctx).Create(&user).Error; err != nil {
+		return 0, translateError(err, "create user", "user with this username")
 	}
 	return user.ID, nil
 }
 
-func (r *AuthPostgres) GetUser(username string) (models.User, error) {
+func (r *AuthPostgres) GetUser(ctx context.Context, username string) (models.User, error) {
 	var user models.User
-	if err := r.db.Where("username = ?", username).First(&user).Error; err != nil {
-		return models.User{}, err
+	if err := r.db.WithContext(ctx).Where("username = ?", username).First(&user).Error; err != nil {
+		return models.User{}, translateError(err, "find user", "user")
 	}
 	return user, nil
 }
+
+func (r *AuthPostgres) GetUserById(ctx context.Context, id uint) (models.User, error) {
+	var user models.User
+	if err := r.db.WithContext(ctx).First(&user, id).Error; err != nil {
+		return models.User{}, translateError(err, "find user", "user")
+	}
+	return user, nil
+}
+
+func (r *AuthPostgres) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	res := r.db.WithContext(ctx).Model(&models.User{}).Where("id = ?", id).Update("password", hash)
+	if res.Error != nil {
+		return fmt.Errorf("failed to update password: %w", res.Error)
+	}
+	if res.RowsAffected == 0 {
+		return fmt.Errorf("user %w", ErrNotFound)
+	}
+	return nil
+}
@@ -0,0 +1,213 @@
+package repository
+
+import (
+	"cmp"
+	"context"
+	"fmt"
+	"grpc/server/models"
+	"slices"
+	"strings"
+	"sync"
+)
+
+// BookMemory keeps books in process memory with the same semantics as
+// BookPostgres: unique titles, owner checks and keyset pagination.
+type BookMemory struct {
+	mu    sync.RWMutex
+	books map[uint]models.Book
+	// titles indexes books by title to enforce their uniqueness.
+	titles map[string]uint
+	nextID uint
+}
+
+func NewBookMemory() *BookMemory {
+	return &BookMemory{books: make(map[uint]models.Book), titles: make(map[string]uint)}
+}
+
+func (r *BookMemory) Create(ctx context.Context, book models.Book) (uint, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if r.titleTaken(book.Title, 0) {
+		return 0, ErrDuplicateTitle
+	}
+	return r.insert(book), nil
+}
+
+func (r *BookMemory) CreateBatch(ctx context.Context, books []models.Book) ([]models.BatchResult, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	results := make([]models.BatchResult, len(books))
+	for i, book := range books {
+		if r.titleTaken(book.Title, 0) {
+			results[i].Err = ErrDuplicateTitle
+			continue
+		}
+		results[i].ID = r.insert(book)
+	}
+	return results, nil
+}
+
+func (r *BookMemory) List(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error) {
+	column, ok := bookOrderColumns[query.OrderBy]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported book order %q", query.OrderBy)
+	}
+
+	r.mu.RLock()
+	books := r.filter(query.BookFilter)
+	r.mu.RUnlock()
+
+	var total int64
+	if query.Count {
+		total = int64(len(books))
+	}
+	compare := func(a, b models.Book) int {
+		if c := compareBookColumn(column, a, b); c != 0 {
+			return c
+		}
+		return cmp.Compare(a.ID, b.ID)
+	}
+	if query.Desc {
+		asc := compare
+		compare = func(a, b models.Book) int { return asc(b, a) }
+	}
+	slices.SortFunc(books, compare)
+
+	if query.After != nil {
+		after := models.Book{
+			ID:        query.After.ID,
+			Title:     query.After.Title,
+			Author:    query.After.Author,
+			CreatedAt: query.After.CreatedAt,
+		}
+		i, _ := slices.BinarySearchFunc(books, after, compare)
+		if i < len(books) && compare(books[i], after) == 0 {
+			i++
+		}
+		books = books[i:]
+	}
+	if query.Limit > 0 && len(books) > query.Limit {
+		books = books[:query.Limit]
+	}
+	return books, total, nil
+}
+
+func (r *BookMemory) GetBatch(ctx context.Context, filter models.BookFilter, afterId uint, limit int) ([]models.Book, error) {
+	r.mu.RLock()
+	books := r.filter(filter)
+	r.mu.RUnlock()
+
+	books = slices.DeleteFunc(books, func(b models.Book) bool { return b.ID <= afterId })
+	slices.SortFunc(books, func(a, b models.Book) int { return cmp.Compare(a.ID, b.ID) })
+	if len(books) > limit {
+		books = books[:limit]
+	}
+	return books, nil
+}
+
+func (r *BookMemory) GetById(ctx context.Context, bookId uint) (models.Book, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	book, ok := r.books[bookId]
+	if !ok {
+		return models.Book{}, fmt.Errorf("book with id %d %w", bookId, ErrNotFound)
+	}
+	return book, nil
+}
+
+func (r *BookMemory) Delete(ctx context.Context, principal models.Principal, bookId uint) (models.Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	book, ok := r.books[bookId]
+	if !ok {
+		return models.Book{}, fmt.Errorf("book with id %d %w", bookId, ErrNotFound)
+	}
+	if !principal.CanModify(book) {
+		return models.Book{}, fmt.Errorf("deleting book %d: %w", bookId, ErrPermissionDenied)
+	}
+
+	delete(r.books, bookId)
+	delete(r.titles, book.Title)
+	return book, nil
+}
+
+func (r *BookMemory) Update(ctx context.Context, principal models.Principal, bookId uint, input models.UpdateBook) (models.Book, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	book, ok := r.books[bookId]
+	if !ok {
+		return models.Book{}, fmt.Errorf("book with id %d %w", bookId, ErrNotFound)
+	}
+	if !principal.CanModify(book) {
+		return models.Book{}, fmt.Errorf("updating book %d: %w", bookId, ErrPermissionDenied)
+	}
+
+	if input.Title != nil {
+		if r.titleTaken(*input.Title, bookId) {
+			return models.Book{}, ErrDuplicateTitle
+		}
+		delete(r.titles, book.Title)
+		book.Title = *input.Title
+		r.titles[book.Title] = bookId
+	}
+	if input.Author != nil {
+		book.Author = *input.Author
+	}
+
+	r.books[bookId] = book
+	return book, nil
+}
+
+// insert stores book under the next id. The caller must hold the write lock.
+func (r *BookMemory) insert(book models.Book) uint {
+	r.nextID++
+	book.ID = r.nextID
+	stampCreatedAt(&book)
+	r.books[book.ID] = book
+	r.titles[book.Title] = book.ID
+	return book.ID
+}
+
+// titleTaken reports whether a book other than exceptId has title. The caller
+// must hold the lock.
+func (r *BookMemory) titleTaken(title string, exceptId uint) bool {
+	id, ok := r.titles[title]
+	return ok && id != exceptId
+}
+
+// filter returns a copy of the books matching filter. The caller must hold
+// the lock.
+func (r *BookMemory) filter(filter models.BookFilter) []models.Book {
+	var books []models.Book
+	for _, book := range r.books {
+		if filter.Author != "" && book.Author != filter.Author {
+			continue
+		}
+		if filter.UserId != 0 && book.UserId != filter.UserId {
+			continue
+		}
+		if filter.TitlePrefix != "" && !strings.HasPrefix(book.Title, filter.TitlePrefix) {
+			continue
+		}
+		books = append(books, book)
+	}
+	return books
+}
+
+func compareBookColumn(column string, a, b models.Book) int {
+	switch column {
+	case "title":
+		return strings.Compare(a.Title, b.Title)
+	case "author":
+		return strings.Compare(a.Author, b.Author)
+	case "created_at":
+		return a.CreatedAt.Compare(b.CreatedAt)
+	default:
+		return 0
+	}
+}
@@ -1,11 +1,13 @@
 package repository
 
 import (
-	"errors"
+	"context"
 	"fmt"
 	"grpc/server/models"
+	"time"
 
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type BookPostgres struct {
@@ -16,65 +18,160 @@ func NewBookPostgres(db *gorm.DB) *BookPostgres {
 	return &BookPostgres{db: db}
 }
 
-func (r *BookPostgres) Create(book models.Book) (uint, error) {
-	if err := r.db.Create(&book).Error; err != nil {
-		return 0, fmt.Errorf("failed to create book: %w", err)
+func (r *BookPostgres) Create(ctx context.Context, book models.Book) (uint, error) {
+	stampCreatedAt(&book)
+	if err := r.db.WithContext(ctx).Create(&book).Error; err != nil {
+		return 0, translateError(err, "create book", "book with this title")
 	}
 	return book.ID, nil
 }
 
-func (r *BookPostgres) GetAll() ([]models.Book, error) {
+// CreateBatch inserts books in a single transaction. Rows whose title is
+// already taken are reported in their BatchResult instead of aborting the
+// batch; any other failure rolls the whole batch back.
+func (r *BookPostgres) CreateBatch(ctx context.Context, books []models.Book) ([]models.BatchResult, error) {
+	results := make([]models.BatchResult, len(books))
+
+	err := r.db.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		for i := range books {
+			book := books[i]
+			stampCreatedAt(&book)
+			res := tx.Clauses(clause.OnConflict{
+				Columns:   []clause.Column{{Name: "title"}},
+				DoNothing: true,
+			}).Create(&book)
+			if res.Error != nil {
+				return res.Error
+			}
+			if res.RowsAffected == 0 {
+				results[i].Err = ErrDuplicateTitle
+				continue
+			}
+			results[i].ID = book.ID
+		}
+		return nil
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to import books: %w", err)
+	}
+	return results, nil
+}
+
+func (r *BookPostgres) List(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error) {
+	column, ok := bookOrderColumns[query.OrderBy]
+	if !ok {
+		return nil, 0, fmt.Errorf("unsupported book order %q", query.OrderBy)
+	}
+
+	db := r.db.WithContext(ctx)
+	var total int64
+	if query.Count {
+		var err error
+		if total, err = r.count(db, query.BookFilter); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	direction, cmp := "ASC", ">"
+	if query.Desc {
+		direction, cmp = "DESC", "<"
+	}
+
+	tx := filterBooks(db, query.BookFilter)
+	if query.After != nil {
+		if column == "id" {
+			tx = tx.Where("id "+cmp+" ?", query.After.ID)
+		} else {
+			value := cursorValue(column, query.After)
+			tx = tx.Where(
+				fmt.Sprintf("%[1]s %[2]s ? OR (%[1]s = ? AND id %[2]s ?)", column, cmp),
+				value, value, query.After.ID,
+			)
+		}
+	}
+	if column != "id" {
+		tx = tx.Order(column + " " + direction)
+	}
+	tx = tx.Order("id " + direction)
+
 	var books []models.Book
-	if err := r.db.Find(&books).Error; err != nil {
-		return nil, fmt.Errorf("failed to get all books: %w", err)
+	if err := tx.Limit(query.Limit).Find(&books).Error; err != nil {
+		return nil, 0, fmt.Errorf("failed to list books: %w", err)
+	}
+	return books, total, nil
+}
+
+// count returns the number of books matching filter. On Postgres an
+// unfiltered count of a large table is taken from the planner's statistics
+// instead of scanning it.
+func (r *BookPostgres) count(db *gorm.DB, filter models.BookFilter) (int64, error) {
+	if filter == (models.BookFilter{}) && db.Dialector.Name() == "postgres" {
+		var estimate float64
+		err := db.Raw("SELECT reltuples FROM pg_class WHERE oid = 'books'::regclass").Scan(&estimate).Error
+		if err != nil {
+			return 0, fmt.Errorf("failed to estimate books: %w", err)
+		}
+		if estimate >= minEstimatedBooks {
+			return int64(estimate), nil
+		}
+	}
+
+	var total int64
+	if err := filterBooks(db.Model(&models.Book{}), filter).Count(&total).Error; err != nil {
+		return 0, fmt.Errorf("failed to count books: %w", err)
+	}
+	return total, nil
+}
+
+func (r *BookPostgres) GetBatch(ctx context.Context, filter models.BookFilter, afterId uint, limit int) ([]models.Book, error) {
+	var books []models.Book
+	err := filterBooks(r.db.WithContext(ctx), filter).
+		Where("id > ?", afterId).
+		Order("id ASC").
+		Limit(limit).
+		Find(&books).Error
+	if err != nil {
+		return nil, fmt.Errorf("failed to read books after id %d: %w", afterId, err)
 	}
 	return books, nil
 }
 
-func (r *BookPostgres) GetById(bookId uint) (models.Book, error) {
+func (r *BookPostgres) GetById(ctx context.Context, bookId uint) (models.Book, error) {
 	var book models.Book
-	err := r.db.First(&book, bookId).Error
-	if err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return models.Book{}, fmt.Errorf("book with id %d not found", bookId)
-		}
-		return models.Book{}, fmt.Errorf("failed to find book with id %d: %w", bookId, err)
+	if err := r.db.WithContext(ctx).First(&book, bookId).Error; err != nil {
+		return models.Book{}, translateError(err, fmt.Sprintf("find book with id %d", bookId), fmt.Sprintf("book with id %d", bookId))
 	}
 	return book, nil
 }
 
-func (r *BookPostgres) Delete(userId, bookId uint) error {
+func (r *BookPostgres) Delete(ctx context.Context, principal models.Principal, bookId uint) (models.Book, error) {
+	db := r.db.WithContext(ctx)
 	var book models.Book
 
-	if err := r.db.First(&book, bookId).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("book not found")
-		}
-		return err
+	if err := db.First(&book, bookId).Error; err != nil {
+		return models.Book{}, translateError(err, fmt.Sprintf("find book with id %d", bookId), fmt.Sprintf("book with id %d", bookId))
 	}
 
-	if userId != book.UserId {
-		return fmt.Errorf("user does not have permission to delete this book")
+	if !principal.CanModify(book) {
+		return models.Book{}, fmt.Errorf("deleting book %d: %w", bookId, ErrPermissionDenied)
 	}
 
-	if err := r.db.Delete(&book).Error; err != nil {
-		return fmt.Errorf("failed to delete book: %w", err)
+	if err := db.Delete(&book).Error; err != nil {
+		return models.Book{}, fmt.Errorf("failed to delete book: %w", err)
 	}
 
-	return nil
+	return book, nil
 }
 
-func (r *BookPostgres) Update(userId, bookId uint, input models.UpdateBook) error {
+func (r *BookPostgres) Update(ctx context.Context, principal models.Principal, bookId uint, input models.UpdateBook) (models.Book, error) {
+	db := r.db.WithContext(ctx)
 	var book models.Book
-	if err := r.db.First(&book, bookId).Error; err != nil {
-		if errors.Is(err, gorm.ErrRecordNotFound) {
-			return fmt.Errorf("book not found")
-		}
-		return err
+	if err := db.First(&book, bookId).Error; err != nil {
+		return models.Book{}, translateError(err, fmt.Sprintf("find book with id %d", bookId), fmt.Sprintf("book with id %d", bookId))
 	}
 
-	if userId != book.UserId {
-		return fmt.Errorf("user does not have permission to update this book")
+	if !principal.CanModify(book) {
+		return models.Book{}, fmt.Errorf("updating book %d: %w", bookId, ErrPermissionDenied)
 	}
 
 	if input.Title != nil {
@@ -85,9 +182,18 @@ func (r *BookPostgres) Update(userId, bookId uint, input models.UpdateBook) erro
 		book.Author = *input.Author
 	}
 
-	if err := r.db.Save(&book).Error; err != nil {
-		return fmt.Errorf("failed to save book: %w", err)
+	if err := db.Save(&book).Error; err != nil {
+		return models.Book{}, translateError(err, "save book", "book with this title")
 	}
 
-	return nil
+	return book, nil
+}
+
+// stampCreatedAt sets the creation time in the application rather than
+// through the column default, which SQLite stores with second precision in a
+// format that does not compare correctly with keyset cursors.
+func stampCreatedAt(book *models.Book) {
+	if book.CreatedAt.IsZero() {
+		book.CreatedAt = time.Now()
+	}
 }
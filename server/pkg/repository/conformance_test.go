@@ -0,0 +1,458 @@
+package repository
+
+import (
+	"context"
+	"grpc/server/models"
+	"grpc/server/pkg/migrate"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"gorm.io/driver/postgres"
+	"gorm.io/gorm"
+)
+
+// The conformance suite runs the same tests against every backend so that
+// they keep identical semantics. Postgres runs only when POSTGRES_TEST_DSN
+// points at a disposable database.
+
+func TestConformance_Memory(t *testing.T) {
+	runConformance(t, func(t *testing.T) *Repository {
+		return NewMemoryRepository()
+	})
+}
+
+func TestConformance_SQLite(t *testing.T) {
+	runConformance(t, func(t *testing.T) *Repository {
+		return NewRepository(migrated(t, NewSQLiteDB(filepath.Join(t.TempDir(), "test.db"))))
+	})
+}
+
+func TestConformance_Postgres(t *testing.T) {
+	dsn := os.Getenv("POSTGRES_TEST_DSN")
+	if dsn == "" {
+		t.Skip("POSTGRES_TEST_DSN is not set")
+	}
+	runConformance(t, func(t *testing.T) *Repository {
+		db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
+		require.NoError(t, err)
+		require.NoError(t, db.Migrator().DropTable("schema_migrations", &models.User{}, &models.Book{}, &models.RefreshToken{}, &models.RevokedToken{},
+			&models.LoginAttempt{}, &models.LockoutEvent{}))
+		return NewRepository(migrated(t, db))
+	})
+}
+
+func migrated(t *testing.T, db *gorm.DB) *gorm.DB {
+	migrator, err := migrate.New(db)
+	require.NoError(t, err)
+	_, err = migrator.Up(context.Background())
+	require.NoError(t, err)
+	return db
+}
+
+func runConformance(t *testing.T, newRepo func(t *testing.T) *Repository) {
+	tests := map[string]func(t *testing.T, repo *Repository){
+		"Users":         testUsers,
+		"BookLifecycle": testBookLifecycle,
+		"CreateBatch":   testCreateBatch,
+		"List":          testList,
+		"ListFilters":   testListFilters,
+		"GetBatch":      testGetBatch,
+		"RefreshTokens": testRefreshTokens,
+		"Revocation":    testRevocation,
+		"LoginAttempts": testLoginAttempts,
+		"Ping":          testPing,
+	}
+	for name, test := range tests {
+		t.Run(name, func(t *testing.T) {
+			test(t, newRepo(t))
+		})
+	}
+}
+
+func testPing(t *testing.T, repo *Repository) {
+	assert.NoError(t, repo.Ping(context.Background()))
+}
+
+func testUsers(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+
+	id, err := repo.CreateUser(ctx, models.User{Name: "Ann", Username: "ann", Password: "hash", Role: models.RoleUser})
+	require.NoError(t, err)
+
+	_, err = repo.CreateUser(ctx, models.User{Name: "Other Ann", Username: "ann", Password: "hash", Role: models.RoleUser})
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	user, err := repo.GetUser(ctx, "ann")
+	require.NoError(t, err)
+	assert.Equal(t, id, user.ID)
+	assert.Equal(t, models.RoleUser, user.Role)
+
+	user, err = repo.GetUserById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "ann", user.Username)
+
+	_, err = repo.GetUser(ctx, "bob")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = repo.GetUserById(ctx, id+100)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	require.NoError(t, repo.UpdatePassword(ctx, id, "new hash"))
+	user, err = repo.GetUser(ctx, "ann")
+	require.NoError(t, err)
+	assert.Equal(t, "new hash", user.Password)
+	assert.ErrorIs(t, repo.UpdatePassword(ctx, id+100, "hash"), ErrNotFound)
+}
+
+func testBookLifecycle(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+	owner := models.Principal{UserID: 1}
+	stranger := models.Principal{UserID: 2}
+
+	id, err := repo.Create(ctx, models.Book{Title: "Dune", Author: "Herbert", UserId: 1})
+	require.NoError(t, err)
+	otherId, err := repo.Create(ctx, models.Book{Title: "Emma", Author: "Austen", UserId: 1})
+	require.NoError(t, err)
+
+	_, err = repo.Create(ctx, models.Book{Title: "Dune", Author: "Someone", UserId: 2})
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	book, err := repo.GetById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Herbert", book.Author)
+	assert.False(t, book.CreatedAt.IsZero())
+
+	_, err = repo.GetById(ctx, id+100)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	title := "Dune Messiah"
+	_, err = repo.Update(ctx, stranger, id, models.UpdateBook{Title: &title})
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	_, err = repo.Update(ctx, owner, id+100, models.UpdateBook{Title: &title})
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	taken := "Emma"
+	_, err = repo.Update(ctx, owner, id, models.UpdateBook{Title: &taken})
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+
+	book, err = repo.Update(ctx, models.Principal{UserID: 2, AnyOwner: true}, id, models.UpdateBook{Title: &title})
+	require.NoError(t, err)
+	assert.Equal(t, "Dune Messiah", book.Title)
+	assert.Equal(t, "Herbert", book.Author)
+
+	book, err = repo.GetById(ctx, id)
+	require.NoError(t, err)
+	assert.Equal(t, "Dune Messiah", book.Title)
+
+	// Renaming a book frees its old title.
+	_, err = repo.Create(ctx, models.Book{Title: "Dune", Author: "Someone", UserId: 2})
+	assert.NoError(t, err)
+
+	_, err = repo.Delete(ctx, stranger, id)
+	assert.ErrorIs(t, err, ErrPermissionDenied)
+
+	deleted, err := repo.Delete(ctx, owner, id)
+	require.NoError(t, err)
+	assert.Equal(t, id, deleted.ID)
+	assert.Equal(t, "Dune Messiah", deleted.Title)
+
+	_, err = repo.Delete(ctx, owner, id)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	_, err = repo.Delete(ctx, models.Principal{UserID: 2, AnyOwner: true}, otherId)
+	assert.NoError(t, err)
+
+	// Deleting a book frees its title.
+	_, err = repo.Create(ctx, models.Book{Title: "Emma", Author: "Austen", UserId: 1})
+	assert.NoError(t, err)
+}
+
+func testCreateBatch(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+
+	_, err := repo.Create(ctx, models.Book{Title: "Existing", Author: "A"})
+	require.NoError(t, err)
+
+	results, err := repo.CreateBatch(ctx, []models.Book{
+		{Title: "One", Author: "A"},
+		{Title: "One", Author: "B"},
+		{Title: "Existing", Author: "C"},
+		{Title: "Two", Author: "A"},
+	})
+	require.NoError(t, err)
+	require.Len(t, results, 4)
+	assert.NotZero(t, results[0].ID)
+	assert.ErrorIs(t, results[1].Err, ErrDuplicateTitle)
+	assert.ErrorIs(t, results[2].Err, ErrDuplicateTitle)
+	assert.NotZero(t, results[3].ID)
+
+	book, err := repo.GetById(ctx, results[0].ID)
+	require.NoError(t, err)
+	assert.Equal(t, "A", book.Author)
+}
+
+func testList(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+	seed := []models.Book{
+		{Title: "Go", Author: "Pike"},
+		{Title: "Rust", Author: "Klabnik"},
+		{Title: "Gleam", Author: "Pike"},
+		{Title: "C", Author: "Kernighan"},
+		{Title: "Zig", Author: "Kelley"},
+	}
+	for _, book := range seed {
+		_, err := repo.Create(ctx, book)
+		require.NoError(t, err)
+		// Distinct creation times keep the created order deterministic.
+		time.Sleep(2 * time.Millisecond)
+	}
+
+	cases := []struct {
+		orderBy string
+		desc    bool
+		want    []string
+	}{
+		{"id", false, []string{"Go", "Rust", "Gleam", "C", "Zig"}},
+		{"id", true, []string{"Zig", "C", "Gleam", "Rust", "Go"}},
+		{"title", false, []string{"C", "Gleam", "Go", "Rust", "Zig"}},
+		{"title", true, []string{"Zig", "Rust", "Go", "Gleam", "C"}},
+		{"author", false, []string{"Zig", "C", "Rust", "Go", "Gleam"}},
+		{"author", true, []string{"Gleam", "Go", "Rust", "C", "Zig"}},
+		{"created", false, []string{"Go", "Rust", "Gleam", "C", "Zig"}},
+		{"created", true, []string{"Zig", "C", "Gleam", "Rust", "Go"}},
+	}
+	for _, c := range cases {
+		var got []string
+		query := models.BookQuery{OrderBy: c.orderBy, Desc: c.desc, Limit: 2, Count: true}
+		for page := 0; page <= len(seed); page++ {
+			books, total, err := repo.List(ctx, query)
+			require.NoError(t, err)
+			if query.Count {
+				assert.Equal(t, int64(len(seed)), total)
+			} else {
+				assert.Zero(t, total)
+			}
+			got = append(got, titles(books)...)
+			if len(books) < query.Limit {
+				break
+			}
+			last := books[len(books)-1]
+			query.After = &models.BookCursor{ID: last.ID, Title: last.Title, Author: last.Author, CreatedAt: last.CreatedAt}
+			query.Count = false
+		}
+		assert.Equal(t, c.want, got, "order by %s desc=%v", c.orderBy, c.desc)
+	}
+
+	_, _, err := repo.List(ctx, models.BookQuery{OrderBy: "isbn", Limit: 2})
+	assert.Error(t, err)
+}
+
+func testListFilters(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+	seed := []models.Book{
+		{Title: "Go Programming", Author: "Pike", UserId: 1},
+		{Title: "go tooling", Author: "Pike", UserId: 2},
+		{Title: "Go_Fast", Author: "Cheney", UserId: 1},
+		{Title: "Gopher", Author: "Cheney", UserId: 2},
+		{Title: "100% Go", Author: "Pike", UserId: 1},
+	}
+	for _, book := range seed {
+		_, err := repo.Create(ctx, book)
+		require.NoError(t, err)
+	}
+
+	cases := []struct {
+		filter models.BookFilter
+		want   []string
+	}{
+		{models.BookFilter{Author: "Pike"}, []string{"Go Programming", "go tooling", "100% Go"}},
+		{models.BookFilter{UserId: 2}, []string{"go tooling", "Gopher"}},
+		{models.BookFilter{TitlePrefix: "Go"}, []string{"Go Programming", "Go_Fast", "Gopher"}},
+		{models.BookFilter{TitlePrefix: "Go_"}, []string{"Go_Fast"}},
+		{models.BookFilter{TitlePrefix: "100%"}, []string{"100% Go"}},
+		{models.BookFilter{TitlePrefix: "Go", Author: "Cheney", UserId: 1}, []string{"Go_Fast"}},
+	}
+	for _, c := range cases {
+		books, total, err := repo.List(ctx, models.BookQuery{BookFilter: c.filter, OrderBy: "id", Limit: 10, Count: true})
+		require.NoError(t, err)
+		assert.Equal(t, c.want, titles(books), "filter %+v", c.filter)
+		assert.Equal(t, int64(len(c.want)), total, "filter %+v", c.filter)
+	}
+}
+
+func testGetBatch(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+	var ids []uint
+	for _, title := range []string{"A1", "B1", "A2", "A3"} {
+		id, err := repo.Create(ctx, models.Book{Title: title, Author: "Author", UserId: uint(len(title))})
+		require.NoError(t, err)
+		ids = append(ids, id)
+	}
+
+	books, err := repo.GetBatch(ctx, models.BookFilter{TitlePrefix: "A"}, 0, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A1", "A2"}, titles(books))
+
+	books, err = repo.GetBatch(ctx, models.BookFilter{TitlePrefix: "A"}, books[1].ID, 2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"A3"}, titles(books))
+
+	books, err = repo.GetBatch(ctx, models.BookFilter{}, ids[3], 2)
+	require.NoError(t, err)
+	assert.Empty(t, books)
+}
+
+func testRefreshTokens(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+	expires := time.Now().Add(time.Hour)
+
+	require.NoError(t, repo.CreateRefreshToken(ctx, models.RefreshToken{UserID: 1, FamilyID: "f", TokenHash: "a", ExpiresAt: expires}))
+	require.NoError(t, repo.CreateRefreshToken(ctx, models.RefreshToken{UserID: 1, FamilyID: "f", TokenHash: "b", ExpiresAt: expires}))
+	require.NoError(t, repo.CreateRefreshToken(ctx, models.RefreshToken{UserID: 1, FamilyID: "g", TokenHash: "c", ExpiresAt: expires}))
+
+	_, err := repo.GetRefreshToken(ctx, "missing")
+	assert.ErrorIs(t, err, ErrRefreshTokenNotFound)
+
+	token, err := repo.GetRefreshToken(ctx, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "f", token.FamilyID)
+	assert.Nil(t, token.UsedAt)
+
+	fresh, err := repo.MarkRefreshTokenUsed(ctx, token.ID)
+	require.NoError(t, err)
+	assert.True(t, fresh)
+
+	fresh, err = repo.MarkRefreshTokenUsed(ctx, token.ID)
+	require.NoError(t, err)
+	assert.False(t, fresh)
+
+	require.NoError(t, repo.RevokeRefreshTokenFamily(ctx, "f"))
+
+	token, err = repo.GetRefreshToken(ctx, "b")
+	require.NoError(t, err)
+	assert.NotNil(t, token.RevokedAt)
+
+	fresh, err = repo.MarkRefreshTokenUsed(ctx, token.ID)
+	require.NoError(t, err)
+	assert.False(t, fresh)
+
+	token, err = repo.GetRefreshToken(ctx, "c")
+	require.NoError(t, err)
+	assert.Nil(t, token.RevokedAt)
+}
+
+func testRevocation(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+	now := time.Now()
+
+	require.NoError(t, repo.Revoke(ctx, "expired", now.Add(-time.Minute)))
+	require.NoError(t, repo.Revoke(ctx, "active", now.Add(time.Hour)))
+	require.NoError(t, repo.Revoke(ctx, "active", now.Add(time.Hour)))
+
+	revoked, err := repo.IsRevoked(ctx, "active")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+
+	revoked, err = repo.IsRevoked(ctx, "unknown")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	pruned, err := repo.PruneExpired(ctx, now)
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+
+	revoked, err = repo.IsRevoked(ctx, "expired")
+	require.NoError(t, err)
+	assert.False(t, revoked)
+
+	revoked, err = repo.IsRevoked(ctx, "active")
+	require.NoError(t, err)
+	assert.True(t, revoked)
+}
+
+func testLoginAttempts(t *testing.T, repo *Repository) {
+	ctx := context.Background()
+	now := time.Now().Truncate(time.Microsecond)
+
+	attempt, err := repo.GetLoginAttempt(ctx, "user:ann")
+	require.NoError(t, err)
+	assert.Equal(t, models.LoginAttempt{Key: "user:ann"}, attempt)
+
+	for i := 1; i <= 3; i++ {
+		attempt, err = repo.RecordLoginFailure(ctx, "user:ann", now.Add(time.Duration(i)*time.Second), time.Minute)
+		require.NoError(t, err)
+		assert.Equal(t, i, attempt.Failures)
+	}
+	assert.True(t, attempt.LastFailureAt.Equal(now.Add(3*time.Second)))
+	assert.Nil(t, attempt.LockedUntil)
+
+	until := now.Add(time.Hour)
+	require.NoError(t, repo.LockLogin(ctx, "user:ann", until))
+	attempt, err = repo.GetLoginAttempt(ctx, "user:ann")
+	require.NoError(t, err)
+	assert.Equal(t, 3, attempt.Failures)
+	require.NotNil(t, attempt.LockedUntil)
+	assert.True(t, attempt.LockedUntil.Equal(until))
+
+	// Keys are counted separately, and a failure long after the previous
+	// one starts the count over without lifting the lockout.
+	attempt, err = repo.RecordLoginFailure(ctx, "ip:192.0.2.1", now, time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempt.Failures)
+	attempt, err = repo.RecordLoginFailure(ctx, "user:ann", now.Add(5*time.Minute), time.Minute)
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempt.Failures)
+	require.NotNil(t, attempt.LockedUntil)
+
+	require.NoError(t, repo.ClearLoginAttempts(ctx, "user:ann"))
+	attempt, err = repo.GetLoginAttempt(ctx, "user:ann")
+	require.NoError(t, err)
+	assert.Equal(t, models.LoginAttempt{Key: "user:ann"}, attempt)
+	attempt, err = repo.GetLoginAttempt(ctx, "ip:192.0.2.1")
+	require.NoError(t, err)
+	assert.Equal(t, 1, attempt.Failures)
+
+	require.NoError(t, repo.CreateLockoutEvent(ctx, models.LockoutEvent{
+		Key: "user:ann", Username: "ann", ClientIP: "192.0.2.1", Failures: 5, LockedUntil: until,
+	}))
+
+	// Entries are pruned once both their last failure and their lockout
+	// are old.
+	_, err = repo.RecordLoginFailure(ctx, "user:bob", now.Add(-2*time.Hour), time.Minute)
+	require.NoError(t, err)
+	require.NoError(t, repo.LockLogin(ctx, "user:bob", until))
+	_, err = repo.RecordLoginFailure(ctx, "user:cat", now.Add(-2*time.Hour), time.Minute)
+	require.NoError(t, err)
+
+	pruned, err := repo.PruneLoginAttempts(ctx, now.Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+	attempt, err = repo.GetLoginAttempt(ctx, "user:cat")
+	require.NoError(t, err)
+	assert.Equal(t, models.LoginAttempt{Key: "user:cat"}, attempt)
+	for _, key := range []string{"user:bob", "ip:192.0.2.1"} {
+		attempt, err = repo.GetLoginAttempt(ctx, key)
+		require.NoError(t, err)
+		assert.Equal(t, 1, attempt.Failures, key)
+	}
+
+	pruned, err = repo.PruneLockoutEvents(ctx, time.Now().Add(-time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(0), pruned)
+	pruned, err = repo.PruneLockoutEvents(ctx, time.Now().Add(time.Hour))
+	require.NoError(t, err)
+	assert.Equal(t, int64(1), pruned)
+}
+
+func titles(books []models.Book) []string {
+	out := make([]string, 0, len(books))
+	for _, book := range books {
+		out = append(out, book.Title)
+	}
+	return out
+}
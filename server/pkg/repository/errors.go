@@ -0,0 +1,34 @@
+package repository
+
+import (
+	"errors"
+	"fmt"
+
+	"gorm.io/gorm"
+)
+
+// Domain errors returned by the repositories. Callers match them with
+// errors.Is; the messages wrapping them never contain database details.
+var (
+	ErrNotFound         = errors.New("not found")
+	ErrPermissionDenied = errors.New("permission denied")
+	ErrAlreadyExists    = errors.New("already exists")
+)
+
+var (
+	ErrDuplicateTitle       = fmt.Errorf("book with this title %w", ErrAlreadyExists)
+	ErrRefreshTokenNotFound = fmt.Errorf("refresh token %w", ErrNotFound)
+)
+
+// translateError maps the gorm errors that mean something to callers onto
+// domain errors described by what; other errors are wrapped with op.
+func translateError(err error, op, what string) error {
+	switch {
+	case errors.Is(err, gorm.ErrRecordNotFound):
+		return fmt.Errorf("%s %w", what, ErrNotFound)
+	case errors.Is(err, gorm.ErrDuplicatedKey):
+		return fmt.Errorf("%s %w", what, ErrAlreadyExists)
+	default:
+		return fmt.Errorf("failed to %s: %w", op, err)
+	}
+}
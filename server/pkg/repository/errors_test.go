@@ -0,0 +1,28 @@
+package repository
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gorm.io/gorm"
+)
+
+func TestTranslateError(t *testing.T) {
+	err := translateError(gorm.ErrRecordNotFound, "find user", "user")
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.Equal(t, "user not found", err.Error())
+
+	err = translateError(gorm.ErrDuplicatedKey, "create book", "book with this title")
+	assert.ErrorIs(t, err, ErrAlreadyExists)
+	assert.Equal(t, "book with this title already exists", err.Error())
+
+	dbErr := errors.New("connection refused")
+	err = translateError(dbErr, "create book", "book with this title")
+	assert.ErrorIs(t, err, dbErr)
+	assert.NotErrorIs(t, err, ErrAlreadyExists)
+	assert.Equal(t, "failed to create book: connection refused", err.Error())
+
+	assert.ErrorIs(t, ErrDuplicateTitle, ErrAlreadyExists)
+	assert.ErrorIs(t, ErrRefreshTokenNotFound, ErrNotFound)
+}
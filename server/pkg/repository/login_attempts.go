@@ -0,0 +1,47 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// PruneLoginHistory periodically drops login attempts without a failure or
+// lockout in the last resetAfter, which would start counting from zero
+// anyway, and lockout events older than retention, until ctx is cancelled.
+// A non-positive interval disables pruning, a non-positive resetAfter keeps
+// every login attempt and a non-positive retention keeps every lockout event.
+func PruneLoginHistory(ctx context.Context, store LoginAttempts, interval, resetAfter, retention time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			if resetAfter > 0 {
+				pruned, err := store.PruneLoginAttempts(ctx, now.Add(-resetAfter))
+				if err != nil {
+					slog.ErrorContext(ctx, "failed to prune login attempts", "error", err)
+				} else if pruned > 0 {
+					slog.InfoContext(ctx, "pruned stale login attempts", "count", pruned)
+				}
+			}
+
+			if retention <= 0 {
+				continue
+			}
+			pruned, err := store.PruneLockoutEvents(ctx, now.Add(-retention))
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to prune lockout events", "error", err)
+			} else if pruned > 0 {
+				slog.InfoContext(ctx, "pruned old lockout events", "count", pruned)
+			}
+		}
+	}
+}
@@ -0,0 +1,103 @@
+package repository
+
+import (
+	"context"
+	"grpc/server/models"
+	"sync"
+	"time"
+)
+
+// LoginAttemptsMemory keeps failed sign-ins in process memory. Counts and
+// lockouts are lost on restart and are not shared between replicas.
+type LoginAttemptsMemory struct {
+	mu          sync.Mutex
+	attempts    map[string]models.LoginAttempt
+	events      []models.LockoutEvent
+	nextEventID uint
+}
+
+func NewLoginAttemptsMemory() *LoginAttemptsMemory {
+	return &LoginAttemptsMemory{attempts: make(map[string]models.LoginAttempt)}
+}
+
+func (r *LoginAttemptsMemory) GetLoginAttempt(ctx context.Context, key string) (models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if attempt, ok := r.attempts[key]; ok {
+		return attempt, nil
+	}
+	return models.LoginAttempt{Key: key}, nil
+}
+
+func (r *LoginAttemptsMemory) RecordLoginFailure(ctx context.Context, key string, now time.Time, resetAfter time.Duration) (models.LoginAttempt, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	attempt, ok := r.attempts[key]
+	if !ok {
+		attempt = models.LoginAttempt{Key: key}
+	}
+	if attempt.LastFailureAt.Before(now.Add(-resetAfter)) {
+		attempt.Failures = 0
+	}
+	attempt.Failures++
+	attempt.LastFailureAt = now
+	r.attempts[key] = attempt
+	return attempt, nil
+}
+
+func (r *LoginAttemptsMemory) LockLogin(ctx context.Context, key string, until time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if attempt, ok := r.attempts[key]; ok {
+		attempt.LockedUntil = &until
+		r.attempts[key] = attempt
+	}
+	return nil
+}
+
+func (r *LoginAttemptsMemory) ClearLoginAttempts(ctx context.Context, key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	delete(r.attempts, key)
+	return nil
+}
+
+func (r *LoginAttemptsMemory) CreateLockoutEvent(ctx context.Context, event models.LockoutEvent) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.nextEventID++
+	event.ID = r.nextEventID
+	event.CreatedAt = time.Now()
+	r.events = append(r.events, event)
+	return nil
+}
+
+func (r *LoginAttemptsMemory) PruneLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pruned int64
+	for key, attempt := range r.attempts {
+		if attempt.LastFailureAt.Before(before) && (attempt.LockedUntil == nil || attempt.LockedUntil.Before(before)) {
+			delete(r.attempts, key)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
+
+func (r *LoginAttemptsMemory) PruneLockoutEvents(ctx context.Context, before time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	kept := r.events[:0]
+	for _, event := range r.events {
+		if !event.CreatedAt.Before(before) {
+			kept = append(kept, event)
+		}
+	}
+	pruned := int64(len(r.events) - len(kept))
+	r.events = kept
+	return pruned, nil
+}
@@ -0,0 +1,87 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"grpc/server/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type LoginAttemptsPostgres struct {
+	db *gorm.DB
+}
+
+func NewLoginAttemptsPostgres(db *gorm.DB) *LoginAttemptsPostgres {
+	return &LoginAttemptsPostgres{db: db}
+}
+
+func (r *LoginAttemptsPostgres) GetLoginAttempt(ctx context.Context, key string) (models.LoginAttempt, error) {
+	var attempts []models.LoginAttempt
+	if err := r.db.WithContext(ctx).Where("key = ?", key).Limit(1).Find(&attempts).Error; err != nil {
+		return models.LoginAttempt{}, fmt.Errorf("failed to get login attempts: %w", err)
+	}
+	if len(attempts) == 0 {
+		return models.LoginAttempt{Key: key}, nil
+	}
+	return attempts[0], nil
+}
+
+// RecordLoginFailure increments the count in a single statement so that
+// concurrent failures are all counted.
+func (r *LoginAttemptsPostgres) RecordLoginFailure(ctx context.Context, key string, now time.Time, resetAfter time.Duration) (models.LoginAttempt, error) {
+	var attempt models.LoginAttempt
+	err := r.db.WithContext(ctx).Raw(`
+		INSERT INTO login_attempts (key, failures, last_failure_at) VALUES (?, 1, ?)
+		ON CONFLICT (key) DO UPDATE SET
+			failures = CASE WHEN login_attempts.last_failure_at < ? THEN 1 ELSE login_attempts.failures + 1 END,
+			last_failure_at = excluded.last_failure_at
+		RETURNING key, failures, last_failure_at, locked_until`,
+		key, now, now.Add(-resetAfter),
+	).Scan(&attempt).Error
+	if err != nil {
+		return models.LoginAttempt{}, fmt.Errorf("failed to record login failure: %w", err)
+	}
+	return attempt, nil
+}
+
+func (r *LoginAttemptsPostgres) LockLogin(ctx context.Context, key string, until time.Time) error {
+	err := r.db.WithContext(ctx).Model(&models.LoginAttempt{}).Where("key = ?", key).Update("locked_until", until).Error
+	if err != nil {
+		return fmt.Errorf("failed to lock login: %w", err)
+	}
+	return nil
+}
+
+func (r *LoginAttemptsPostgres) ClearLoginAttempts(ctx context.Context, key string) error {
+	if err := r.db.WithContext(ctx).Where("key = ?", key).Delete(&models.LoginAttempt{}).Error; err != nil {
+		return fmt.Errorf("failed to clear login attempts: %w", err)
+	}
+	return nil
+}
+
+func (r *LoginAttemptsPostgres) CreateLockoutEvent(ctx context.Context, event models.LockoutEvent) error {
+	if err := r.db.WithContext(ctx).Create(&event).Error; err != nil {
+		return fmt.Errorf("failed to record lockout: %w", err)
+	}
+	return nil
+}
+
+func (r *LoginAttemptsPostgres) PruneLoginAttempts(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).
+		Where("last_failure_at < ? AND (locked_until IS NULL OR locked_until < ?)", before, before).
+		Delete(&models.LoginAttempt{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to prune login attempts: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
+
+func (r *LoginAttemptsPostgres) PruneLockoutEvents(ctx context.Context, before time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("created_at < ?", before).Delete(&models.LockoutEvent{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to prune lockout events: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"grpc/server/models"
+
+	"gorm.io/gorm"
+)
+
+// minEstimatedBooks is the table size from which an unfiltered total is
+// estimated rather than counted. Below it counting is cheap, and the
+// statistics of a small, freshly written table are often stale.
+const minEstimatedBooks = 10000
+
+// bookOrderColumns maps the public order_by keys to book columns.
+var bookOrderColumns = map[string]string{
+	"id":      "id",
+	"title":   "title",
+	"author":  "author",
+	"created": "created_at",
+}
+
+func filterBooks(tx *gorm.DB, filter models.BookFilter) *gorm.DB {
+	if filter.Author != "" {
+		tx = tx.Where("author = ?", filter.Author)
+	}
+	if filter.UserId != 0 {
+		tx = tx.Where("user_id = ?", filter.UserId)
+	}
+	if filter.TitlePrefix != "" {
+		tx = tx.Where("title LIKE ? ESCAPE '\\'", escapeLike(filter.TitlePrefix)+"%")
+	}
+	return tx
+}
+
+func cursorValue(column string, cursor *models.BookCursor) interface{} {
+	switch column {
+	case "title":
+		return cursor.Title
+	case "author":
+		return cursor.Author
+	case "created_at":
+		return cursor.CreatedAt
+	default:
+		return cursor.ID
+	}
+}
+
+func escapeLike(s string) string {
+	out := make([]rune, 0, len(s))
+	for _, r := range s {
+		if r == '%' || r == '_' || r == '\\' {
+			out = append(out, '\\')
+		}
+		out = append(out, r)
+	}
+	return string(out)
+}
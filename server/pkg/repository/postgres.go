@@ -1,8 +1,8 @@
 package repository
 
 import (
+	"context"
 	"fmt"
-	"grpc/server/models"
 	"log"
 
 	"gorm.io/driver/postgres"
@@ -21,12 +21,28 @@ type Config struct {
 func NewPostgresDB(cfg Config) *gorm.DB {
 	dsn := fmt.Sprintf("host=%s port=%s user=%s dbname=%s password=%s sslmode=%s",
 		cfg.Host, cfg.Port, cfg.Username, cfg.DBName, cfg.Password, cfg.SSLMode)
-	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{})
+	db, err := gorm.Open(postgres.Open(dsn), &gorm.Config{TranslateError: true})
 	if err != nil {
 		log.Fatal("Database connection failed:", err)
 	}
-	db.AutoMigrate(&models.User{}, &models.Book{})
 
 	fmt.Println("Database connected")
 	return db
 }
+
+// DBPinger checks that the database behind a GORM connection pool answers.
+type DBPinger struct {
+	db *gorm.DB
+}
+
+func NewDBPinger(db *gorm.DB) *DBPinger {
+	return &DBPinger{db: db}
+}
+
+func (p *DBPinger) Ping(ctx context.Context) error {
+	sqlDB, err := p.db.DB()
+	if err != nil {
+		return err
+	}
+	return sqlDB.PingContext(ctx)
+}
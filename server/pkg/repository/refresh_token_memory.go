@@ -0,0 +1,78 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"grpc/server/models"
+	"sync"
+	"time"
+)
+
+// RefreshTokenMemory keeps refresh tokens in process memory. Tokens are lost
+// on restart, which signs every user out.
+type RefreshTokenMemory struct {
+	mu     sync.Mutex
+	tokens map[uint]models.RefreshToken
+	nextID uint
+}
+
+func NewRefreshTokenMemory() *RefreshTokenMemory {
+	return &RefreshTokenMemory{tokens: make(map[uint]models.RefreshToken)}
+}
+
+func (r *RefreshTokenMemory) CreateRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, existing := range r.tokens {
+		if existing.TokenHash == token.TokenHash {
+			return fmt.Errorf("refresh token %w", ErrAlreadyExists)
+		}
+	}
+
+	r.nextID++
+	token.ID = r.nextID
+	token.CreatedAt = time.Now()
+	r.tokens[token.ID] = token
+	return nil
+}
+
+func (r *RefreshTokenMemory) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	for _, token := range r.tokens {
+		if token.TokenHash == tokenHash {
+			return token, nil
+		}
+	}
+	return models.RefreshToken{}, ErrRefreshTokenNotFound
+}
+
+func (r *RefreshTokenMemory) MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	token, ok := r.tokens[id]
+	if !ok || token.UsedAt != nil || token.RevokedAt != nil {
+		return false, nil
+	}
+	now := time.Now()
+	token.UsedAt = &now
+	r.tokens[id] = token
+	return true, nil
+}
+
+func (r *RefreshTokenMemory) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	for id, token := range r.tokens {
+		if token.FamilyID == familyId && token.RevokedAt == nil {
+			token.RevokedAt = &now
+			r.tokens[id] = token
+		}
+	}
+	return nil
+}
@@ -0,0 +1,57 @@
+package repository
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grpc/server/models"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RefreshTokenPostgres struct {
+	db *gorm.DB
+}
+
+func NewRefreshTokenPostgres(db *gorm.DB) *RefreshTokenPostgres {
+	return &RefreshTokenPostgres{db: db}
+}
+
+func (r *RefreshTokenPostgres) CreateRefreshToken(ctx context.Context, token models.RefreshToken) error {
+	if err := r.db.WithContext(ctx).Create(&token).Error; err != nil {
+		return fmt.Errorf("failed to store refresh token: %w", err)
+	}
+	return nil
+}
+
+func (r *RefreshTokenPostgres) GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error) {
+	var token models.RefreshToken
+	if err := r.db.WithContext(ctx).Where("token_hash = ?", tokenHash).First(&token).Error; err != nil {
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return models.RefreshToken{}, ErrRefreshTokenNotFound
+		}
+		return models.RefreshToken{}, fmt.Errorf("failed to find refresh token: %w", err)
+	}
+	return token, nil
+}
+
+func (r *RefreshTokenPostgres) MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error) {
+	res := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("id = ? AND used_at IS NULL AND revoked_at IS NULL", id).
+		Update("used_at", time.Now())
+	if res.Error != nil {
+		return false, fmt.Errorf("failed to mark refresh token used: %w", res.Error)
+	}
+	return res.RowsAffected == 1, nil
+}
+
+func (r *RefreshTokenPostgres) RevokeRefreshTokenFamily(ctx context.Context, familyId string) error {
+	err := r.db.WithContext(ctx).Model(&models.RefreshToken{}).
+		Where("family_id = ? AND revoked_at IS NULL", familyId).
+		Update("revoked_at", time.Now()).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke refresh token family: %w", err)
+	}
+	return nil
+}
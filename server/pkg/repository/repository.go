@@ -1,7 +1,9 @@
 package repository
 
 import (
+	"context"
 	"grpc/server/models"
+	"time"
 
 	"gorm.io/gorm"
 )
@@ -9,26 +11,100 @@ import (
 //go:generate mockgen -source=repository.go -destination=mocks/mock.go
 
 type Authorization interface {
-	CreateUser(user models.User) (uint, error)
-	GetUser(username string) (models.User, error)
+	CreateUser(ctx context.Context, user models.User) (uint, error)
+	GetUser(ctx context.Context, username string) (models.User, error)
+	GetUserById(ctx context.Context, id uint) (models.User, error)
+	// UpdatePassword replaces the stored password hash of user id.
+	UpdatePassword(ctx context.Context, id uint, hash string) error
+}
+
+type RefreshToken interface {
+	CreateRefreshToken(ctx context.Context, token models.RefreshToken) error
+	GetRefreshToken(ctx context.Context, tokenHash string) (models.RefreshToken, error)
+	// MarkRefreshTokenUsed reports false when the token had already been
+	// used or revoked, which callers must treat as a replay.
+	MarkRefreshTokenUsed(ctx context.Context, id uint) (bool, error)
+	RevokeRefreshTokenFamily(ctx context.Context, familyId string) error
+}
+
+type Revocation interface {
+	Revoke(ctx context.Context, jti string, expiresAt time.Time) error
+	IsRevoked(ctx context.Context, jti string) (bool, error)
+	// PruneExpired removes entries for tokens that expired before now.
+	PruneExpired(ctx context.Context, now time.Time) (int64, error)
+}
+
+// LoginAttempts counts failed sign-ins per key and keeps a record of
+// lockouts.
+type LoginAttempts interface {
+	// GetLoginAttempt returns an entry without failures when key has none.
+	GetLoginAttempt(ctx context.Context, key string) (models.LoginAttempt, error)
+	// RecordLoginFailure counts a failure at now and returns the updated
+	// entry. The count starts over when the previous failure is older than
+	// resetAfter.
+	RecordLoginFailure(ctx context.Context, key string, now time.Time, resetAfter time.Duration) (models.LoginAttempt, error)
+	LockLogin(ctx context.Context, key string, until time.Time) error
+	// ClearLoginAttempts forgets the failures and any lockout of key.
+	ClearLoginAttempts(ctx context.Context, key string) error
+	CreateLockoutEvent(ctx context.Context, event models.LockoutEvent) error
+	// PruneLoginAttempts removes entries whose last failure and lockout are
+	// both older than before.
+	PruneLoginAttempts(ctx context.Context, before time.Time) (int64, error)
+	// PruneLockoutEvents removes events recorded before before.
+	PruneLockoutEvents(ctx context.Context, before time.Time) (int64, error)
 }
 
 type Book interface {
-	Create(book models.Book) (uint, error)
-	GetAll() ([]models.Book, error)
-	GetById(bookId uint) (models.Book, error)
-	Delete(userId, bookId uint) error
-	Update(userId, bookId uint, book models.UpdateBook) error
+	Create(ctx context.Context, book models.Book) (uint, error)
+	// CreateBatch inserts books in one transaction and reports a result per
+	// book; a duplicate title fails only its own book.
+	CreateBatch(ctx context.Context, books []models.Book) ([]models.BatchResult, error)
+	List(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error)
+	GetBatch(ctx context.Context, filter models.BookFilter, afterId uint, limit int) ([]models.Book, error)
+	GetById(ctx context.Context, bookId uint) (models.Book, error)
+	Delete(ctx context.Context, principal models.Principal, bookId uint) (models.Book, error)
+	Update(ctx context.Context, principal models.Principal, bookId uint, book models.UpdateBook) (models.Book, error)
+}
+
+// Pinger reports whether the backing store can serve requests.
+type Pinger interface {
+	Ping(ctx context.Context) error
 }
 
 type Repository struct {
 	Authorization
+	RefreshToken
+	Revocation
+	LoginAttempts
 	Book
+	Pinger
 }
 
 func NewRepository(db *gorm.DB) *Repository {
 	return &Repository{
 		Authorization: NewAuthPostgres(db),
+		RefreshToken:  NewRefreshTokenPostgres(db),
+		Revocation:    NewRevocationPostgres(db),
+		LoginAttempts: NewLoginAttemptsPostgres(db),
 		Book:          NewBookPostgres(db),
+		Pinger:        NewDBPinger(db),
 	}
 }
+
+// NewMemoryRepository keeps all data in process memory, for local development
+// and tests. Nothing survives a restart.
+func NewMemoryRepository() *Repository {
+	return &Repository{
+		Authorization: NewAuthMemory(),
+		RefreshToken:  NewRefreshTokenMemory(),
+		Revocation:    NewRevocationMemory(),
+		LoginAttempts: NewLoginAttemptsMemory(),
+		Book:          NewBookMemory(),
+		Pinger:        memoryPinger{},
+	}
+}
+
+// memoryPinger always succeeds: in-memory storage cannot become unreachable.
+type memoryPinger struct{}
+
+func (memoryPinger) Ping(context.Context) error { return nil }
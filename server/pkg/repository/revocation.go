@@ -0,0 +1,32 @@
+package repository
+
+import (
+	"context"
+	"log/slog"
+	"time"
+)
+
+// PruneRevocations periodically drops revocations of expired tokens until
+// ctx is cancelled. A non-positive interval disables pruning.
+func PruneRevocations(ctx context.Context, store Revocation, interval time.Duration) {
+	if interval <= 0 {
+		return
+	}
+
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ctx.Done():
+			return
+		case now := <-ticker.C:
+			pruned, err := store.PruneExpired(ctx, now)
+			if err != nil {
+				slog.ErrorContext(ctx, "failed to prune token revocations", "error", err)
+			} else if pruned > 0 {
+				slog.InfoContext(ctx, "pruned expired token revocations", "count", pruned)
+			}
+		}
+	}
+}
@@ -0,0 +1,46 @@
+package repository
+
+import (
+	"context"
+	"sync"
+	"time"
+)
+
+// RevocationMemory keeps revoked token ids in process memory. Revocations are
+// lost on restart and are not shared between replicas.
+type RevocationMemory struct {
+	mu      sync.RWMutex
+	revoked map[string]time.Time
+}
+
+func NewRevocationMemory() *RevocationMemory {
+	return &RevocationMemory{revoked: make(map[string]time.Time)}
+}
+
+func (r *RevocationMemory) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.revoked[jti] = expiresAt
+	return nil
+}
+
+func (r *RevocationMemory) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+	_, ok := r.revoked[jti]
+	return ok, nil
+}
+
+func (r *RevocationMemory) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var pruned int64
+	for jti, expiresAt := range r.revoked {
+		if expiresAt.Before(now) {
+			delete(r.revoked, jti)
+			pruned++
+		}
+	}
+	return pruned, nil
+}
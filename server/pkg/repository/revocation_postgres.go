@@ -0,0 +1,44 @@
+package repository
+
+import (
+	"context"
+	"fmt"
+	"grpc/server/models"
+	"time"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type RevocationPostgres struct {
+	db *gorm.DB
+}
+
+func NewRevocationPostgres(db *gorm.DB) *RevocationPostgres {
+	return &RevocationPostgres{db: db}
+}
+
+func (r *RevocationPostgres) Revoke(ctx context.Context, jti string, expiresAt time.Time) error {
+	err := r.db.WithContext(ctx).Clauses(clause.OnConflict{DoNothing: true}).
+		Create(&models.RevokedToken{JTI: jti, ExpiresAt: expiresAt}).Error
+	if err != nil {
+		return fmt.Errorf("failed to revoke token: %w", err)
+	}
+	return nil
+}
+
+func (r *RevocationPostgres) IsRevoked(ctx context.Context, jti string) (bool, error) {
+	var count int64
+	if err := r.db.WithContext(ctx).Model(&models.RevokedToken{}).Where("jti = ?", jti).Count(&count).Error; err != nil {
+		return false, fmt.Errorf("failed to check token revocation: %w", err)
+	}
+	return count > 0, nil
+}
+
+func (r *RevocationPostgres) PruneExpired(ctx context.Context, now time.Time) (int64, error) {
+	res := r.db.WithContext(ctx).Where("expires_at < ?", now).Delete(&models.RevokedToken{})
+	if res.Error != nil {
+		return 0, fmt.Errorf("failed to prune revoked tokens: %w", res.Error)
+	}
+	return res.RowsAffected, nil
+}
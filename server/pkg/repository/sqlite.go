@@ -0,0 +1,36 @@
+package repository
+
+import (
+	"fmt"
+	"log"
+	"os"
+	"path/filepath"
+
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// NewSQLiteDB opens the SQLite database file at path, creating it and its
+// directory if needed.
+// LIKE is made case-sensitive to match Postgres, and a single connection is
+// used because SQLite allows one writer at a time.
+func NewSQLiteDB(path string) *gorm.DB {
+	if err := os.MkdirAll(filepath.Dir(path), 0o755); err != nil {
+		log.Fatal("Database connection failed:", err)
+	}
+
+	dsn := fmt.Sprintf("file:%s?_foreign_keys=on&_busy_timeout=5000&_journal_mode=WAL&_cslike=true", path)
+	db, err := gorm.Open(sqlite.Open(dsn), &gorm.Config{TranslateError: true})
+	if err != nil {
+		log.Fatal("Database connection failed:", err)
+	}
+
+	sqlDB, err := db.DB()
+	if err != nil {
+		log.Fatal("Database connection failed:", err)
+	}
+	sqlDB.SetMaxOpenConns(1)
+
+	fmt.Println("Database connected")
+	return db
+}
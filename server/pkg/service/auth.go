@@ -1,31 +1,53 @@
 package service
 
 import (
+	"context"
+	"crypto/rand"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"errors"
 	"fmt"
 	"grpc/server/models"
+	"grpc/server/pkg/password"
 	"grpc/server/pkg/repository"
 	"log"
+	"log/slog"
 	"os"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
 	"github.com/joho/godotenv"
-	"golang.org/x/crypto/bcrypt"
 )
 
 const (
-	tokenTTL = 12 * time.Hour
+	accessTokenTTL  = 15 * time.Minute
+	refreshTokenTTL = 30 * 24 * time.Hour
+)
+
+var (
+	ErrInvalidRefreshToken = errors.New("invalid refresh token")
+	ErrRefreshTokenReused  = errors.New("refresh token reuse detected")
+	ErrTokenRevoked        = errors.New("token has been revoked")
+	ErrInvalidToken        = errors.New("invalid token")
 )
 
 type AuthService struct {
-	repo      repository.Authorization
-	jwtSecret []byte
+	repo    repository.Authorization
+	tokens  repository.RefreshToken
+	revoked repository.Revocation
+	keys    *KeyRing
+	hasher  password.Hasher
+	policy  *password.Checker
+	// dummyHash is verified against the password of unknown usernames so
+	// that they take at least as long to reject as wrong passwords.
+	dummyHash string
 }
 
 type tokenClaims struct {
 	jwt.RegisteredClaims
-	UserID uint `json:"user_id"`
+	UserID uint   `json:"user_id"`
+	Role   string `json:"role,omitempty"`
 }
 
 func init() {
@@ -34,76 +56,267 @@ func init() {
 	}
 }
 
-func NewAuthService(repo repository.Authorization) *AuthService {
-	secret := os.Getenv("JWT_SECRET")
-	if secret == "" {
-		log.Fatal("JWT_SECRET is not set in environment variables")
+func NewAuthService(repo repository.Authorization, tokens repository.RefreshToken, revoked repository.Revocation,
+	hasher password.Hasher, policy *password.Checker) *AuthService {
+	keys, err := keyRingFromEnv()
+	if err != nil {
+		log.Fatalf("loading JWT keys: %s", err.Error())
+	}
+	dummyHash, err := hasher.DummyHash()
+	if err != nil {
+		log.Fatalf("hashing dummy password: %s", err.Error())
 	}
 
 	return &AuthService{
 		repo:      repo,
-		jwtSecret: []byte(secret),
+		tokens:    tokens,
+		revoked:   revoked,
+		keys:      keys,
+		hasher:    hasher,
+		policy:    policy,
+		dummyHash: dummyHash,
 	}
 }
 
-func (s *AuthService) CreateUser(user models.User) (uint, error) {
-	hashedPassword, _ := generatePasswordHash(user.Password)
+// keyRingFromEnv loads the asymmetric keys from JWT_KEYS_DIR when it is set
+// and falls back to the HS256 JWT_SECRET otherwise.
+func keyRingFromEnv() (*KeyRing, error) {
+	if dir := os.Getenv("JWT_KEYS_DIR"); dir != "" {
+		return LoadKeyRing(dir, os.Getenv("JWT_SIGNING_KID"))
+	}
+
+	secret := os.Getenv("JWT_SECRET")
+	if secret == "" {
+		return nil, errors.New("neither JWT_KEYS_DIR nor JWT_SECRET is set in environment variables")
+	}
+	return NewHMACKeyRing([]byte(secret)), nil
+}
+
+// CreateUser hashes the password before the username is checked, so a taken
+// username is not answered any faster than a free one. It still returns
+// ErrAlreadyExists for it; SignUp hides that from the caller.
+func (s *AuthService) CreateUser(ctx context.Context, user models.User) (uint, error) {
+	if err := s.policy.Check(user.Password); err != nil {
+		return 0, err
+	}
+	hashedPassword, err := s.hasher.Hash(user.Password)
+	if err != nil {
+		return 0, err
+	}
 	user.Password = hashedPassword
-	return s.repo.CreateUser(user)
+	user.Role = models.RoleUser
+	return s.repo.CreateUser(ctx, user)
 }
 
-func (s *AuthService) GenerateToken(username, password string) (string, error) {
+// GenerateToken answers unknown usernames and wrong passwords with the same
+// error after a hash comparison. Unknown usernames are checked against the
+// slowest of the configured algorithms, so they are never rejected faster
+// than a stored hash of either. A password stored with outdated hashing
+// settings is hashed again once it has been verified.
+func (s *AuthService) GenerateToken(ctx context.Context, username, password string) (models.TokenPair, error) {
+	user, err := s.repo.GetUser(ctx, username)
+	if err != nil {
+		if errors.Is(err, ErrNotFound) {
+			_, _ = s.hasher.Verify(s.dummyHash, password)
+			return models.TokenPair{}, ErrInvalidCredentials
+		}
+		return models.TokenPair{}, err
+	}
 
-	user, err := s.repo.GetUser(username)
+	ok, err := s.hasher.Verify(user.Password, password)
 	if err != nil {
-		return "", err
+		return models.TokenPair{}, fmt.Errorf("verifying password of user %d: %w", user.ID, err)
+	}
+	if !ok {
+		return models.TokenPair{}, ErrInvalidCredentials
+	}
+	if s.hasher.NeedsRehash(user.Password) {
+		s.rehash(ctx, user.ID, password)
+	}
+
+	familyId, err := randomToken()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	return s.issueTokens(ctx, user, familyId)
+}
+
+// rehash stores a new hash of password. Failures only delay the upgrade to
+// the next sign-in, so they do not fail this one.
+func (s *AuthService) rehash(ctx context.Context, userID uint, password string) {
+	hash, err := s.hasher.Hash(password)
+	if err == nil {
+		err = s.repo.UpdatePassword(ctx, userID, hash)
+	}
+	if err != nil {
+		slog.ErrorContext(ctx, "failed to upgrade password hash", "user_id", userID, "error", err)
+	}
+}
+
+// RefreshToken exchanges a refresh token for a new token pair. Every refresh
+// token can be used once; presenting one again means it has leaked, so the
+// whole family issued from the same sign-in is revoked.
+func (s *AuthService) RefreshToken(ctx context.Context, refreshToken string) (models.TokenPair, error) {
+	stored, err := s.tokens.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return models.TokenPair{}, ErrInvalidRefreshToken
+		}
+		return models.TokenPair{}, err
+	}
+
+	if stored.RevokedAt != nil || time.Now().After(stored.ExpiresAt) {
+		return models.TokenPair{}, ErrInvalidRefreshToken
+	}
+
+	fresh := stored.UsedAt == nil
+	if fresh {
+		// Two concurrent refreshes with the same token race here; only
+		// one of them wins and the other is treated as a replay.
+		if fresh, err = s.tokens.MarkRefreshTokenUsed(ctx, stored.ID); err != nil {
+			return models.TokenPair{}, err
+		}
+	}
+	if !fresh {
+		if err := s.tokens.RevokeRefreshTokenFamily(ctx, stored.FamilyID); err != nil {
+			return models.TokenPair{}, err
+		}
+		return models.TokenPair{}, ErrRefreshTokenReused
+	}
+
+	// Reload the user so that role changes apply from the next refresh.
+	user, err := s.repo.GetUserById(ctx, stored.UserID)
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+	return s.issueTokens(ctx, user, stored.FamilyID)
+}
+
+// SignOut revokes the access token until it expires and, when given, the
+// refresh token family it was issued with so the session cannot be renewed.
+func (s *AuthService) SignOut(ctx context.Context, accessToken, refreshToken string) error {
+	claims, err := s.parseClaims(accessToken)
+	if err != nil {
+		return err
 	}
 
-	if err := bcrypt.CompareHashAndPassword([]byte(user.Password), []byte(password)); err != nil {
-		return "", errors.New("invalid password")
+	if claims.ID != "" && claims.ExpiresAt != nil {
+		if err := s.revoked.Revoke(ctx, claims.ID, claims.ExpiresAt.Time); err != nil {
+			return err
+		}
 	}
 
-	token := jwt.NewWithClaims(jwt.SigningMethodHS256, tokenClaims{
+	if refreshToken == "" {
+		return nil
+	}
+	stored, err := s.tokens.GetRefreshToken(ctx, hashToken(refreshToken))
+	if err != nil {
+		if errors.Is(err, repository.ErrRefreshTokenNotFound) {
+			return nil
+		}
+		return err
+	}
+	if stored.UserID != claims.UserID {
+		return nil
+	}
+	return s.tokens.RevokeRefreshTokenFamily(ctx, stored.FamilyID)
+}
+
+func (s *AuthService) issueTokens(ctx context.Context, user models.User, familyId string) (models.TokenPair, error) {
+	now := time.Now()
+
+	jti, err := randomToken()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	accessToken, err := s.keys.Sign(tokenClaims{
 		RegisteredClaims: jwt.RegisteredClaims{
-			ExpiresAt: jwt.NewNumericDate(time.Now().Add(tokenTTL)),
-			IssuedAt:  jwt.NewNumericDate(time.Now()),
+			ID:        jti,
+			ExpiresAt: jwt.NewNumericDate(now.Add(accessTokenTTL)),
+			IssuedAt:  jwt.NewNumericDate(now),
 		},
 		UserID: user.ID,
+		Role:   user.Role,
 	})
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	refreshToken, err := randomToken()
+	if err != nil {
+		return models.TokenPair{}, err
+	}
 
-	return token.SignedString(s.jwtSecret)
+	err = s.tokens.CreateRefreshToken(ctx, models.RefreshToken{
+		UserID:    user.ID,
+		FamilyID:  familyId,
+		TokenHash: hashToken(refreshToken),
+		ExpiresAt: now.Add(refreshTokenTTL),
+	})
+	if err != nil {
+		return models.TokenPair{}, err
+	}
+
+	return models.TokenPair{
+		AccessToken:  accessToken,
+		RefreshToken: refreshToken,
+		ExpiresIn:    accessTokenTTL,
+	}, nil
 }
 
-func (s *AuthService) ParseToken(tokenString string) (uint, error) {
-	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
-		if _, ok := token.Method.(*jwt.SigningMethodHMAC); !ok {
-			return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
-		}
+func (s *AuthService) ParseToken(ctx context.Context, tokenString string) (models.Principal, error) {
+	claims, err := s.parseClaims(tokenString)
+	if err != nil {
+		return models.Principal{}, err
+	}
 
-		return s.jwtSecret, nil
-	})
+	if claims.ID != "" {
+		revoked, err := s.revoked.IsRevoked(ctx, claims.ID)
+		if err != nil {
+			return models.Principal{}, err
+		}
+		if revoked {
+			return models.Principal{}, ErrTokenRevoked
+		}
+	}
 
-	if err != nil || !token.Valid {
-		return 0, fmt.Errorf("invalid token")
+	// Tokens issued before roles were introduced belong to regular users.
+	role := claims.Role
+	if role == "" {
+		role = models.RoleUser
 	}
+	return models.Principal{UserID: claims.UserID, Role: role}, nil
+}
 
-	claims, ok := token.Claims.(jwt.MapClaims)
-	if !ok {
-		return 0, fmt.Errorf("cannot parse claims")
+func (s *AuthService) JWKS() []models.JWK {
+	return s.keys.JWKS()
+}
+
+func (s *AuthService) parseClaims(tokenString string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	token, err := jwt.ParseWithClaims(tokenString, claims, s.keys.Keyfunc)
+
+	if err != nil || !token.Valid {
+		return nil, ErrInvalidToken
 	}
 
-	userIDFloat, ok := claims["user_id"].(float64)
-	if !ok {
-		return 0, fmt.Errorf("user_id not found in token")
+	if claims.UserID == 0 {
+		return nil, fmt.Errorf("user_id not found in token: %w", ErrInvalidToken)
 	}
 
-	return uint(userIDFloat), nil
+	return claims, nil
 }
 
-func generatePasswordHash(password string) (string, error) {
-	hashedPassword, err := bcrypt.GenerateFromPassword([]byte(password), bcrypt.DefaultCost)
-	if err != nil {
+func randomToken() (string, error) {
+	b := make([]byte, 32)
+	if _, err := rand.Read(b); err != nil {
 		return "", err
 	}
-	return string(hashedPassword), nil
+	return base64.RawURLEncoding.EncodeToString(b), nil
+}
+
+func hashToken(token string) string {
+	sum := sha256.Sum256([]byte(token))
+	return hex.EncodeToString(sum[:])
 }
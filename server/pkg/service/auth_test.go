@@ -1,14 +1,21 @@
 package service
 
 import (
+	"context"
 	"errors"
+	"fmt"
 	"grpc/server/models"
+	"grpc/server/pkg/password"
+	"grpc/server/pkg/repository"
+	mock_repository "grpc/server/pkg/repository/mocks"
 	"os"
+	"strings"
 
 	"testing"
 	"time"
 
 	"github.com/golang-jwt/jwt/v5"
+	"github.com/golang/mock/gomock"
 	"github.com/stretchr/testify/assert"
 	"github.com/stretchr/testify/mock"
 	"golang.org/x/crypto/bcrypt"
@@ -18,44 +25,105 @@ type MockAuthRepo struct {
 	mock.Mock
 }
 
-func (m *MockAuthRepo) CreateUser(user models.User) (uint, error) {
+func (m *MockAuthRepo) CreateUser(ctx context.Context, user models.User) (uint, error) {
 	args := m.Called(user)
 	return args.Get(0).(uint), args.Error(1)
 }
 
-func (m *MockAuthRepo) GetUser(username string) (models.User, error) {
+func (m *MockAuthRepo) GetUser(ctx context.Context, username string) (models.User, error) {
 	args := m.Called(username)
 	return args.Get(0).(models.User), args.Error(1)
 }
 
+func (m *MockAuthRepo) GetUserById(ctx context.Context, id uint) (models.User, error) {
+	args := m.Called(id)
+	return args.Get(0).(models.User), args.Error(1)
+}
+
+func (m *MockAuthRepo) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	args := m.Called(id, hash)
+	return args.Error(0)
+}
+
+var (
+	// testHasher matches the bcrypt hashes the tests create, so that
+	// signing in does not upgrade them.
+	testHasher password.Hasher
+	testPolicy *password.Checker
+)
+
 func TestMain(m *testing.M) {
 	os.Setenv("JWT_SECRET", "TEST_SECRET_KEY")
+
+	var err error
+	testHasher, err = password.New(password.Config{
+		Algorithm: password.AlgorithmBcrypt,
+		Bcrypt:    password.DefaultBcryptParams(),
+		Argon2id:  password.DefaultArgon2idParams(),
+	})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "building test hasher: %v\n", err)
+		os.Exit(1)
+	}
+	testPolicy, err = password.NewChecker(password.Policy{MinLength: 5})
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "building test password policy: %v\n", err)
+		os.Exit(1)
+	}
+
 	code := m.Run()
 	os.Exit(code)
 }
 
 func TestAuthService_CreateUser(t *testing.T) {
 	mockRepo := new(MockAuthRepo)
-	service := NewAuthService(mockRepo)
+	service := NewAuthService(mockRepo, nil, repository.NewRevocationMemory(), testHasher, testPolicy)
 
 	user := models.User{
 		Username: "test",
 		Password: "12345",
 	}
 
-	mockRepo.On("CreateUser", mock.AnythingOfType("models.User")).
+	mockRepo.On("CreateUser", mock.MatchedBy(func(u models.User) bool {
+		return u.Role == models.RoleUser && u.Password != "12345"
+	})).
 		Return(uint(1), nil)
 
-	id, err := service.CreateUser(user)
+	id, err := service.CreateUser(context.Background(), user)
 
 	assert.NoError(t, err)
 	assert.Equal(t, uint(1), id)
 	mockRepo.AssertExpectations(t)
 }
 
+func TestAuthService_CreateUser_WeakPassword(t *testing.T) {
+	mockRepo := new(MockAuthRepo)
+	service := NewAuthService(mockRepo, nil, repository.NewRevocationMemory(), testHasher, testPolicy)
+
+	_, err := service.CreateUser(context.Background(), models.User{Username: "test", Password: "1234"})
+
+	assert.ErrorIs(t, err, ErrWeakPassword)
+	mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything)
+}
+
+func TestAuthService_CreateUser_HashError(t *testing.T) {
+	mockRepo := new(MockAuthRepo)
+	service := NewAuthService(mockRepo, nil, repository.NewRevocationMemory(), testHasher, testPolicy)
+
+	// Within the policy, but longer than bcrypt accepts.
+	_, err := service.CreateUser(context.Background(), models.User{Username: "test", Password: strings.Repeat("x", 73)})
+
+	assert.Error(t, err)
+	mockRepo.AssertNotCalled(t, "CreateUser", mock.Anything)
+}
+
 func TestAuthService_GenerateToken_Success(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
 	mockRepo := new(MockAuthRepo)
-	service := NewAuthService(mockRepo)
+	mockTokens := mock_repository.NewMockRefreshToken(ctrl)
+	service := NewAuthService(mockRepo, mockTokens, repository.NewRevocationMemory(), testHasher, testPolicy)
 
 	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.DefaultCost)
 
@@ -67,16 +135,30 @@ func TestAuthService_GenerateToken_Success(t *testing.T) {
 
 	mockRepo.On("GetUser", "user").Return(user, nil)
 
-	token, err := service.GenerateToken("user", "password123")
+	var stored models.RefreshToken
+	mockTokens.EXPECT().
+		CreateRefreshToken(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, token models.RefreshToken) error {
+			stored = token
+			return nil
+		})
+
+	tokens, err := service.GenerateToken(context.Background(), "user", "password123")
 
 	assert.NoError(t, err)
-	assert.NotEmpty(t, token)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEmpty(t, tokens.RefreshToken)
+	assert.Equal(t, accessTokenTTL, tokens.ExpiresIn)
+	assert.Equal(t, uint(10), stored.UserID)
+	assert.NotEmpty(t, stored.FamilyID)
+	assert.Equal(t, hashToken(tokens.RefreshToken), stored.TokenHash)
+	assert.NotEqual(t, tokens.RefreshToken, stored.TokenHash)
 	mockRepo.AssertExpectations(t)
 }
 
 func TestAuthService_GenerateToken_InvalidPassword(t *testing.T) {
 	mockRepo := new(MockAuthRepo)
-	service := NewAuthService(mockRepo)
+	service := NewAuthService(mockRepo, nil, repository.NewRevocationMemory(), testHasher, testPolicy)
 
 	hashed, _ := bcrypt.GenerateFromPassword([]byte("correct"), bcrypt.DefaultCost)
 
@@ -86,29 +168,94 @@ func TestAuthService_GenerateToken_InvalidPassword(t *testing.T) {
 		Password: string(hashed),
 	}, nil)
 
-	token, err := service.GenerateToken("user", "wrong")
+	tokens, err := service.GenerateToken(context.Background(), "user", "wrong")
 
 	assert.Error(t, err)
-	assert.Equal(t, "invalid password", err.Error())
-	assert.Empty(t, token)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Empty(t, tokens.AccessToken)
 }
 
 func TestAuthService_GenerateToken_UserNotFound(t *testing.T) {
 	mockRepo := new(MockAuthRepo)
-	service := NewAuthService(mockRepo)
+	service := NewAuthService(mockRepo, nil, repository.NewRevocationMemory(), testHasher, testPolicy)
 
 	mockRepo.On("GetUser", "ghost").
-		Return(models.User{}, errors.New("user not found"))
+		Return(models.User{}, fmt.Errorf("user %w", repository.ErrNotFound))
 
-	token, err := service.GenerateToken("ghost", "123")
+	tokens, err := service.GenerateToken(context.Background(), "ghost", "123")
 
-	assert.Error(t, err)
-	assert.Empty(t, token)
+	assert.ErrorIs(t, err, ErrInvalidCredentials)
+	assert.Empty(t, tokens.AccessToken)
+
+	// Unknown usernames pay for a real comparison.
+	ok, err := testHasher.Verify(service.dummyHash, "123")
+	assert.NoError(t, err)
+	assert.False(t, ok)
+}
+
+func TestAuthService_GenerateToken_UpgradesHash(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := new(MockAuthRepo)
+	mockTokens := mock_repository.NewMockRefreshToken(ctrl)
+	argon, err := password.New(password.Config{
+		Algorithm: password.AlgorithmArgon2id,
+		Bcrypt:    password.DefaultBcryptParams(),
+		Argon2id:  password.Argon2idParams{Memory: 64, Iterations: 1, Parallelism: 1, SaltLength: 16, KeyLength: 32},
+	})
+	assert.NoError(t, err)
+	service := NewAuthService(mockRepo, mockTokens, repository.NewRevocationMemory(), argon, testPolicy)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	mockRepo.On("GetUser", "user").Return(models.User{ID: 10, Username: "user", Password: string(hashed)}, nil)
+	mockRepo.On("UpdatePassword", uint(10), mock.MatchedBy(func(hash string) bool {
+		ok, err := argon.Verify(hash, "password123")
+		return ok && err == nil && !argon.NeedsRehash(hash)
+	})).Return(nil)
+	mockTokens.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+
+	_, err = service.GenerateToken(context.Background(), "user", "password123")
+
+	assert.NoError(t, err)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_GenerateToken_UpgradeFailureDoesNotFailSignIn(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := new(MockAuthRepo)
+	mockTokens := mock_repository.NewMockRefreshToken(ctrl)
+	service := NewAuthService(mockRepo, mockTokens, repository.NewRevocationMemory(), testHasher, testPolicy)
+
+	hashed, _ := bcrypt.GenerateFromPassword([]byte("password123"), bcrypt.MinCost)
+	mockRepo.On("GetUser", "user").Return(models.User{ID: 10, Username: "user", Password: string(hashed)}, nil)
+	mockRepo.On("UpdatePassword", uint(10), mock.Anything).Return(errors.New("database is down"))
+	mockTokens.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+
+	tokens, err := service.GenerateToken(context.Background(), "user", "password123")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	mockRepo.AssertExpectations(t)
+}
+
+func TestAuthService_GenerateToken_UnknownHash(t *testing.T) {
+	mockRepo := new(MockAuthRepo)
+	service := NewAuthService(mockRepo, nil, repository.NewRevocationMemory(), testHasher, testPolicy)
+
+	mockRepo.On("GetUser", "user").Return(models.User{ID: 10, Username: "user", Password: "plaintext"}, nil)
+
+	_, err := service.GenerateToken(context.Background(), "user", "plaintext")
+
+	assert.ErrorIs(t, err, password.ErrUnknownHash)
+	assert.NotErrorIs(t, err, ErrInvalidCredentials)
 }
 
 func TestAuthService_ParseToken_Success(t *testing.T) {
 	mockRepo := new(MockAuthRepo)
-	service := NewAuthService(mockRepo)
+	service := NewAuthService(mockRepo, nil, repository.NewRevocationMemory(), testHasher, testPolicy)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": float64(42),
@@ -118,15 +265,16 @@ func TestAuthService_ParseToken_Success(t *testing.T) {
 
 	tokenStr, _ := token.SignedString([]byte("TEST_SECRET_KEY"))
 
-	id, err := service.ParseToken(tokenStr)
+	principal, err := service.ParseToken(context.Background(), tokenStr)
 
 	assert.NoError(t, err)
-	assert.Equal(t, uint(42), id)
+	assert.Equal(t, uint(42), principal.UserID)
+	assert.Equal(t, models.RoleUser, principal.Role)
 }
 
 func TestAuthService_ParseToken_InvalidSignature(t *testing.T) {
 	mockRepo := new(MockAuthRepo)
-	service := NewAuthService(mockRepo)
+	service := NewAuthService(mockRepo, nil, repository.NewRevocationMemory(), testHasher, testPolicy)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"user_id": float64(1),
@@ -134,7 +282,7 @@ func TestAuthService_ParseToken_InvalidSignature(t *testing.T) {
 
 	tokenStr, _ := token.SignedString([]byte("WRONG_KEY"))
 
-	_, err := service.ParseToken(tokenStr)
+	_, err := service.ParseToken(context.Background(), tokenStr)
 
 	assert.Error(t, err)
 	assert.Equal(t, "invalid token", err.Error())
@@ -142,7 +290,7 @@ func TestAuthService_ParseToken_InvalidSignature(t *testing.T) {
 
 func TestAuthService_ParseToken_NoUserID(t *testing.T) {
 	mockRepo := new(MockAuthRepo)
-	service := NewAuthService(mockRepo)
+	service := NewAuthService(mockRepo, nil, repository.NewRevocationMemory(), testHasher, testPolicy)
 
 	token := jwt.NewWithClaims(jwt.SigningMethodHS256, jwt.MapClaims{
 		"exp": float64(time.Now().Add(time.Hour).Unix()),
@@ -150,8 +298,162 @@ func TestAuthService_ParseToken_NoUserID(t *testing.T) {
 
 	tokenStr, _ := token.SignedString([]byte("TEST_SECRET_KEY"))
 
-	_, err := service.ParseToken(tokenStr)
+	_, err := service.ParseToken(context.Background(), tokenStr)
+
+	assert.ErrorIs(t, err, ErrInvalidToken)
+	assert.ErrorContains(t, err, "user_id not found in token")
+}
+
+func TestAuthService_RefreshToken_Rotates(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockRepo := new(MockAuthRepo)
+	mockTokens := mock_repository.NewMockRefreshToken(ctrl)
+	service := NewAuthService(mockRepo, mockTokens, repository.NewRevocationMemory(), testHasher, testPolicy)
+
+	mockRepo.On("GetUserById", uint(10)).Return(models.User{ID: 10, Role: models.RoleAdmin}, nil)
+
+	stored := models.RefreshToken{
+		ID:        3,
+		UserID:    10,
+		FamilyID:  "family",
+		TokenHash: hashToken("old-token"),
+		ExpiresAt: time.Now().Add(time.Hour),
+	}
+
+	mockTokens.EXPECT().GetRefreshToken(gomock.Any(), hashToken("old-token")).Return(stored, nil)
+	mockTokens.EXPECT().MarkRefreshTokenUsed(gomock.Any(), uint(3)).Return(true, nil)
+	mockTokens.EXPECT().
+		CreateRefreshToken(gomock.Any(), gomock.Any()).
+		DoAndReturn(func(_ context.Context, token models.RefreshToken) error {
+			assert.Equal(t, "family", token.FamilyID)
+			assert.Equal(t, uint(10), token.UserID)
+			return nil
+		})
+
+	tokens, err := service.RefreshToken(context.Background(), "old-token")
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, tokens.AccessToken)
+	assert.NotEqual(t, "old-token", tokens.RefreshToken)
+
+	principal, err := service.ParseToken(context.Background(), tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, models.Principal{UserID: 10, Role: models.RoleAdmin}, principal)
+}
+
+func TestAuthService_RefreshToken_ReuseRevokesFamily(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokens := mock_repository.NewMockRefreshToken(ctrl)
+	service := NewAuthService(new(MockAuthRepo), mockTokens, repository.NewRevocationMemory(), testHasher, testPolicy)
+
+	usedAt := time.Now().Add(-time.Minute)
+	mockTokens.EXPECT().GetRefreshToken(gomock.Any(), hashToken("replayed")).Return(models.RefreshToken{
+		ID:        3,
+		FamilyID:  "family",
+		ExpiresAt: time.Now().Add(time.Hour),
+		UsedAt:    &usedAt,
+	}, nil)
+	mockTokens.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), "family").Return(nil)
+
+	_, err := service.RefreshToken(context.Background(), "replayed")
+
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+}
+
+func TestAuthService_RefreshToken_ConcurrentReuse(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokens := mock_repository.NewMockRefreshToken(ctrl)
+	service := NewAuthService(new(MockAuthRepo), mockTokens, repository.NewRevocationMemory(), testHasher, testPolicy)
+
+	mockTokens.EXPECT().GetRefreshToken(gomock.Any(), gomock.Any()).Return(models.RefreshToken{
+		ID:        3,
+		FamilyID:  "family",
+		ExpiresAt: time.Now().Add(time.Hour),
+	}, nil)
+	mockTokens.EXPECT().MarkRefreshTokenUsed(gomock.Any(), uint(3)).Return(false, nil)
+	mockTokens.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), "family").Return(nil)
+
+	_, err := service.RefreshToken(context.Background(), "raced")
+
+	assert.ErrorIs(t, err, ErrRefreshTokenReused)
+}
+
+func TestAuthService_RefreshToken_Invalid(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokens := mock_repository.NewMockRefreshToken(ctrl)
+	service := NewAuthService(new(MockAuthRepo), mockTokens, repository.NewRevocationMemory(), testHasher, testPolicy)
+
+	revokedAt := time.Now()
+	mockTokens.EXPECT().GetRefreshToken(gomock.Any(), hashToken("unknown")).
+		Return(models.RefreshToken{}, repository.ErrRefreshTokenNotFound)
+	mockTokens.EXPECT().GetRefreshToken(gomock.Any(), hashToken("expired")).
+		Return(models.RefreshToken{ExpiresAt: time.Now().Add(-time.Hour)}, nil)
+	mockTokens.EXPECT().GetRefreshToken(gomock.Any(), hashToken("revoked")).
+		Return(models.RefreshToken{ExpiresAt: time.Now().Add(time.Hour), RevokedAt: &revokedAt}, nil)
+
+	for _, token := range []string{"unknown", "expired", "revoked"} {
+		_, err := service.RefreshToken(context.Background(), token)
+		assert.ErrorIs(t, err, ErrInvalidRefreshToken, token)
+	}
+}
+
+func TestAuthService_SignOut_RevokesAccessToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokens := mock_repository.NewMockRefreshToken(ctrl)
+	revoked := repository.NewRevocationMemory()
+	service := NewAuthService(new(MockAuthRepo), mockTokens, revoked, testHasher, testPolicy)
+
+	mockTokens.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+	tokens, err := service.issueTokens(context.Background(), models.User{ID: 7, Role: models.RoleUser}, "family")
+	assert.NoError(t, err)
+
+	principal, err := service.ParseToken(context.Background(), tokens.AccessToken)
+	assert.NoError(t, err)
+	assert.Equal(t, uint(7), principal.UserID)
+
+	mockTokens.EXPECT().GetRefreshToken(gomock.Any(), hashToken(tokens.RefreshToken)).
+		Return(models.RefreshToken{UserID: 7, FamilyID: "family"}, nil)
+	mockTokens.EXPECT().RevokeRefreshTokenFamily(gomock.Any(), "family").Return(nil)
+
+	assert.NoError(t, service.SignOut(context.Background(), tokens.AccessToken, tokens.RefreshToken))
+
+	_, err = service.ParseToken(context.Background(), tokens.AccessToken)
+	assert.ErrorIs(t, err, ErrTokenRevoked)
+
+	pruned, _ := revoked.PruneExpired(context.Background(), time.Now())
+	assert.Zero(t, pruned, "revocation must outlive the token it blocks")
+}
+
+func TestAuthService_SignOut_IgnoresForeignRefreshToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockTokens := mock_repository.NewMockRefreshToken(ctrl)
+	service := NewAuthService(new(MockAuthRepo), mockTokens, repository.NewRevocationMemory(), testHasher, testPolicy)
+
+	mockTokens.EXPECT().CreateRefreshToken(gomock.Any(), gomock.Any()).Return(nil)
+	tokens, err := service.issueTokens(context.Background(), models.User{ID: 7}, "family")
+	assert.NoError(t, err)
+
+	mockTokens.EXPECT().GetRefreshToken(gomock.Any(), hashToken("someone-elses")).
+		Return(models.RefreshToken{UserID: 8, FamilyID: "other"}, nil)
+
+	assert.NoError(t, service.SignOut(context.Background(), tokens.AccessToken, "someone-elses"))
+}
+
+func TestAuthService_SignOut_InvalidToken(t *testing.T) {
+	service := NewAuthService(new(MockAuthRepo), nil, repository.NewRevocationMemory(), testHasher, testPolicy)
 
+	err := service.SignOut(context.Background(), "garbage", "")
 	assert.Error(t, err)
-	assert.Equal(t, "user_id not found in token", err.Error())
 }
@@ -1,34 +1,205 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"grpc/server/models"
+	"grpc/server/pkg/events"
 	"grpc/server/pkg/repository"
+	"io"
+	"strings"
+	"sync"
+)
+
+const (
+	defaultStreamBatchSize = 500
+	maxStreamBatchSize     = 5000
+
+	// eventHistorySize is how many recent changes WatchBooks clients can
+	// resume from.
+	eventHistorySize = 1000
 )
 
 type BookService struct {
-	repo repository.Book
+	repo   repository.Book
+	events *events.Bus
+	// writeMu is held across every write and the publishing of its events,
+	// so that revisions follow the order in which the writes were committed
+	// and a watcher resuming from a revision misses nothing. It serializes
+	// book writes within the process.
+	writeMu sync.Mutex
 }
 
 func NewBookService(repo repository.Book) *BookService {
-	return &BookService{repo: repo}
+	return &BookService{
+		repo:   repo,
+		events: events.NewBus(eventHistorySize),
+	}
 }
 
-func (s *BookService) Create(book models.Book) (uint, error) {
-	return s.repo.Create(book)
+func (s *BookService) Create(ctx context.Context, book models.Book) (uint, error) {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	id, err := s.repo.Create(ctx, book)
+	if err != nil {
+		return 0, err
+	}
+	book.ID = id
+	s.events.Publish(events.BookCreated, book)
+	return id, nil
 }
 
-func (s *BookService) GetAll() ([]models.Book, error) {
-	return s.repo.GetAll()
+// Import inserts the already validated batches returned by next until it
+// returns io.EOF, each in its own transaction as soon as it arrives, and
+// reports rows that could not be created, keeping their position in the
+// original import. Batches committed before next or a later batch fails are
+// kept.
+func (s *BookService) Import(ctx context.Context, next func() ([]models.ImportRow, error)) (models.ImportSummary, error) {
+	var summary models.ImportSummary
+	for {
+		rows, err := next()
+		if err == io.EOF {
+			return summary, nil
+		}
+		if err != nil {
+			return models.ImportSummary{}, err
+		}
+
+		if err := s.importBatch(ctx, rows, &summary); err != nil {
+			return models.ImportSummary{}, err
+		}
+	}
 }
 
-func (s *BookService) GetById(bookId uint) (models.Book, error) {
-	return s.repo.GetById(bookId)
+// importBatch writes one batch of Import and adds its results to summary.
+func (s *BookService) importBatch(ctx context.Context, rows []models.ImportRow, summary *models.ImportSummary) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	books := make([]models.Book, len(rows))
+	for i, row := range rows {
+		books[i] = row.Book
+	}
+	results, err := s.repo.CreateBatch(ctx, books)
+	if err != nil {
+		return err
+	}
+
+	for i, res := range results {
+		if res.Err != nil {
+			importErr := models.ImportError{Row: rows[i].Row, Message: res.Err.Error()}
+			if errors.Is(res.Err, repository.ErrDuplicateTitle) {
+				importErr.Field = "title"
+			}
+			summary.Errors = append(summary.Errors, importErr)
+			continue
+		}
+		summary.CreatedIDs = append(summary.CreatedIDs, res.ID)
+		books[i].ID = res.ID
+		s.events.Publish(events.BookCreated, books[i])
+	}
+	return nil
 }
 
-func (s *BookService) Delete(userid, bookId uint) error {
-	return s.repo.Delete(userid, bookId)
+func (s *BookService) List(ctx context.Context, input models.ListBooksInput) (models.BookPage, error) {
+	orderBy := input.OrderBy
+	if orderBy == "" {
+		orderBy = defaultOrderBy
+	}
+	pageSize := input.PageSize
+	if pageSize <= 0 {
+		pageSize = defaultPageSize
+	}
+
+	query := models.BookQuery{
+		BookFilter: input.BookFilter,
+		OrderBy:    strings.TrimSuffix(orderBy, " desc"),
+		Desc:       strings.HasSuffix(orderBy, " desc"),
+		Limit:      pageSize + 1,
+		Count:      input.PageToken == "",
+	}
+	var token pageToken
+	if input.PageToken != "" {
+		var err error
+		if token, err = decodePageToken(orderBy, input.BookFilter, input.PageToken); err != nil {
+			return models.BookPage{}, err
+		}
+		query.After = &token.BookCursor
+	}
+
+	books, total, err := s.repo.List(ctx, query)
+	if err != nil {
+		return models.BookPage{}, err
+	}
+	// Only the first page is counted; later pages repeat its total.
+	if !query.Count {
+		total = token.Total
+	}
+
+	page := models.BookPage{Books: books, TotalSize: total}
+	if len(books) > pageSize {
+		page.Books = books[:pageSize]
+		page.NextPageToken = encodePageToken(orderBy, input.BookFilter, total, page.Books[pageSize-1])
+	}
+	return page, nil
+}
+
+// Stream walks every book matching filter in id order, reading batchSize rows
+// at a time, and stops at the first error returned by fn.
+func (s *BookService) Stream(ctx context.Context, filter models.BookFilter, batchSize int, fn func(models.Book) error) error {
+	if batchSize <= 0 || batchSize > maxStreamBatchSize {
+		batchSize = defaultStreamBatchSize
+	}
+
+	var afterId uint
+	for {
+		books, err := s.repo.GetBatch(ctx, filter, afterId, batchSize)
+		if err != nil {
+			return err
+		}
+		for _, book := range books {
+			if err := fn(book); err != nil {
+				return err
+			}
+		}
+		if len(books) < batchSize {
+			return nil
+		}
+		afterId = books[len(books)-1].ID
+	}
+}
+
+func (s *BookService) GetById(ctx context.Context, bookId uint) (models.Book, error) {
+	return s.repo.GetById(ctx, bookId)
+}
+
+func (s *BookService) Delete(ctx context.Context, principal models.Principal, bookId uint) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	book, err := s.repo.Delete(ctx, principal, bookId)
+	if err != nil {
+		return err
+	}
+	s.events.Publish(events.BookDeleted, book)
+	return nil
+}
+
+func (s *BookService) Update(ctx context.Context, principal models.Principal, bookId uint, input models.UpdateBook) error {
+	s.writeMu.Lock()
+	defer s.writeMu.Unlock()
+
+	book, err := s.repo.Update(ctx, principal, bookId, input)
+	if err != nil {
+		return err
+	}
+	s.events.Publish(events.BookUpdated, book)
+	return nil
 }
 
-func (s *BookService) Update(userId, bookId uint, book models.UpdateBook) error {
-	return s.repo.Update(userId, bookId, book)
+// Watch subscribes to book changes made through this service after
+// fromRevision; the caller must Close the subscription.
+func (s *BookService) Watch(fromRevision uint64) (*events.Subscription, error) {
+	return s.events.Subscribe(fromRevision)
 }
@@ -1,8 +1,14 @@
 package service
 
 import (
+	"context"
+	"errors"
 	"grpc/server/models"
+	"grpc/server/pkg/events"
+	"grpc/server/pkg/repository"
 	mock_repository "grpc/server/pkg/repository/mocks"
+	"io"
+	"time"
 
 	"testing"
 
@@ -19,14 +25,14 @@ func TestBookService_Create(t *testing.T) {
 
 	book := models.Book{Title: "Test Book"}
 
-	mockBook.EXPECT().Create(book).Return(uint(1), nil)
+	mockBook.EXPECT().Create(gomock.Any(), book).Return(uint(1), nil)
 
-	id, err := service.Create(book)
+	id, err := service.Create(context.Background(), book)
 	assert.NoError(t, err)
 	assert.Equal(t, uint(1), id)
 }
 
-func TestBookService_GetAll(t *testing.T) {
+func TestBookService_List(t *testing.T) {
 	ctrl := gomock.NewController(t)
 	defer ctrl.Finish()
 
@@ -38,11 +44,84 @@ func TestBookService_GetAll(t *testing.T) {
 		{ID: 2, Title: "Book2"},
 	}
 
-	mockBook.EXPECT().GetAll().Return(books, nil)
+	mockBook.EXPECT().
+		List(gomock.Any(), models.BookQuery{OrderBy: "id", Limit: defaultPageSize + 1, Count: true}).
+		Return(books, int64(2), nil)
 
-	result, err := service.GetAll()
+	result, err := service.List(context.Background(), models.ListBooksInput{})
 	assert.NoError(t, err)
-	assert.Equal(t, books, result)
+	assert.Equal(t, books, result.Books)
+	assert.Equal(t, int64(2), result.TotalSize)
+	assert.Empty(t, result.NextPageToken)
+}
+
+func TestBookService_List_NextPage(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_repository.NewMockBook(ctrl)
+	service := NewBookService(mockBook)
+
+	input := models.ListBooksInput{
+		BookFilter: models.BookFilter{Author: "Rob Pike"},
+		PageSize:   2,
+		OrderBy:    "title desc",
+	}
+
+	mockBook.EXPECT().
+		List(gomock.Any(), models.BookQuery{
+			BookFilter: input.BookFilter,
+			OrderBy:    "title",
+			Desc:       true,
+			Limit:      3,
+			Count:      true,
+		}).
+		Return([]models.Book{{ID: 7, Title: "C"}, {ID: 3, Title: "B"}, {ID: 9, Title: "A"}}, int64(5), nil)
+
+	first, err := service.List(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Len(t, first.Books, 2)
+	assert.NotEmpty(t, first.NextPageToken)
+
+	mockBook.EXPECT().
+		List(gomock.Any(), models.BookQuery{
+			BookFilter: input.BookFilter,
+			OrderBy:    "title",
+			Desc:       true,
+			After:      &models.BookCursor{ID: 3, Title: "B"},
+			Limit:      3,
+		}).
+		Return([]models.Book{{ID: 9, Title: "A"}}, int64(0), nil)
+
+	input.PageToken = first.NextPageToken
+	second, err := service.List(context.Background(), input)
+	assert.NoError(t, err)
+	assert.Len(t, second.Books, 1)
+	assert.Equal(t, int64(5), second.TotalSize)
+	assert.Empty(t, second.NextPageToken)
+}
+
+func TestBookService_List_InvalidPageToken(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_repository.NewMockBook(ctrl)
+	service := NewBookService(mockBook)
+
+	_, err := service.List(context.Background(), models.ListBooksInput{PageToken: "not-a-token"})
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+
+	token := encodePageToken("title", models.BookFilter{}, 1, models.Book{ID: 1, Title: "Go"})
+	_, err = service.List(context.Background(), models.ListBooksInput{PageToken: token, OrderBy: "author"})
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
+
+	token = encodePageToken("title", models.BookFilter{Author: "Pike"}, 1, models.Book{ID: 1, Title: "Go"})
+	_, err = service.List(context.Background(), models.ListBooksInput{
+		BookFilter: models.BookFilter{Author: "Kernighan"},
+		PageToken:  token,
+		OrderBy:    "title",
+	})
+	assert.ErrorIs(t, err, ErrInvalidPageToken)
 }
 
 func TestBookService_GetById(t *testing.T) {
@@ -54,9 +133,9 @@ func TestBookService_GetById(t *testing.T) {
 
 	book := models.Book{ID: 1, Title: "Book1"}
 
-	mockBook.EXPECT().GetById(uint(1)).Return(book, nil)
+	mockBook.EXPECT().GetById(gomock.Any(), uint(1)).Return(book, nil)
 
-	result, err := service.GetById(1)
+	result, err := service.GetById(context.Background(), 1)
 	assert.NoError(t, err)
 	assert.Equal(t, book, result)
 }
@@ -68,9 +147,11 @@ func TestBookService_Delete(t *testing.T) {
 	mockBook := mock_repository.NewMockBook(ctrl)
 	service := NewBookService(mockBook)
 
-	mockBook.EXPECT().Delete(uint(1), uint(2)).Return(nil)
+	owner := models.Principal{UserID: 1, Role: models.RoleUser}
+
+	mockBook.EXPECT().Delete(gomock.Any(), owner, uint(2)).Return(models.Book{ID: 2, UserId: 1}, nil)
 
-	err := service.Delete(1, 2)
+	err := service.Delete(context.Background(), owner, 2)
 	assert.NoError(t, err)
 }
 
@@ -84,8 +165,252 @@ func TestBookService_Update(t *testing.T) {
 	title := "Updated"
 	update := models.UpdateBook{Title: &title}
 
-	mockBook.EXPECT().Update(uint(1), uint(2), update).Return(nil)
+	owner := models.Principal{UserID: 1, Role: models.RoleUser}
+
+	mockBook.EXPECT().Update(gomock.Any(), owner, uint(2), update).Return(models.Book{ID: 2, Title: title, UserId: 1}, nil)
+
+	err := service.Update(context.Background(), owner, 2, update)
+	assert.NoError(t, err)
+}
+
+func TestBookService_Stream(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_repository.NewMockBook(ctrl)
+	service := NewBookService(mockBook)
+
+	filter := models.BookFilter{UserId: 3}
+
+	gomock.InOrder(
+		mockBook.EXPECT().GetBatch(gomock.Any(), filter, uint(0), 2).
+			Return([]models.Book{{ID: 1}, {ID: 4}}, nil),
+		mockBook.EXPECT().GetBatch(gomock.Any(), filter, uint(4), 2).
+			Return([]models.Book{{ID: 9}}, nil),
+	)
+
+	var ids []uint
+	err := service.Stream(context.Background(), filter, 2, func(book models.Book) error {
+		ids = append(ids, book.ID)
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{1, 4, 9}, ids)
+}
+
+func TestBookService_Stream_StopsOnCallbackError(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_repository.NewMockBook(ctrl)
+	service := NewBookService(mockBook)
+
+	mockBook.EXPECT().GetBatch(gomock.Any(), models.BookFilter{}, uint(0), defaultStreamBatchSize).
+		Return([]models.Book{{ID: 1}, {ID: 2}}, nil)
+
+	stop := errors.New("client went away")
+	calls := 0
+	err := service.Stream(context.Background(), models.BookFilter{}, 0, func(book models.Book) error {
+		calls++
+		return stop
+	})
+	assert.ErrorIs(t, err, stop)
+	assert.Equal(t, 1, calls)
+}
+
+func TestBookService_Import(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_repository.NewMockBook(ctrl)
+	service := NewBookService(mockBook)
+
+	sub, err := service.Watch(0)
+	assert.NoError(t, err)
+	defer sub.Close()
+
+	rows := []models.ImportRow{
+		{Row: 1, Book: models.Book{Title: "First", UserId: 1}},
+		{Row: 3, Book: models.Book{Title: "First", UserId: 1}},
+		{Row: 4, Book: models.Book{Title: "Second", UserId: 1}},
+	}
+
+	next := batches(rows[:2], rows[2:])
+	read := 0
+	mockBook.EXPECT().
+		CreateBatch(gomock.Any(), []models.Book{rows[0].Book, rows[1].Book}).
+		DoAndReturn(func(context.Context, []models.Book) ([]models.BatchResult, error) {
+			assert.Equal(t, 1, read, "a batch must be written before the next one is read")
+			return []models.BatchResult{
+				{ID: 10},
+				{Err: repository.ErrDuplicateTitle},
+			}, nil
+		})
+	mockBook.EXPECT().
+		CreateBatch(gomock.Any(), []models.Book{rows[2].Book}).
+		Return([]models.BatchResult{{ID: 11}}, nil)
+
+	summary, err := service.Import(context.Background(), func() ([]models.ImportRow, error) {
+		read++
+		return next()
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{10, 11}, summary.CreatedIDs)
+	assert.Equal(t, []models.ImportError{
+		{Row: 3, Field: "title", Message: repository.ErrDuplicateTitle.Error()},
+	}, summary.Errors)
+
+	first := <-sub.Events()
+	assert.Equal(t, events.BookCreated, first.Type)
+	assert.Equal(t, uint(10), first.Book.ID)
+	second := <-sub.Events()
+	assert.Equal(t, uint(11), second.Book.ID)
+}
+
+func TestBookService_Import_StreamErrorKeepsCommittedBatches(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_repository.NewMockBook(ctrl)
+	service := NewBookService(mockBook)
+
+	sub, err := service.Watch(0)
+	assert.NoError(t, err)
+	defer sub.Close()
+
+	broken := errors.New("stream broken")
+	mockBook.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).Return([]models.BatchResult{{ID: 10}}, nil)
+
+	sent := false
+	_, err = service.Import(context.Background(), func() ([]models.ImportRow, error) {
+		if sent {
+			return nil, broken
+		}
+		sent = true
+		return []models.ImportRow{{Row: 1, Book: models.Book{Title: "First"}}}, nil
+	})
+	assert.ErrorIs(t, err, broken)
+
+	event := <-sub.Events()
+	assert.Equal(t, uint(10), event.Book.ID)
+}
+
+func TestBookService_Import_Error(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_repository.NewMockBook(ctrl)
+	service := NewBookService(mockBook)
+
+	mockBook.EXPECT().CreateBatch(gomock.Any(), gomock.Any()).Return(nil, errors.New("db down"))
+
+	_, err := service.Import(context.Background(), batches([]models.ImportRow{{Row: 1}}))
+	assert.Error(t, err)
+}
+
+func TestBookService_WritesPublishInCommitOrder(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_repository.NewMockBook(ctrl)
+	service := NewBookService(mockBook)
+
+	sub, err := service.Watch(0)
+	assert.NoError(t, err)
+	defer sub.Close()
+
+	writing, release := make(chan struct{}), make(chan struct{})
+	mockBook.EXPECT().Create(gomock.Any(), models.Book{Title: "First"}).
+		DoAndReturn(func(context.Context, models.Book) (uint, error) {
+			close(writing)
+			<-release
+			return 1, nil
+		})
+	mockBook.EXPECT().Delete(gomock.Any(), gomock.Any(), uint(2)).Return(models.Book{ID: 2}, nil)
+
+	created := make(chan struct{})
+	go func() {
+		defer close(created)
+		service.Create(context.Background(), models.Book{Title: "First"})
+	}()
+	<-writing
+
+	deleted := make(chan struct{})
+	go func() {
+		defer close(deleted)
+		service.Delete(context.Background(), models.Principal{}, 2)
+	}()
+
+	select {
+	case <-deleted:
+		t.Fatal("Delete wrote while Create was still writing")
+	case <-time.After(20 * time.Millisecond):
+	}
+	close(release)
+	<-created
+	<-deleted
+
+	first := <-sub.Events()
+	assert.Equal(t, events.BookCreated, first.Type)
+	second := <-sub.Events()
+	assert.Equal(t, events.BookDeleted, second.Type)
+	assert.Greater(t, second.Revision, first.Revision)
+}
+
+// batches returns a next func for Import that yields each batch in turn.
+func batches(rows ...[]models.ImportRow) func() ([]models.ImportRow, error) {
+	return func() ([]models.ImportRow, error) {
+		if len(rows) == 0 {
+			return nil, io.EOF
+		}
+		batch := rows[0]
+		rows = rows[1:]
+		return batch, nil
+	}
+}
+
+func TestBookService_Watch(t *testing.T) {
+	ctrl := gomock.NewController(t)
+	defer ctrl.Finish()
+
+	mockBook := mock_repository.NewMockBook(ctrl)
+	service := NewBookService(mockBook)
+
+	sub, err := service.Watch(0)
+	assert.NoError(t, err)
+	defer sub.Close()
+
+	title := "Updated"
+	update := models.UpdateBook{Title: &title}
+
+	owner := models.Principal{UserID: 1}
+	stranger := models.Principal{UserID: 2}
+
+	mockBook.EXPECT().Create(gomock.Any(), models.Book{Title: "New", UserId: 1}).Return(uint(5), nil)
+	mockBook.EXPECT().Update(gomock.Any(), owner, uint(5), update).Return(models.Book{ID: 5, Title: title, UserId: 1}, nil)
+	mockBook.EXPECT().Update(gomock.Any(), stranger, uint(5), update).Return(models.Book{}, errors.New("forbidden"))
+	mockBook.EXPECT().Delete(gomock.Any(), owner, uint(5)).Return(models.Book{ID: 5, Title: title, UserId: 1}, nil)
+
+	_, err = service.Create(context.Background(), models.Book{Title: "New", UserId: 1})
+	assert.NoError(t, err)
+	assert.NoError(t, service.Update(context.Background(), owner, 5, update))
+	assert.Error(t, service.Update(context.Background(), stranger, 5, update))
+	assert.NoError(t, service.Delete(context.Background(), owner, 5))
+
+	created := <-sub.Events()
+	assert.Equal(t, events.BookCreated, created.Type)
+	assert.Equal(t, uint(5), created.Book.ID)
+
+	updated := <-sub.Events()
+	assert.Equal(t, events.BookUpdated, updated.Type)
+	assert.Equal(t, title, updated.Book.Title)
+
+	deleted := <-sub.Events()
+	assert.Equal(t, events.BookDeleted, deleted.Type)
+	assert.Equal(t, uint64(3), deleted.Revision)
 
-	err := service.Update(1, 2, update)
+	resumed, err := service.Watch(1)
 	assert.NoError(t, err)
+	defer resumed.Close()
+	assert.Equal(t, uint64(2), (<-resumed.Events()).Revision)
 }
@@ -0,0 +1,17 @@
+package service
+
+import (
+	"errors"
+	"grpc/server/pkg/password"
+	"grpc/server/pkg/repository"
+)
+
+// Domain errors surfaced by the services. The handler layer translates them
+// into gRPC status codes.
+var (
+	ErrNotFound           = repository.ErrNotFound
+	ErrPermissionDenied   = repository.ErrPermissionDenied
+	ErrAlreadyExists      = repository.ErrAlreadyExists
+	ErrInvalidCredentials = errors.New("invalid username or password")
+	ErrWeakPassword       = password.ErrWeakPassword
+)
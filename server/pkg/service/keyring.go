@@ -0,0 +1,182 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"grpc/server/models"
+	"math/big"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"github.com/golang-jwt/jwt/v5"
+)
+
+type keyEntry struct {
+	kid     string
+	method  jwt.SigningMethod
+	private interface{}
+	public  interface{}
+}
+
+// KeyRing signs access tokens with a single active key and verifies them
+// against every loaded key, selected by the token's kid header. Keeping the
+// previous keys in the ring lets tokens issued before a rotation stay valid.
+type KeyRing struct {
+	active *keyEntry
+	keys   map[string]*keyEntry
+}
+
+// NewHMACKeyRing returns a ring with a single HS256 shared secret. Tokens
+// carry no kid and the ring publishes no JWKS keys.
+func NewHMACKeyRing(secret []byte) *KeyRing {
+	entry := &keyEntry{method: jwt.SigningMethodHS256, private: secret, public: secret}
+	return &KeyRing{active: entry, keys: map[string]*keyEntry{"": entry}}
+}
+
+// LoadKeyRing reads every *.pem file in dir, using the file name without the
+// extension as kid. RSA keys sign with RS256 and Ed25519 keys with EdDSA;
+// public-only keys are used for verification. activeKid selects the signing
+// key, by default the private key with the greatest kid, so naming files by
+// date rotates to the newest key.
+func LoadKeyRing(dir, activeKid string) (*KeyRing, error) {
+	files, err := filepath.Glob(filepath.Join(dir, "*.pem"))
+	if err != nil {
+		return nil, err
+	}
+
+	ring := &KeyRing{keys: make(map[string]*keyEntry)}
+	for _, file := range files {
+		kid := strings.TrimSuffix(filepath.Base(file), ".pem")
+
+		data, err := os.ReadFile(file)
+		if err != nil {
+			return nil, err
+		}
+		entry, err := parsePEMKey(kid, data)
+		if err != nil {
+			return nil, fmt.Errorf("loading key %s: %w", file, err)
+		}
+		ring.keys[kid] = entry
+	}
+
+	if activeKid == "" {
+		var kids []string
+		for kid, entry := range ring.keys {
+			if entry.private != nil {
+				kids = append(kids, kid)
+			}
+		}
+		sort.Strings(kids)
+		if len(kids) == 0 {
+			return nil, fmt.Errorf("no private signing key found in %s", dir)
+		}
+		activeKid = kids[len(kids)-1]
+	}
+
+	active, ok := ring.keys[activeKid]
+	if !ok || active.private == nil {
+		return nil, fmt.Errorf("signing key %q not found in %s", activeKid, dir)
+	}
+	ring.active = active
+	return ring, nil
+}
+
+func parsePEMKey(kid string, data []byte) (*keyEntry, error) {
+	block, _ := pem.Decode(data)
+	if block == nil {
+		return nil, errors.New("no PEM block found")
+	}
+
+	var key interface{}
+	var err error
+	switch block.Type {
+	case "PRIVATE KEY":
+		key, err = x509.ParsePKCS8PrivateKey(block.Bytes)
+	case "RSA PRIVATE KEY":
+		key, err = x509.ParsePKCS1PrivateKey(block.Bytes)
+	case "PUBLIC KEY":
+		key, err = x509.ParsePKIXPublicKey(block.Bytes)
+	case "RSA PUBLIC KEY":
+		key, err = x509.ParsePKCS1PublicKey(block.Bytes)
+	default:
+		return nil, fmt.Errorf("unsupported PEM block %q", block.Type)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	entry := &keyEntry{kid: kid}
+	switch k := key.(type) {
+	case *rsa.PrivateKey:
+		entry.method, entry.private, entry.public = jwt.SigningMethodRS256, k, &k.PublicKey
+	case *rsa.PublicKey:
+		entry.method, entry.public = jwt.SigningMethodRS256, k
+	case ed25519.PrivateKey:
+		entry.method, entry.private, entry.public = jwt.SigningMethodEdDSA, k, k.Public()
+	case ed25519.PublicKey:
+		entry.method, entry.public = jwt.SigningMethodEdDSA, k
+	default:
+		return nil, fmt.Errorf("unsupported key type %T", key)
+	}
+	return entry, nil
+}
+
+func (k *KeyRing) Sign(claims jwt.Claims) (string, error) {
+	token := jwt.NewWithClaims(k.active.method, claims)
+	if k.active.kid != "" {
+		token.Header["kid"] = k.active.kid
+	}
+	return token.SignedString(k.active.private)
+}
+
+// Keyfunc resolves the verification key for token and rejects tokens whose
+// algorithm does not match the key, so an RSA public key can never be used
+// as an HMAC secret.
+func (k *KeyRing) Keyfunc(token *jwt.Token) (interface{}, error) {
+	kid, _ := token.Header["kid"].(string)
+
+	entry, ok := k.keys[kid]
+	if !ok {
+		return nil, fmt.Errorf("unknown signing key %q", kid)
+	}
+	if token.Method.Alg() != entry.method.Alg() {
+		return nil, fmt.Errorf("unexpected signing method: %v", token.Header["alg"])
+	}
+	return entry.public, nil
+}
+
+// JWKS returns the public verification keys; HMAC secrets are never listed.
+func (k *KeyRing) JWKS() []models.JWK {
+	var keys []models.JWK
+	for _, entry := range k.keys {
+		switch pub := entry.public.(type) {
+		case *rsa.PublicKey:
+			keys = append(keys, models.JWK{
+				Kty: "RSA",
+				Kid: entry.kid,
+				Use: "sig",
+				Alg: entry.method.Alg(),
+				N:   base64.RawURLEncoding.EncodeToString(pub.N.Bytes()),
+				E:   base64.RawURLEncoding.EncodeToString(big.NewInt(int64(pub.E)).Bytes()),
+			})
+		case ed25519.PublicKey:
+			keys = append(keys, models.JWK{
+				Kty: "OKP",
+				Kid: entry.kid,
+				Use: "sig",
+				Alg: entry.method.Alg(),
+				Crv: "Ed25519",
+				X:   base64.RawURLEncoding.EncodeToString(pub),
+			})
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return keys[i].Kid < keys[j].Kid })
+	return keys
+}
@@ -0,0 +1,184 @@
+package service
+
+import (
+	"crypto/ed25519"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/pem"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func writePEM(t *testing.T, dir, name, blockType string, der []byte) {
+	t.Helper()
+	data := pem.EncodeToMemory(&pem.Block{Type: blockType, Bytes: der})
+	require.NoError(t, os.WriteFile(filepath.Join(dir, name), data, 0o600))
+}
+
+func writeRSAKey(t *testing.T, dir, name string) *rsa.PrivateKey {
+	t.Helper()
+	key, err := rsa.GenerateKey(rand.Reader, 2048)
+	require.NoError(t, err)
+	writePEM(t, dir, name, "RSA PRIVATE KEY", x509.MarshalPKCS1PrivateKey(key))
+	return key
+}
+
+func writeEd25519Key(t *testing.T, dir, name string) ed25519.PrivateKey {
+	t.Helper()
+	_, key, err := ed25519.GenerateKey(rand.Reader)
+	require.NoError(t, err)
+	der, err := x509.MarshalPKCS8PrivateKey(key)
+	require.NoError(t, err)
+	writePEM(t, dir, name, "PRIVATE KEY", der)
+	return key
+}
+
+func testClaims(userId uint) tokenClaims {
+	return tokenClaims{
+		RegisteredClaims: jwt.RegisteredClaims{
+			ExpiresAt: jwt.NewNumericDate(time.Now().Add(time.Hour)),
+		},
+		UserID: userId,
+	}
+}
+
+func parseWith(ring *KeyRing, token string) (*tokenClaims, error) {
+	claims := &tokenClaims{}
+	_, err := jwt.ParseWithClaims(token, claims, ring.Keyfunc)
+	return claims, err
+}
+
+func TestKeyRing_SignsWithNewestKeyAndVerifiesOld(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "2026-01.pem")
+
+	oldRing, err := LoadKeyRing(dir, "")
+	require.NoError(t, err)
+	oldToken, err := oldRing.Sign(testClaims(1))
+	require.NoError(t, err)
+
+	writeEd25519Key(t, dir, "2026-02.pem")
+
+	ring, err := LoadKeyRing(dir, "")
+	require.NoError(t, err)
+
+	newToken, err := ring.Sign(testClaims(2))
+	require.NoError(t, err)
+
+	parsed, _, err := jwt.NewParser().ParseUnverified(newToken, &tokenClaims{})
+	require.NoError(t, err)
+	assert.Equal(t, "2026-02", parsed.Header["kid"])
+	assert.Equal(t, "EdDSA", parsed.Header["alg"])
+
+	claims, err := parseWith(ring, oldToken)
+	require.NoError(t, err, "tokens signed before rotation must still verify")
+	assert.Equal(t, uint(1), claims.UserID)
+
+	claims, err = parseWith(ring, newToken)
+	require.NoError(t, err)
+	assert.Equal(t, uint(2), claims.UserID)
+}
+
+func TestKeyRing_ExplicitActiveKid(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "a.pem")
+	writeRSAKey(t, dir, "b.pem")
+
+	ring, err := LoadKeyRing(dir, "a")
+	require.NoError(t, err)
+	assert.Equal(t, "a", ring.active.kid)
+
+	_, err = LoadKeyRing(dir, "missing")
+	assert.Error(t, err)
+}
+
+func TestKeyRing_PublicKeyOnlyVerifies(t *testing.T) {
+	signDir, verifyDir := t.TempDir(), t.TempDir()
+	key := writeRSAKey(t, signDir, "k1.pem")
+
+	der, err := x509.MarshalPKIXPublicKey(&key.PublicKey)
+	require.NoError(t, err)
+	writePEM(t, verifyDir, "k1.pem", "PUBLIC KEY", der)
+	writeRSAKey(t, verifyDir, "k2.pem")
+
+	signer, err := LoadKeyRing(signDir, "")
+	require.NoError(t, err)
+	token, err := signer.Sign(testClaims(5))
+	require.NoError(t, err)
+
+	verifier, err := LoadKeyRing(verifyDir, "")
+	require.NoError(t, err)
+	assert.Equal(t, "k2", verifier.active.kid)
+
+	_, err = parseWith(verifier, token)
+	assert.NoError(t, err)
+
+	_, err = LoadKeyRing(verifyDir, "k1")
+	assert.Error(t, err, "a public key cannot be the signing key")
+}
+
+func TestKeyRing_RejectsUnknownKidAndAlgorithmConfusion(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "k1.pem")
+
+	ring, err := LoadKeyRing(dir, "")
+	require.NoError(t, err)
+
+	foreignDir := t.TempDir()
+	writeEd25519Key(t, foreignDir, "foreign.pem")
+
+	other, err := LoadKeyRing(foreignDir, "")
+	require.NoError(t, err)
+	foreign, err := other.Sign(testClaims(1))
+	require.NoError(t, err)
+
+	_, err = parseWith(ring, foreign)
+	assert.Error(t, err)
+
+	hmacToken := jwt.NewWithClaims(jwt.SigningMethodHS256, testClaims(1))
+	hmacToken.Header["kid"] = "k1"
+	signed, err := hmacToken.SignedString([]byte("guess"))
+	require.NoError(t, err)
+
+	_, err = parseWith(ring, signed)
+	assert.Error(t, err)
+}
+
+func TestKeyRing_JWKS(t *testing.T) {
+	dir := t.TempDir()
+	writeRSAKey(t, dir, "rsa.pem")
+	edKey := writeEd25519Key(t, dir, "ed.pem")
+
+	ring, err := LoadKeyRing(dir, "")
+	require.NoError(t, err)
+
+	keys := ring.JWKS()
+	require.Len(t, keys, 2)
+
+	assert.Equal(t, "ed", keys[0].Kid)
+	assert.Equal(t, "OKP", keys[0].Kty)
+	assert.Equal(t, "Ed25519", keys[0].Crv)
+	assert.Equal(t, "EdDSA", keys[0].Alg)
+	assert.Equal(t, base64.RawURLEncoding.EncodeToString(edKey.Public().(ed25519.PublicKey)), keys[0].X)
+
+	assert.Equal(t, "rsa", keys[1].Kid)
+	assert.Equal(t, "RSA", keys[1].Kty)
+	assert.Equal(t, "RS256", keys[1].Alg)
+	assert.Equal(t, "AQAB", keys[1].E)
+	assert.NotEmpty(t, keys[1].N)
+
+	assert.Empty(t, NewHMACKeyRing([]byte("secret")).JWKS())
+}
+
+func TestLoadKeyRing_EmptyDir(t *testing.T) {
+	_, err := LoadKeyRing(t.TempDir(), "")
+	assert.Error(t, err)
+}
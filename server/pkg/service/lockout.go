@@ -0,0 +1,230 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"grpc/server/models"
+	"grpc/server/pkg/clientip"
+	"grpc/server/pkg/repository"
+	"log/slog"
+	"time"
+)
+
+// ErrLoginLocked is matched by the LockedError returned while a username or
+// client address may not sign in.
+var ErrLoginLocked = errors.New("too many failed sign-in attempts")
+
+// LockedError reports how long a locked caller has to wait.
+type LockedError struct {
+	RetryAfter time.Duration
+}
+
+func (e *LockedError) Error() string {
+	return fmt.Sprintf("%s, try again in %s", ErrLoginLocked, e.RetryAfter.Round(time.Second))
+}
+
+func (e *LockedError) Unwrap() error {
+	return ErrLoginLocked
+}
+
+// LockoutPolicy decides when failed sign-ins lock a username or a client
+// address. Once a key reaches its threshold every further failure locks it
+// again, for twice as long as the time before. Below its threshold a
+// username is already made to wait a growing Backoff between attempts.
+type LockoutPolicy struct {
+	// MaxFailures is the threshold for a username.
+	MaxFailures int `mapstructure:"max_failures"`
+	// Backoff is the wait after a username's first failure. It doubles with
+	// each further failure below MaxFailures, up to Duration.
+	Backoff time.Duration `mapstructure:"backoff"`
+	// MaxIPFailures is the threshold for a client address, which many users
+	// may share.
+	MaxIPFailures int `mapstructure:"max_ip_failures"`
+	// Duration is the length of the first lockout, capped at MaxDuration as
+	// it doubles.
+	Duration    time.Duration `mapstructure:"duration"`
+	MaxDuration time.Duration `mapstructure:"max_duration"`
+	// ResetAfter forgets the failures of a key that had none for this long.
+	ResetAfter time.Duration `mapstructure:"reset_after"`
+}
+
+func DefaultLockoutPolicy() LockoutPolicy {
+	return LockoutPolicy{
+		MaxFailures:   5,
+		Backoff:       time.Second,
+		MaxIPFailures: 50,
+		Duration:      time.Minute,
+		MaxDuration:   time.Hour,
+		ResetAfter:    24 * time.Hour,
+	}
+}
+
+// WithDefaults fills the fields left at zero or below from
+// DefaultLockoutPolicy, giving the policy that LoginGuard enforces.
+func (p LockoutPolicy) WithDefaults() LockoutPolicy {
+	d := DefaultLockoutPolicy()
+	if p.MaxFailures <= 0 {
+		p.MaxFailures = d.MaxFailures
+	}
+	if p.Backoff <= 0 {
+		p.Backoff = d.Backoff
+	}
+	if p.MaxIPFailures <= 0 {
+		p.MaxIPFailures = d.MaxIPFailures
+	}
+	if p.Duration <= 0 {
+		p.Duration = d.Duration
+	}
+	if p.MaxDuration < p.Duration {
+		p.MaxDuration = max(d.MaxDuration, p.Duration)
+	}
+	if p.ResetAfter <= 0 {
+		p.ResetAfter = d.ResetAfter
+	}
+	return p
+}
+
+// lockoutFor returns the lockout after excess failures beyond the threshold.
+func (p LockoutPolicy) lockoutFor(excess int) time.Duration {
+	lockout := p.Duration
+	for i := 0; i < excess && lockout < p.MaxDuration; i++ {
+		lockout *= 2
+	}
+	return min(lockout, p.MaxDuration)
+}
+
+// backoffFor returns the wait after failures below the threshold.
+func (p LockoutPolicy) backoffFor(failures int) time.Duration {
+	backoff := p.Backoff
+	for i := 1; i < failures && backoff < p.Duration; i++ {
+		backoff *= 2
+	}
+	return min(backoff, p.Duration)
+}
+
+// LoginGuard wraps an Authorization service and refuses sign-ins for
+// usernames and client addresses with too many recent failures.
+type LoginGuard struct {
+	Authorization
+	users    repository.Authorization
+	attempts repository.LoginAttempts
+	policy   LockoutPolicy
+	now      func() time.Time
+}
+
+func NewLoginGuard(auth Authorization, users repository.Authorization, attempts repository.LoginAttempts, policy LockoutPolicy) *LoginGuard {
+	return &LoginGuard{
+		Authorization: auth,
+		users:         users,
+		attempts:      attempts,
+		policy:        policy.WithDefaults(),
+		now:           time.Now,
+	}
+}
+
+// GenerateToken checks the password only when neither the username nor the
+// client address is locked, so a lockout also stops guessing.
+func (g *LoginGuard) GenerateToken(ctx context.Context, username, password string) (models.TokenPair, error) {
+	now := g.now()
+	ip := clientip.FromContext(ctx)
+
+	var wait time.Duration
+	for _, t := range g.thresholds(username, ip) {
+		attempt, err := g.attempts.GetLoginAttempt(ctx, t.key)
+		if err != nil {
+			return models.TokenPair{}, err
+		}
+		if attempt.LockedUntil != nil && attempt.LockedUntil.After(now) {
+			wait = max(wait, attempt.LockedUntil.Sub(now))
+		}
+	}
+	if wait > 0 {
+		return models.TokenPair{}, &LockedError{RetryAfter: wait}
+	}
+
+	pair, err := g.Authorization.GenerateToken(ctx, username, password)
+	switch {
+	case err == nil:
+		// The address keeps its count: one valid account must not let an
+		// attacker reset the limit for guessing others.
+		if err := g.attempts.ClearLoginAttempts(ctx, userKey(username)); err != nil {
+			slog.ErrorContext(ctx, "failed to clear login attempts", "username", username, "error", err)
+		}
+	case errors.Is(err, ErrInvalidCredentials):
+		if err := g.recordFailure(ctx, now, username, ip); err != nil {
+			return models.TokenPair{}, err
+		}
+	}
+	return pair, err
+}
+
+type threshold struct {
+	key     string
+	max     int
+	backoff bool
+}
+
+// thresholds returns the keys a sign-in counts against. A client address,
+// which many users may share, gets no backoff; an unknown one is skipped
+// rather than lumping every such call into one key.
+func (g *LoginGuard) thresholds(username, ip string) []threshold {
+	thresholds := []threshold{{key: userKey(username), max: g.policy.MaxFailures, backoff: true}}
+	if ip != "" && ip != clientip.Unknown {
+		thresholds = append(thresholds, threshold{key: ipKey(ip), max: g.policy.MaxIPFailures})
+	}
+	return thresholds
+}
+
+func (g *LoginGuard) recordFailure(ctx context.Context, now time.Time, username, ip string) error {
+	for _, t := range g.thresholds(username, ip) {
+		attempt, err := g.attempts.RecordLoginFailure(ctx, t.key, now, g.policy.ResetAfter)
+		if err != nil {
+			return err
+		}
+		if attempt.Failures < t.max {
+			if t.backoff {
+				if err := g.attempts.LockLogin(ctx, t.key, now.Add(g.policy.backoffFor(attempt.Failures))); err != nil {
+					return err
+				}
+			}
+			continue
+		}
+
+		until := now.Add(g.policy.lockoutFor(attempt.Failures - t.max))
+		if err := g.attempts.LockLogin(ctx, t.key, until); err != nil {
+			return err
+		}
+		err = g.attempts.CreateLockoutEvent(ctx, models.LockoutEvent{
+			Key:         t.key,
+			Username:    username,
+			ClientIP:    ip,
+			Failures:    attempt.Failures,
+			LockedUntil: until,
+		})
+		if err != nil {
+			return err
+		}
+		slog.WarnContext(ctx, "sign-in locked", "key", t.key, "username", username,
+			"client_ip", ip, "failures", attempt.Failures, "until", until)
+	}
+	return nil
+}
+
+// UnlockUser lifts the lockout of username and forgets its failures. Locks
+// on client addresses stay until they expire. Unknown usernames are
+// reported as ErrNotFound.
+func (g *LoginGuard) UnlockUser(ctx context.Context, username string) error {
+	if _, err := g.users.GetUser(ctx, username); err != nil {
+		return err
+	}
+	if err := g.attempts.ClearLoginAttempts(ctx, userKey(username)); err != nil {
+		return err
+	}
+	slog.InfoContext(ctx, "sign-in unlocked", "username", username)
+	return nil
+}
+
+func userKey(username string) string { return "user:" + username }
+
+func ipKey(ip string) string { return "ip:" + ip }
@@ -0,0 +1,204 @@
+package service
+
+import (
+	"context"
+	"errors"
+	"grpc/server/models"
+	"grpc/server/pkg/clientip"
+	"grpc/server/pkg/repository"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// passwordAuth accepts one password for every username but "ghost", which
+// does not exist and is rejected like a wrong password.
+type passwordAuth struct {
+	Authorization
+	password string
+	calls    int
+}
+
+func (a *passwordAuth) GenerateToken(ctx context.Context, username, password string) (models.TokenPair, error) {
+	a.calls++
+	if username == "ghost" || password != a.password {
+		return models.TokenPair{}, ErrInvalidCredentials
+	}
+	return models.TokenPair{AccessToken: "token"}, nil
+}
+
+type lockoutFixture struct {
+	guard    *LoginGuard
+	auth     *passwordAuth
+	attempts *repository.LoginAttemptsMemory
+	now      time.Time
+}
+
+func newLockoutFixture() *lockoutFixture {
+	f := &lockoutFixture{
+		auth:     &passwordAuth{password: "right"},
+		attempts: repository.NewLoginAttemptsMemory(),
+		now:      time.Unix(1_700_000_000, 0),
+	}
+	users := repository.NewAuthMemory()
+	for _, username := range []string{"ann", "bob"} {
+		users.CreateUser(context.Background(), models.User{Username: username})
+	}
+	f.guard = NewLoginGuard(f.auth, users, f.attempts, LockoutPolicy{
+		MaxFailures:   3,
+		Backoff:       time.Second,
+		MaxIPFailures: 5,
+		Duration:      time.Minute,
+		MaxDuration:   5 * time.Minute,
+		ResetAfter:    time.Hour,
+	})
+	f.guard.now = func() time.Time { return f.now }
+	return f
+}
+
+func (f *lockoutFixture) signIn(ip, username, password string) error {
+	_, err := f.guard.GenerateToken(clientip.NewContext(context.Background(), ip), username, password)
+	return err
+}
+
+func retryAfter(t *testing.T, err error) time.Duration {
+	t.Helper()
+	var locked *LockedError
+	require.ErrorAs(t, err, &locked)
+	assert.ErrorIs(t, err, ErrLoginLocked)
+	return locked.RetryAfter
+}
+
+func TestLoginGuard_LocksUsernameWithBackoff(t *testing.T) {
+	f := newLockoutFixture()
+
+	for i := 0; i < 3; i++ {
+		f.now = f.now.Add(10 * time.Second)
+		assert.ErrorIs(t, f.signIn("192.0.2.1", "ann", "wrong"), ErrInvalidCredentials)
+	}
+
+	// The password is not even checked while locked, from any address.
+	calls := f.auth.calls
+	assert.Equal(t, time.Minute, retryAfter(t, f.signIn("192.0.2.2", "ann", "right")))
+	assert.Equal(t, calls, f.auth.calls)
+	assert.NoError(t, f.signIn("192.0.2.2", "bob", "right"), "other usernames are not affected")
+
+	// Each failure after the lockout doubles the next one, up to the cap.
+	for _, want := range []time.Duration{2 * time.Minute, 4 * time.Minute, 5 * time.Minute} {
+		f.now = f.now.Add(10 * time.Minute)
+		assert.ErrorIs(t, f.signIn("192.0.2.3", "ann", "wrong"), ErrInvalidCredentials)
+		assert.Equal(t, want, retryAfter(t, f.signIn("192.0.2.3", "ann", "right")))
+	}
+
+	f.now = f.now.Add(5 * time.Minute)
+	require.NoError(t, f.signIn("192.0.2.3", "ann", "right"))
+	attempt, err := f.attempts.GetLoginAttempt(context.Background(), "user:ann")
+	require.NoError(t, err)
+	assert.Zero(t, attempt.Failures, "a successful sign-in clears the username")
+}
+
+func TestLoginGuard_BacksOffBeforeLockout(t *testing.T) {
+	f := newLockoutFixture()
+
+	// Each failure below the threshold doubles the wait before the next
+	// attempt, without checking the password or counting a failure.
+	for _, want := range []time.Duration{time.Second, 2 * time.Second} {
+		assert.ErrorIs(t, f.signIn("192.0.2.1", "ann", "wrong"), ErrInvalidCredentials)
+		calls := f.auth.calls
+		assert.Equal(t, want, retryAfter(t, f.signIn("192.0.2.2", "ann", "right")))
+		assert.Equal(t, calls, f.auth.calls)
+		f.now = f.now.Add(want)
+	}
+
+	attempt, err := f.attempts.GetLoginAttempt(context.Background(), "user:ann")
+	require.NoError(t, err)
+	assert.Equal(t, 2, attempt.Failures)
+	assert.NoError(t, f.signIn("192.0.2.1", "bob", "right"), "addresses get no backoff")
+}
+
+func TestLoginGuard_UnknownAddress(t *testing.T) {
+	f := newLockoutFixture()
+
+	// Calls without a known address count only against their username, so
+	// they cannot lock each other out through a shared key.
+	for _, username := range []string{"a1", "a2", "a3", "a4", "a5"} {
+		assert.ErrorIs(t, f.signIn(clientip.Unknown, username, "wrong"), ErrInvalidCredentials)
+	}
+	assert.NoError(t, f.signIn(clientip.Unknown, "bob", "right"))
+	assert.NoError(t, f.signIn("", "ann", "right"))
+
+	attempt, err := f.attempts.GetLoginAttempt(context.Background(), "ip:"+clientip.Unknown)
+	require.NoError(t, err)
+	assert.Zero(t, attempt.Failures)
+}
+
+func TestLoginGuard_LocksClientAddress(t *testing.T) {
+	f := newLockoutFixture()
+
+	// Spreading guesses over usernames still trips the address threshold.
+	for _, username := range []string{"a1", "a2", "a3", "a4"} {
+		assert.ErrorIs(t, f.signIn("192.0.2.1", username, "wrong"), ErrInvalidCredentials)
+	}
+	assert.ErrorIs(t, f.signIn("192.0.2.1", "ghost", "wrong"), ErrInvalidCredentials, "unknown usernames count too")
+	assert.Equal(t, time.Minute, retryAfter(t, f.signIn("192.0.2.1", "bob", "right")))
+	assert.NoError(t, f.signIn("192.0.2.9", "bob", "right"))
+
+	// Unlocking a user does not lift the lock on the address.
+	require.NoError(t, f.guard.UnlockUser(context.Background(), "bob"))
+	retryAfter(t, f.signIn("192.0.2.1", "bob", "right"))
+}
+
+func TestLoginGuard_FailuresExpire(t *testing.T) {
+	f := newLockoutFixture()
+
+	assert.ErrorIs(t, f.signIn("192.0.2.1", "ann", "wrong"), ErrInvalidCredentials)
+	f.now = f.now.Add(10 * time.Second)
+	assert.ErrorIs(t, f.signIn("192.0.2.1", "ann", "wrong"), ErrInvalidCredentials)
+	f.now = f.now.Add(2 * time.Hour)
+	assert.ErrorIs(t, f.signIn("192.0.2.1", "ann", "wrong"), ErrInvalidCredentials)
+	assert.Equal(t, time.Second, retryAfter(t, f.signIn("192.0.2.1", "ann", "right")), "the count started over")
+	f.now = f.now.Add(time.Second)
+	assert.NoError(t, f.signIn("192.0.2.1", "ann", "right"))
+}
+
+func TestLoginGuard_UnlockUser(t *testing.T) {
+	f := newLockoutFixture()
+
+	for i := 0; i < 3; i++ {
+		f.now = f.now.Add(10 * time.Second)
+		f.signIn("192.0.2.1", "ann", "wrong")
+	}
+	retryAfter(t, f.signIn("192.0.2.2", "ann", "right"))
+
+	require.NoError(t, f.guard.UnlockUser(context.Background(), "ann"))
+	assert.NoError(t, f.signIn("192.0.2.2", "ann", "right"))
+
+	assert.ErrorIs(t, f.guard.UnlockUser(context.Background(), "ghost"), ErrNotFound)
+}
+
+type failingAttempts struct {
+	repository.LoginAttempts
+}
+
+func (failingAttempts) GetLoginAttempt(ctx context.Context, key string) (models.LoginAttempt, error) {
+	return models.LoginAttempt{}, errors.New("database is down")
+}
+
+func TestLoginGuard_StoreError(t *testing.T) {
+	auth := &passwordAuth{password: "right"}
+	guard := NewLoginGuard(auth, repository.NewAuthMemory(), failingAttempts{}, LockoutPolicy{})
+
+	_, err := guard.GenerateToken(context.Background(), "ann", "right")
+	assert.EqualError(t, err, "database is down")
+	assert.Zero(t, auth.calls, "sign-in must fail closed when attempts cannot be checked")
+}
+
+func TestLockoutPolicy_Defaults(t *testing.T) {
+	assert.Equal(t, DefaultLockoutPolicy(), LockoutPolicy{}.WithDefaults())
+
+	p := LockoutPolicy{Duration: 2 * time.Hour}.WithDefaults()
+	assert.Equal(t, 2*time.Hour, p.MaxDuration)
+	assert.Equal(t, 2*time.Hour, p.lockoutFor(10))
+}
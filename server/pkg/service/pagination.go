@@ -0,0 +1,69 @@
+package service
+
+import (
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/json"
+	"errors"
+	"grpc/server/models"
+	"strings"
+)
+
+const (
+	defaultPageSize = 50
+	defaultOrderBy  = "id"
+)
+
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+type pageToken struct {
+	OrderBy string `json:"o"`
+	// Filter is a hash of the filter the token was issued for.
+	Filter string `json:"f"`
+	// Total is the total size counted for the first page, so that later
+	// pages can report it without counting again.
+	Total int64 `json:"n,omitempty"`
+	models.BookCursor
+}
+
+func encodePageToken(orderBy string, filter models.BookFilter, total int64, book models.Book) string {
+	token := pageToken{
+		OrderBy:    orderBy,
+		Filter:     filterHash(filter),
+		Total:      total,
+		BookCursor: models.BookCursor{ID: book.ID},
+	}
+	switch strings.TrimSuffix(orderBy, " desc") {
+	case "title":
+		token.Title = book.Title
+	case "author":
+		token.Author = book.Author
+	case "created":
+		token.CreatedAt = book.CreatedAt
+	}
+
+	data, _ := json.Marshal(token)
+	return base64.RawURLEncoding.EncodeToString(data)
+}
+
+// decodePageToken rejects tokens issued for a different sort order or
+// filter, since their cursor would point at an unrelated position in the
+// result set.
+func decodePageToken(orderBy string, filter models.BookFilter, token string) (pageToken, error) {
+	data, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return pageToken{}, ErrInvalidPageToken
+	}
+
+	var decoded pageToken
+	if err := json.Unmarshal(data, &decoded); err != nil || decoded.OrderBy != orderBy || decoded.Filter != filterHash(filter) {
+		return pageToken{}, ErrInvalidPageToken
+	}
+	return decoded, nil
+}
+
+func filterHash(filter models.BookFilter) string {
+	data, _ := json.Marshal(filter)
+	sum := sha256.Sum256(data)
+	return base64.RawURLEncoding.EncodeToString(sum[:8])
+}
@@ -1,7 +1,10 @@
 package service
 
 import (
+	"context"
 	"grpc/server/models"
+	"grpc/server/pkg/events"
+	"grpc/server/pkg/password"
 	"grpc/server/pkg/repository"
 )
 
@@ -9,26 +12,57 @@ import (
 
 type Service struct {
 	Authorization
+	Lockout
 	Book
 }
 
+type Config struct {
+	Lockout LockoutPolicy
+	// Hasher and PasswordPolicy default to password.DefaultConfig and
+	// password.DefaultPolicy.
+	Hasher         password.Hasher
+	PasswordPolicy *password.Checker
+}
+
 type Authorization interface {
-	CreateUser(user models.User) (uint, error)
-	GenerateToken(username, password string) (string, error)
-	ParseToken(token string) (uint, error)
+	CreateUser(ctx context.Context, user models.User) (uint, error)
+	GenerateToken(ctx context.Context, username, password string) (models.TokenPair, error)
+	RefreshToken(ctx context.Context, refreshToken string) (models.TokenPair, error)
+	ParseToken(ctx context.Context, token string) (models.Principal, error)
+	SignOut(ctx context.Context, accessToken, refreshToken string) error
+	JWKS() []models.JWK
+}
+
+type Lockout interface {
+	UnlockUser(ctx context.Context, username string) error
 }
 
 type Book interface {
-	Create(book models.Book) (uint, error)
-	GetAll() ([]models.Book, error)
-	GetById(bookId uint) (models.Book, error)
-	Delete(userId, bookId uint) error
-	Update(userId, bookId uint, book models.UpdateBook) error
+	Create(ctx context.Context, book models.Book) (uint, error)
+	Import(ctx context.Context, next func() ([]models.ImportRow, error)) (models.ImportSummary, error)
+	List(ctx context.Context, input models.ListBooksInput) (models.BookPage, error)
+	Stream(ctx context.Context, filter models.BookFilter, batchSize int, fn func(models.Book) error) error
+	GetById(ctx context.Context, bookId uint) (models.Book, error)
+	Delete(ctx context.Context, principal models.Principal, bookId uint) error
+	Update(ctx context.Context, principal models.Principal, bookId uint, book models.UpdateBook) error
+	Watch(fromRevision uint64) (*events.Subscription, error)
 }
 
-func NewService(repos *repository.Repository) *Service {
+func NewService(repos *repository.Repository, cfg Config) *Service {
+	// The defaults are valid, so neither constructor can fail.
+	if cfg.Hasher == nil {
+		cfg.Hasher, _ = password.New(password.DefaultConfig())
+	}
+	if cfg.PasswordPolicy == nil {
+		cfg.PasswordPolicy, _ = password.NewChecker(password.DefaultPolicy())
+	}
+	guard := NewLoginGuard(
+		NewAuthService(repos.Authorization, repos.RefreshToken, repos.Revocation, cfg.Hasher, cfg.PasswordPolicy),
+		repos.Authorization, repos.LoginAttempts, cfg.Lockout,
+	)
 	return &Service{
-		Authorization: NewAuthService(repos.Authorization),
+		Authorization: guard,
+		Lockout:       guard,
 		Book:          NewBookService(repos.Book),
 	}
 }
@@ -1,6 +1,7 @@
 package service
 
 import (
+	"context"
 	"grpc/server/models"
 	"grpc/server/pkg/repository"
 
@@ -11,34 +12,50 @@ import (
 
 type fakeAuthRepo struct{}
 
-func (f fakeAuthRepo) CreateUser(user models.User) (uint, error) {
+func (f fakeAuthRepo) CreateUser(ctx context.Context, user models.User) (uint, error) {
 	return 0, nil
 }
 
-func (f fakeAuthRepo) GetUser(username string) (models.User, error) {
+func (f fakeAuthRepo) GetUser(ctx context.Context, username string) (models.User, error) {
 	return models.User{}, nil
 }
 
+func (f fakeAuthRepo) GetUserById(ctx context.Context, id uint) (models.User, error) {
+	return models.User{}, nil
+}
+
+func (f fakeAuthRepo) UpdatePassword(ctx context.Context, id uint, hash string) error {
+	return nil
+}
+
 type fakeBookRepo struct{}
 
-func (f fakeBookRepo) Create(book models.Book) (uint, error) {
+func (f fakeBookRepo) Create(ctx context.Context, book models.Book) (uint, error) {
 	return 0, nil
 }
 
-func (f fakeBookRepo) GetAll() ([]models.Book, error) {
+func (f fakeBookRepo) CreateBatch(ctx context.Context, books []models.Book) ([]models.BatchResult, error) {
+	return nil, nil
+}
+
+func (f fakeBookRepo) List(ctx context.Context, query models.BookQuery) ([]models.Book, int64, error) {
+	return nil, 0, nil
+}
+
+func (f fakeBookRepo) GetBatch(ctx context.Context, filter models.BookFilter, afterId uint, limit int) ([]models.Book, error) {
 	return nil, nil
 }
 
-func (f fakeBookRepo) GetById(bookId uint) (models.Book, error) {
+func (f fakeBookRepo) GetById(ctx context.Context, bookId uint) (models.Book, error) {
 	return models.Book{}, nil
 }
 
-func (f fakeBookRepo) Delete(userId, bookId uint) error {
-	return nil
+func (f fakeBookRepo) Delete(ctx context.Context, principal models.Principal, bookId uint) (models.Book, error) {
+	return models.Book{}, nil
 }
 
-func (f fakeBookRepo) Update(userId, bookId uint, book models.UpdateBook) error {
-	return nil
+func (f fakeBookRepo) Update(ctx context.Context, principal models.Principal, bookId uint, book models.UpdateBook) (models.Book, error) {
+	return models.Book{}, nil
 }
 
 func TestNewService(t *testing.T) {
@@ -47,19 +64,26 @@ func TestNewService(t *testing.T) {
 		Book:          fakeBookRepo{},
 	}
 
-	svc := NewService(repos)
+	svc := NewService(repos, Config{})
 
 	assert.NotNil(t, svc)
 
-	_, ok := svc.Authorization.(*AuthService)
-	assert.True(t, ok, "Authorization must be *AuthService")
+	guard, ok := svc.Authorization.(*LoginGuard)
+	assert.True(t, ok, "Authorization must be *LoginGuard")
+	assert.Same(t, guard, svc.Lockout)
+	assert.Equal(t, DefaultLockoutPolicy(), guard.policy)
+
+	_, ok = guard.Authorization.(*AuthService)
+	assert.True(t, ok, "LoginGuard must wrap *AuthService")
 
 	_, ok = svc.Book.(*BookService)
 	assert.True(t, ok, "Book must be *BookService")
 
-	authService := svc.Authorization.(*AuthService)
+	authService := guard.Authorization.(*AuthService)
 	bookService := svc.Book.(*BookService)
 
 	assert.NotNil(t, authService.repo)
 	assert.NotNil(t, bookService.repo)
+	assert.NotNil(t, authService.hasher)
+	assert.NotNil(t, authService.policy)
 }
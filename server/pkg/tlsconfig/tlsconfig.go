@@ -0,0 +1,278 @@
+// Package tlsconfig builds TLS configurations for the gRPC server and its
+// clients from certificate files that are reloaded when they change on disk,
+// so rotated certificates take effect without a restart.
+package tlsconfig
+
+import (
+	"context"
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"log/slog"
+	"net"
+	"os"
+	"sync"
+	"time"
+
+	"google.golang.org/grpc/credentials"
+)
+
+// checkInterval is how long a reloader trusts its files after checking them,
+// so that handshakes do not stat them every time.
+var checkInterval = 5 * time.Second
+
+var versions = map[string]uint16{
+	"":    tls.VersionTLS12,
+	"1.2": tls.VersionTLS12,
+	"1.3": tls.VersionTLS13,
+}
+
+// ServerConfig describes the server side of the connection. Setting
+// ClientCAFile turns on mutual TLS: clients must present a certificate
+// signed by one of its CAs.
+type ServerConfig struct {
+	CertFile     string `mapstructure:"cert_file"`
+	KeyFile      string `mapstructure:"key_file"`
+	ClientCAFile string `mapstructure:"client_ca_file"`
+	MinVersion   string `mapstructure:"min_version"`
+}
+
+// Enabled reports whether the server should serve TLS.
+func (c ServerConfig) Enabled() bool {
+	return c.CertFile != "" || c.KeyFile != ""
+}
+
+// ClientConfig describes the client side of the connection. An empty CAFile
+// verifies the server against the system roots; CertFile and KeyFile supply a
+// client certificate for servers that require mutual TLS.
+type ClientConfig struct {
+	CAFile     string
+	CertFile   string
+	KeyFile    string
+	ServerName string
+	MinVersion string
+}
+
+// Server returns a TLS configuration that rereads the key pair and client CA
+// bundle whenever their files change. A reload that fails keeps the
+// previous files in use.
+func Server(cfg ServerConfig) (*tls.Config, error) {
+	if cfg.CertFile == "" || cfg.KeyFile == "" {
+		return nil, fmt.Errorf("tls: both cert_file and key_file are required")
+	}
+	minVersion, err := parseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+
+	certs, err := newReloader(loadKeyPair(cfg.CertFile, cfg.KeyFile), cfg.CertFile, cfg.KeyFile)
+	if err != nil {
+		return nil, err
+	}
+	var clientCAs *reloader[*x509.CertPool]
+	if cfg.ClientCAFile != "" {
+		if clientCAs, err = newReloader(loadPool(cfg.ClientCAFile), cfg.ClientCAFile); err != nil {
+			return nil, err
+		}
+	}
+
+	return &tls.Config{
+		MinVersion: minVersion,
+		GetConfigForClient: func(*tls.ClientHelloInfo) (*tls.Config, error) {
+			config := &tls.Config{
+				MinVersion:   minVersion,
+				Certificates: []tls.Certificate{*certs.get()},
+				NextProtos:   []string{"h2"},
+			}
+			if clientCAs != nil {
+				config.ClientCAs = clientCAs.get()
+				config.ClientAuth = tls.RequireAndVerifyClientCert
+			}
+			return config, nil
+		},
+	}, nil
+}
+
+// Client returns a TLS configuration for dialing the server. The client
+// certificate, if any, is reread whenever its files change; the CA bundle is
+// read once. Use ClientCredentials to pick up a rotated CA bundle too.
+func Client(cfg ClientConfig) (*tls.Config, error) {
+	minVersion, err := parseVersion(cfg.MinVersion)
+	if err != nil {
+		return nil, err
+	}
+	config := &tls.Config{
+		MinVersion: minVersion,
+		ServerName: cfg.ServerName,
+	}
+
+	if cfg.CAFile != "" {
+		if config.RootCAs, err = loadPool(cfg.CAFile)(); err != nil {
+			return nil, err
+		}
+	}
+
+	if cfg.CertFile != "" || cfg.KeyFile != "" {
+		certs, err := newReloader(loadKeyPair(cfg.CertFile, cfg.KeyFile), cfg.CertFile, cfg.KeyFile)
+		if err != nil {
+			return nil, err
+		}
+		config.GetClientCertificate = func(*tls.CertificateRequestInfo) (*tls.Certificate, error) {
+			return certs.get(), nil
+		}
+	}
+	return config, nil
+}
+
+// ClientCredentials returns gRPC transport credentials for dialing the
+// server, like Client, except that the CA bundle is also reread whenever its
+// file changes.
+func ClientCredentials(cfg ClientConfig) (credentials.TransportCredentials, error) {
+	base, err := Client(ClientConfig{
+		CertFile:   cfg.CertFile,
+		KeyFile:    cfg.KeyFile,
+		ServerName: cfg.ServerName,
+		MinVersion: cfg.MinVersion,
+	})
+	if err != nil {
+		return nil, err
+	}
+	creds := &clientCredentials{base: base}
+	if cfg.CAFile != "" {
+		if creds.roots, err = newReloader(loadPool(cfg.CAFile), cfg.CAFile); err != nil {
+			return nil, err
+		}
+	}
+	return creds, nil
+}
+
+// clientCredentials verifies each handshake against the current CA bundle.
+type clientCredentials struct {
+	base  *tls.Config
+	roots *reloader[*x509.CertPool]
+}
+
+func (c *clientCredentials) ClientHandshake(ctx context.Context, authority string, conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	config := c.base.Clone()
+	if c.roots != nil {
+		config.RootCAs = c.roots.get()
+	}
+	return credentials.NewTLS(config).ClientHandshake(ctx, authority, conn)
+}
+
+func (c *clientCredentials) ServerHandshake(conn net.Conn) (net.Conn, credentials.AuthInfo, error) {
+	return nil, nil, errors.New("tls: client credentials cannot accept connections")
+}
+
+func (c *clientCredentials) Info() credentials.ProtocolInfo {
+	return credentials.NewTLS(c.base).Info()
+}
+
+func (c *clientCredentials) Clone() credentials.TransportCredentials {
+	return &clientCredentials{base: c.base.Clone(), roots: c.roots}
+}
+
+func (c *clientCredentials) OverrideServerName(name string) error {
+	c.base.ServerName = name
+	return nil
+}
+
+func parseVersion(version string) (uint16, error) {
+	v, ok := versions[version]
+	if !ok {
+		return 0, fmt.Errorf("tls: unsupported min_version %q, want 1.2 or 1.3", version)
+	}
+	return v, nil
+}
+
+func loadKeyPair(certFile, keyFile string) func() (*tls.Certificate, error) {
+	return func() (*tls.Certificate, error) {
+		cert, err := tls.LoadX509KeyPair(certFile, keyFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: loading key pair: %w", err)
+		}
+		return &cert, nil
+	}
+}
+
+func loadPool(caFile string) func() (*x509.CertPool, error) {
+	return func() (*x509.CertPool, error) {
+		pem, err := os.ReadFile(caFile)
+		if err != nil {
+			return nil, fmt.Errorf("tls: reading CA bundle: %w", err)
+		}
+		pool := x509.NewCertPool()
+		if !pool.AppendCertsFromPEM(pem) {
+			return nil, fmt.Errorf("tls: no certificates found in %s", caFile)
+		}
+		return pool, nil
+	}
+}
+
+// reloader caches a value built from files and rebuilds it when any of the
+// files' size or modification time changes, checking at most once per
+// checkInterval.
+type reloader[T any] struct {
+	files    []string
+	load     func() (T, error)
+	interval time.Duration
+
+	mu      sync.Mutex
+	value   T
+	version string
+	failed  string
+	checked time.Time
+}
+
+func newReloader[T any](load func() (T, error), files ...string) (*reloader[T], error) {
+	r := &reloader[T]{files: files, load: load, interval: checkInterval, checked: time.Now()}
+	version, err := r.stat()
+	if err != nil {
+		return nil, err
+	}
+	if r.value, err = load(); err != nil {
+		return nil, err
+	}
+	r.version = version
+	return r, nil
+}
+
+func (r *reloader[T]) get() T {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(r.checked) < r.interval {
+		return r.value
+	}
+	r.checked = now
+
+	version, err := r.stat()
+	if err != nil || version == r.version || version == r.failed {
+		return r.value
+	}
+	value, err := r.load()
+	if err != nil {
+		r.failed = version
+		// Rotations often write the certificate and key separately; keep
+		// serving the old pair until both are readable and match.
+		slog.Warn("keeping previous TLS files", "files", r.files, "error", err)
+		return r.value
+	}
+	r.value, r.version = value, version
+	slog.Info("reloaded TLS files", "files", r.files)
+	return r.value
+}
+
+func (r *reloader[T]) stat() (string, error) {
+	var version string
+	for _, file := range r.files {
+		info, err := os.Stat(file)
+		if err != nil {
+			return "", fmt.Errorf("tls: %w", err)
+		}
+		version += fmt.Sprintf("%d:%d;", info.Size(), info.ModTime().UnixNano())
+	}
+	return version, nil
+}
@@ -0,0 +1,286 @@
+package tlsconfig
+
+import (
+	"context"
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/tls"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type testCA struct {
+	cert *x509.Certificate
+	key  *ecdsa.PrivateKey
+	pem  []byte
+}
+
+func newTestCA(t *testing.T) *testCA {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber:          big.NewInt(1),
+		Subject:               pkix.Name{CommonName: "test ca"},
+		NotBefore:             time.Now().Add(-time.Hour),
+		NotAfter:              time.Now().Add(time.Hour),
+		IsCA:                  true,
+		BasicConstraintsValid: true,
+		KeyUsage:              x509.KeyUsageCertSign,
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, tmpl, &key.PublicKey, key)
+	require.NoError(t, err)
+	cert, err := x509.ParseCertificate(der)
+	require.NoError(t, err)
+	return &testCA{cert: cert, key: key, pem: pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der})}
+}
+
+// issue writes a certificate for name signed by ca to dir and returns the
+// certificate and key paths.
+func (ca *testCA) issue(t *testing.T, dir, name string, serial int64) (string, string) {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	require.NoError(t, err)
+	tmpl := &x509.Certificate{
+		SerialNumber: big.NewInt(serial),
+		Subject:      pkix.Name{CommonName: name},
+		DNSNames:     []string{name},
+		NotBefore:    time.Now().Add(-time.Hour),
+		NotAfter:     time.Now().Add(time.Hour),
+		KeyUsage:     x509.KeyUsageDigitalSignature,
+		ExtKeyUsage:  []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth},
+	}
+	der, err := x509.CreateCertificate(rand.Reader, tmpl, ca.cert, &key.PublicKey, ca.key)
+	require.NoError(t, err)
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	require.NoError(t, err)
+
+	certFile := filepath.Join(dir, name+".crt")
+	keyFile := filepath.Join(dir, name+".key")
+	require.NoError(t, os.WriteFile(certFile, pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}), 0o600))
+	require.NoError(t, os.WriteFile(keyFile, pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), 0o600))
+	return certFile, keyFile
+}
+
+func (ca *testCA) write(t *testing.T, dir string) string {
+	file := filepath.Join(dir, "ca.crt")
+	require.NoError(t, os.WriteFile(file, ca.pem, 0o600))
+	return file
+}
+
+// handshake connects client to server over loopback and returns the
+// certificate the server presented.
+func handshake(t *testing.T, server, client *tls.Config) (*x509.Certificate, error) {
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+
+	serverErr := make(chan error, 1)
+	go func() {
+		conn, err := lis.Accept()
+		if err != nil {
+			serverErr <- err
+			return
+		}
+		defer conn.Close()
+		serverErr <- tls.Server(conn, server).Handshake()
+	}()
+
+	conn, err := tls.Dial("tcp", lis.Addr().String(), client)
+	if err != nil {
+		return nil, err
+	}
+	defer conn.Close()
+	// TLS 1.3 reports a rejected client certificate only after the client
+	// has finished its side, so wait for the server's verdict too.
+	if err := <-serverErr; err != nil {
+		return nil, err
+	}
+	return conn.ConnectionState().PeerCertificates[0], nil
+}
+
+func TestServer_TLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "localhost", 2)
+
+	server, err := Server(ServerConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	client, err := Client(ClientConfig{CAFile: ca.write(t, dir), ServerName: "localhost"})
+	require.NoError(t, err)
+
+	cert, err := handshake(t, server, client)
+	require.NoError(t, err)
+	assert.Equal(t, "localhost", cert.Subject.CommonName)
+}
+
+func TestServer_MutualTLS(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	caFile := ca.write(t, dir)
+	certFile, keyFile := ca.issue(t, dir, "localhost", 2)
+	clientCert, clientKey := ca.issue(t, dir, "client", 3)
+
+	server, err := Server(ServerConfig{CertFile: certFile, KeyFile: keyFile, ClientCAFile: caFile})
+	require.NoError(t, err)
+
+	anonymous, err := Client(ClientConfig{CAFile: caFile, ServerName: "localhost"})
+	require.NoError(t, err)
+	_, err = handshake(t, server, anonymous)
+	assert.Error(t, err)
+
+	authenticated, err := Client(ClientConfig{CAFile: caFile, CertFile: clientCert, KeyFile: clientKey, ServerName: "localhost"})
+	require.NoError(t, err)
+	_, err = handshake(t, server, authenticated)
+	assert.NoError(t, err)
+}
+
+// checkEveryTime makes reloaders created by the test check their files on
+// every use.
+func checkEveryTime(t *testing.T) {
+	previous := checkInterval
+	checkInterval = 0
+	t.Cleanup(func() { checkInterval = previous })
+}
+
+// touch moves the modification time of file forward so that a rewrite within
+// the same clock tick is still noticed.
+func touch(t *testing.T, file string) {
+	future := time.Now().Add(time.Minute)
+	require.NoError(t, os.Chtimes(file, future, future))
+}
+
+func TestServer_ReloadsCertificate(t *testing.T) {
+	checkEveryTime(t)
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "localhost", 2)
+
+	server, err := Server(ServerConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	client, err := Client(ClientConfig{CAFile: ca.write(t, dir), ServerName: "localhost"})
+	require.NoError(t, err)
+
+	cert, err := handshake(t, server, client)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cert.SerialNumber.Int64())
+
+	ca.issue(t, dir, "localhost", 4)
+	touch(t, certFile)
+
+	cert, err = handshake(t, server, client)
+	require.NoError(t, err)
+	assert.Equal(t, int64(4), cert.SerialNumber.Int64())
+}
+
+func TestServer_KeepsCertificateWhenReloadFails(t *testing.T) {
+	checkEveryTime(t)
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "localhost", 2)
+
+	server, err := Server(ServerConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	client, err := Client(ClientConfig{CAFile: ca.write(t, dir), ServerName: "localhost"})
+	require.NoError(t, err)
+
+	require.NoError(t, os.WriteFile(certFile, []byte("not a certificate"), 0o600))
+
+	cert, err := handshake(t, server, client)
+	require.NoError(t, err)
+	assert.Equal(t, int64(2), cert.SerialNumber.Int64())
+}
+
+func TestReloader_ChecksOncePerInterval(t *testing.T) {
+	file := filepath.Join(t.TempDir(), "value")
+	require.NoError(t, os.WriteFile(file, []byte("one"), 0o600))
+	load := func() (string, error) {
+		data, err := os.ReadFile(file)
+		return string(data), err
+	}
+
+	r, err := newReloader(load, file)
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(file, []byte("two"), 0o600))
+	touch(t, file)
+	assert.Equal(t, "one", r.get())
+
+	r.checked = time.Now().Add(-checkInterval)
+	assert.Equal(t, "two", r.get())
+}
+
+func TestClientCredentials_ReloadsCA(t *testing.T) {
+	checkEveryTime(t)
+	dir := t.TempDir()
+	oldCA, newCA := newTestCA(t), newTestCA(t)
+	caFile := oldCA.write(t, dir)
+	certFile, keyFile := newCA.issue(t, dir, "localhost", 2)
+
+	server, err := Server(ServerConfig{CertFile: certFile, KeyFile: keyFile})
+	require.NoError(t, err)
+	creds, err := ClientCredentials(ClientConfig{CAFile: caFile})
+	require.NoError(t, err)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	defer lis.Close()
+	go func() {
+		for {
+			conn, err := lis.Accept()
+			if err != nil {
+				return
+			}
+			tls.Server(conn, server).Handshake()
+			conn.Close()
+		}
+	}()
+
+	handshake := func() error {
+		conn, err := net.Dial("tcp", lis.Addr().String())
+		require.NoError(t, err)
+		defer conn.Close()
+		_, _, err = creds.ClientHandshake(context.Background(), "localhost", conn)
+		return err
+	}
+
+	assert.Error(t, handshake())
+
+	newCA.write(t, dir)
+	touch(t, caFile)
+	assert.NoError(t, handshake())
+}
+
+func TestServer_MinVersion(t *testing.T) {
+	dir := t.TempDir()
+	ca := newTestCA(t)
+	certFile, keyFile := ca.issue(t, dir, "localhost", 2)
+
+	server, err := Server(ServerConfig{CertFile: certFile, KeyFile: keyFile, MinVersion: "1.3"})
+	require.NoError(t, err)
+	client, err := Client(ClientConfig{CAFile: ca.write(t, dir), ServerName: "localhost"})
+	require.NoError(t, err)
+	client.MaxVersion = tls.VersionTLS12
+
+	_, err = handshake(t, server, client)
+	assert.Error(t, err)
+}
+
+func TestInvalidConfig(t *testing.T) {
+	_, err := Server(ServerConfig{CertFile: "server.crt"})
+	assert.Error(t, err)
+
+	_, err = Server(ServerConfig{CertFile: "missing.crt", KeyFile: "missing.key"})
+	assert.Error(t, err)
+
+	_, err = Client(ClientConfig{MinVersion: "1.1"})
+	assert.Error(t, err)
+}
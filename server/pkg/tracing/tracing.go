@@ -0,0 +1,204 @@
+// Package tracing sets up OpenTelemetry tracing for the server and the REST
+// proxy and records spans around database queries. Trace context travels
+// between processes in W3C traceparent headers and gRPC metadata.
+package tracing
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc"
+	"go.opentelemetry.io/contrib/instrumentation/google.golang.org/grpc/otelgrpc/filters"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/attribute"
+	"go.opentelemetry.io/otel/codes"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	semconv "go.opentelemetry.io/otel/semconv/v1.26.0"
+	"go.opentelemetry.io/otel/trace"
+	"google.golang.org/grpc/stats"
+	"gorm.io/gorm"
+)
+
+// Config selects where spans are exported.
+type Config struct {
+	// Exporter is "otlp", "stdout", "file" or "none"; empty means none.
+	Exporter string
+	// Endpoint is the OTLP/gRPC collector address. When empty the
+	// OTEL_EXPORTER_OTLP_ENDPOINT variable or localhost:4317 is used.
+	Endpoint string
+	// Insecure disables TLS towards the collector.
+	Insecure bool
+	// File receives one JSON span per line with the file exporter.
+	File string
+	// SampleRatio is the fraction of new traces to record; traces started
+	// upstream follow the caller's decision.
+	SampleRatio float64
+	// ServiceName identifies this process unless OTEL_SERVICE_NAME is set.
+	ServiceName string
+}
+
+// Setup installs the global tracer provider and the W3C trace context
+// propagator. The returned function flushes pending spans and must be
+// called before the process exits.
+func Setup(ctx context.Context, cfg Config) (func(context.Context) error, error) {
+	otel.SetTextMapPropagator(propagation.NewCompositeTextMapPropagator(
+		propagation.TraceContext{}, propagation.Baggage{},
+	))
+
+	var closer io.Closer
+	var exporter sdktrace.SpanExporter
+	var err error
+	switch cfg.Exporter {
+	case "", "none":
+		return func(context.Context) error { return nil }, nil
+	case "stdout":
+		exporter, err = stdouttrace.New(stdouttrace.WithPrettyPrint())
+	case "file":
+		if cfg.File == "" {
+			return nil, fmt.Errorf("tracing: the file exporter needs a file")
+		}
+		if err := os.MkdirAll(filepath.Dir(cfg.File), 0o755); err != nil {
+			return nil, fmt.Errorf("tracing: %w", err)
+		}
+		var f *os.File
+		if f, err = os.OpenFile(cfg.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0o644); err != nil {
+			return nil, fmt.Errorf("tracing: %w", err)
+		}
+		closer = f
+		exporter, err = stdouttrace.New(stdouttrace.WithWriter(f))
+	case "otlp":
+		opts := []otlptracegrpc.Option{}
+		if cfg.Endpoint != "" {
+			opts = append(opts, otlptracegrpc.WithEndpoint(cfg.Endpoint))
+		}
+		if cfg.Insecure {
+			opts = append(opts, otlptracegrpc.WithInsecure())
+		}
+		exporter, err = otlptracegrpc.New(ctx, opts...)
+	default:
+		return nil, fmt.Errorf("tracing: unknown exporter %q", cfg.Exporter)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+
+	res, err := resource.Merge(
+		resource.Default(),
+		resource.NewSchemaless(semconv.ServiceName(cfg.ServiceName)),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+	// Let OTEL_SERVICE_NAME and OTEL_RESOURCE_ATTRIBUTES win over the default.
+	if res, err = resource.Merge(res, resource.Environment()); err != nil {
+		return nil, fmt.Errorf("tracing: %w", err)
+	}
+
+	ratio := cfg.SampleRatio
+	if ratio <= 0 || ratio > 1 {
+		ratio = 1
+	}
+	provider := sdktrace.NewTracerProvider(
+		sdktrace.WithBatcher(exporter),
+		sdktrace.WithResource(res),
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(ratio))),
+	)
+	otel.SetTracerProvider(provider)
+
+	return func(ctx context.Context) error {
+		err := provider.Shutdown(ctx)
+		if closer != nil {
+			closer.Close()
+		}
+		return err
+	}, nil
+}
+
+const (
+	instrumentationName = "grpc/server/pkg/tracing"
+	spanKey             = "tracing:span"
+)
+
+// InstrumentGORM registers callbacks on db that wrap every query in a span,
+// a child of the span in the context passed to db.WithContext.
+func InstrumentGORM(db *gorm.DB) error {
+	cb := db.Callback()
+	system := db.Dialector.Name()
+	return errors.Join(
+		cb.Create().Before("gorm:create").Register("tracing:before_create", beforeQuery("create")),
+		cb.Create().After("gorm:create").Register("tracing:after_create", afterQuery(system)),
+		cb.Query().Before("gorm:query").Register("tracing:before_query", beforeQuery("select")),
+		cb.Query().After("gorm:query").Register("tracing:after_query", afterQuery(system)),
+		cb.Update().Before("gorm:update").Register("tracing:before_update", beforeQuery("update")),
+		cb.Update().After("gorm:update").Register("tracing:after_update", afterQuery(system)),
+		cb.Delete().Before("gorm:delete").Register("tracing:before_delete", beforeQuery("delete")),
+		cb.Delete().After("gorm:delete").Register("tracing:after_delete", afterQuery(system)),
+		cb.Row().Before("gorm:row").Register("tracing:before_row", beforeQuery("row")),
+		cb.Row().After("gorm:row").Register("tracing:after_row", afterQuery(system)),
+		cb.Raw().Before("gorm:raw").Register("tracing:before_raw", beforeQuery("raw")),
+		cb.Raw().After("gorm:raw").Register("tracing:after_raw", afterQuery(system)),
+	)
+}
+
+func beforeQuery(operation string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		ctx := tx.Statement.Context
+		if ctx == nil {
+			ctx = context.Background()
+		}
+		name := "db." + operation
+		if tx.Statement.Table != "" {
+			name += " " + tx.Statement.Table
+		}
+		_, span := otel.Tracer(instrumentationName).Start(ctx, name,
+			trace.WithSpanKind(trace.SpanKindClient),
+			trace.WithAttributes(semconv.DBOperationName(operation)),
+		)
+		tx.InstanceSet(spanKey, span)
+	}
+}
+
+func afterQuery(system string) func(*gorm.DB) {
+	return func(tx *gorm.DB) {
+		value, ok := tx.InstanceGet(spanKey)
+		if !ok {
+			return
+		}
+		span := value.(trace.Span)
+		defer span.End()
+
+		// The statement is recorded with placeholders, so bound values such
+		// as password hashes never reach the trace.
+		span.SetAttributes(
+			attribute.String("db.system", system),
+			semconv.DBCollectionName(tx.Statement.Table),
+			semconv.DBQueryText(tx.Statement.SQL.String()),
+			attribute.Int64("db.rows_affected", tx.Statement.RowsAffected),
+		)
+		if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+			span.RecordError(tx.Error)
+			span.SetStatus(codes.Error, tx.Error.Error())
+		}
+	}
+}
+
+// ServerHandler returns a gRPC stats handler that starts a span for every
+// incoming RPC, continuing the trace found in the request metadata. Health
+// checks are not traced.
+func ServerHandler() stats.Handler {
+	return otelgrpc.NewServerHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))
+}
+
+// ClientHandler returns a gRPC stats handler that starts a span for every
+// outgoing RPC and sends its trace context in the request metadata.
+func ClientHandler() stats.Handler {
+	return otelgrpc.NewClientHandler(otelgrpc.WithFilter(filters.Not(filters.HealthCheck())))
+}
@@ -0,0 +1,139 @@
+package tracing
+
+import (
+	"bufio"
+	"context"
+	"encoding/json"
+	"grpc/proto"
+	"grpc/server/models"
+	"net"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/codes"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+	"gorm.io/driver/sqlite"
+	"gorm.io/gorm"
+)
+
+// recordSpans installs a tracer provider that keeps finished spans in memory
+// for the duration of the test.
+func recordSpans(t *testing.T) *tracetest.SpanRecorder {
+	recorder := tracetest.NewSpanRecorder()
+	provider := sdktrace.NewTracerProvider(sdktrace.WithSpanProcessor(recorder))
+
+	previous := otel.GetTracerProvider()
+	otel.SetTracerProvider(provider)
+	t.Cleanup(func() { otel.SetTracerProvider(previous) })
+	return recorder
+}
+
+func spanNamed(t *testing.T, spans []sdktrace.ReadOnlySpan, name string) sdktrace.ReadOnlySpan {
+	for _, span := range spans {
+		if span.Name() == name {
+			return span
+		}
+	}
+	t.Fatalf("no span named %q", name)
+	return nil
+}
+
+func TestInstrumentGORM(t *testing.T) {
+	recorder := recordSpans(t)
+	db, err := gorm.Open(sqlite.Open(filepath.Join(t.TempDir(), "test.db")), &gorm.Config{})
+	require.NoError(t, err)
+	require.NoError(t, db.Exec("CREATE TABLE books (id integer PRIMARY KEY, title text UNIQUE, author text, user_id integer, created_at datetime)").Error)
+	require.NoError(t, InstrumentGORM(db))
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	require.NoError(t, db.WithContext(ctx).Create(&models.Book{Title: "Dune", Author: "Frank Herbert"}).Error)
+	assert.Error(t, db.WithContext(ctx).Create(&models.Book{Title: "Dune", Author: "Frank Herbert"}).Error)
+	parent.End()
+
+	spans := recorder.Ended()
+	require.Len(t, spans, 3)
+	for _, span := range spans[:2] {
+		assert.Equal(t, "db.create books", span.Name())
+		assert.Equal(t, parent.SpanContext().SpanID(), span.Parent().SpanID())
+	}
+	assert.Equal(t, codes.Unset, spans[0].Status().Code)
+	assert.Equal(t, codes.Error, spans[1].Status().Code)
+
+	var statement string
+	for _, attr := range spans[0].Attributes() {
+		if attr.Key == "db.query.text" {
+			statement = attr.Value.AsString()
+		}
+	}
+	assert.Contains(t, statement, "INSERT INTO `books`")
+	assert.NotContains(t, statement, "Frank Herbert")
+}
+
+func TestPropagation(t *testing.T) {
+	recorder := recordSpans(t)
+
+	lis, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	server := grpc.NewServer(grpc.StatsHandler(ServerHandler()))
+	proto.RegisterBookServiceServer(server, proto.UnimplementedBookServiceServer{})
+	go server.Serve(lis)
+	defer server.Stop()
+
+	conn, err := grpc.NewClient(lis.Addr().String(),
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithStatsHandler(ClientHandler()),
+	)
+	require.NoError(t, err)
+	defer conn.Close()
+
+	shutdown, err := Setup(context.Background(), Config{})
+	require.NoError(t, err)
+	defer shutdown(context.Background())
+
+	ctx, parent := otel.Tracer("test").Start(context.Background(), "request")
+	_, err = proto.NewBookServiceClient(conn).GetBook(ctx, &proto.BookId{Id: 1})
+	require.Error(t, err)
+	parent.End()
+	server.GracefulStop()
+
+	serverSpan := spanNamed(t, recorder.Ended(), "proto.BookService/GetBook")
+	assert.Equal(t, parent.SpanContext().TraceID(), serverSpan.SpanContext().TraceID())
+	assert.True(t, serverSpan.Parent().IsRemote())
+}
+
+func TestSetup_File(t *testing.T) {
+	previous := otel.GetTracerProvider()
+	defer otel.SetTracerProvider(previous)
+
+	file := filepath.Join(t.TempDir(), "traces", "spans.jsonl")
+	shutdown, err := Setup(context.Background(), Config{Exporter: "file", File: file, ServiceName: "test"})
+	require.NoError(t, err)
+
+	_, span := otel.Tracer("test").Start(context.Background(), "work")
+	span.End()
+	require.NoError(t, shutdown(context.Background()))
+
+	f, err := os.Open(file)
+	require.NoError(t, err)
+	defer f.Close()
+	scanner := bufio.NewScanner(f)
+	require.True(t, scanner.Scan())
+	var exported struct{ Name string }
+	require.NoError(t, json.Unmarshal(scanner.Bytes(), &exported))
+	assert.Equal(t, "work", exported.Name)
+}
+
+func TestSetup_Invalid(t *testing.T) {
+	_, err := Setup(context.Background(), Config{Exporter: "zipkin"})
+	assert.Error(t, err)
+
+	_, err = Setup(context.Background(), Config{Exporter: "file"})
+	assert.Error(t, err)
+}